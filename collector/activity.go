@@ -0,0 +1,74 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// activityMetrics are the per-thread metrics of show activity, by field.
+var activityMetrics = map[string]metricInfo{
+	"loops":        newThreadMetric("loops_total", "Total number of polling loops.", prometheus.CounterValue),
+	"wake_tasks":   newThreadMetric("task_wakeups_total", "Total number of wakeups to process tasks.", prometheus.CounterValue),
+	"wake_signal":  newThreadMetric("signal_wakeups_total", "Total number of wakeups to process signals.", prometheus.CounterValue),
+	"poll_io":      newThreadMetric("polled_io_total", "Total number of polls reporting I/O events.", prometheus.CounterValue),
+	"poll_exp":     newThreadMetric("polled_timeouts_total", "Total number of polls ended by a timer.", prometheus.CounterValue),
+	"ctxsw":        newThreadMetric("context_switches_total", "Total number of context switches.", prometheus.CounterValue),
+	"tasksw":       newThreadMetric("task_switches_total", "Total number of task switches.", prometheus.CounterValue),
+	"empty_rq":     newThreadMetric("empty_run_queue_total", "Total number of loops finding the run queue empty.", prometheus.CounterValue),
+	"long_rq":      newThreadMetric("long_run_queue_total", "Total number of loops finding a long run queue.", prometheus.CounterValue),
+	"conn_dead":    newThreadMetric("dead_connections_total", "Total number of events on dead connections.", prometheus.CounterValue),
+	"stream_calls": newThreadMetric("stream_calls_total", "Total number of calls to process streams.", prometheus.CounterValue),
+	"accepted":     newThreadMetric("accepted_connections_total", "Total number of accepted connections.", prometheus.CounterValue),
+	"fd_takeover":  newThreadMetric("connection_takeovers_total", "Total number of idle connections taken over from other threads.", prometheus.CounterValue),
+	"avg_loop_us":  fromMicroseconds(newThreadMetric("loop_duration_average_seconds", "Average duration of a polling loop.", prometheus.GaugeValue)),
+}
+
+func newThreadMetric(metricName string, docString string, t prometheus.ValueType) metricInfo {
+	return newMetricInfo(prometheus.BuildFQName(namespace, "thread", metricName), docString, t, []string{"thread"}, nil)
+}
+
+// parseActivity sends the metrics of the output of show activity. Its lines
+// hold the values of a field for every thread, e.g. "loops: 10 12". HAProxy
+// 2.4 and later prefix them with the total, as in "loops: 22 [ 10 12 ]". With
+// a single thread, only one value is shown.
+func parseActivity(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		field, values, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			continue
+		}
+		m, ok := metrics[field]
+		if !ok {
+			continue
+		}
+		if _, perThread, ok := strings.Cut(values, "["); ok {
+			values = strings.TrimSuffix(strings.TrimSpace(perThread), "]")
+		}
+		for i, value := range strings.Fields(values) {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			m.send(ch, v, strconv.Itoa(i+1))
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseActivity(t *testing.T) {
+	tests := []struct {
+		name, output string
+	}{
+		{
+			name:   "per thread",
+			output: "thread_id: 1 (1..2)\ndate_now: 1700000000.000000\nloops: 10 20\nfd_takeover: 1 0\navg_loop_us: 15 250\n",
+		},
+		{
+			name:   "with total",
+			output: "thread_id: 1 (1..2)\ndate_now: 1700000000.000000\nloops: 30 [ 10 20 ]\nfd_takeover: 1 [ 1 0 ]\navg_loop_us: 132 [ 15 250 ]\nunknown: 1 [ 1 0 ]\n",
+		},
+	}
+	expected := `
+# HELP haproxy_thread_connection_takeovers_total Total number of idle connections taken over from other threads.
+# TYPE haproxy_thread_connection_takeovers_total counter
+haproxy_thread_connection_takeovers_total{thread="1"} 1
+haproxy_thread_connection_takeovers_total{thread="2"} 0
+# HELP haproxy_thread_loop_duration_average_seconds Average duration of a polling loop.
+# TYPE haproxy_thread_loop_duration_average_seconds gauge
+haproxy_thread_loop_duration_average_seconds{thread="1"} 1.5e-05
+haproxy_thread_loop_duration_average_seconds{thread="2"} 0.00025
+# HELP haproxy_thread_loops_total Total number of polling loops.
+# TYPE haproxy_thread_loops_total counter
+haproxy_thread_loops_total{thread="1"} 10
+haproxy_thread_loops_total{thread="2"} 20
+`
+
+	for _, tt := range tests {
+		p := parsedRuntime{collector: runtimeCollectors["activity"], output: tt.output}
+		if err := testutil.CollectAndCompare(p, strings.NewReader(expected), "haproxy_thread_connection_takeovers_total", "haproxy_thread_loop_duration_average_seconds", "haproxy_thread_loops_total"); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func TestParseActivitySingleThread(t *testing.T) {
+	p := parsedRuntime{collector: runtimeCollectors["activity"], output: "thread_id: 0 (1..1)\nloops: 42\n"}
+	expected := `
+# HELP haproxy_thread_loops_total Total number of polling loops.
+# TYPE haproxy_thread_loops_total counter
+haproxy_thread_loops_total{thread="1"} 42
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(expected), "haproxy_thread_loops_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"math"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/haproxy_exporter/parser"
+)
+
+// maxServerFields are the gauges of the server metrics which are aggregated
+// over the servers of a backend by taking their maximum. Counters and the
+// other gauges of sumServerFields are summed, the remaining gauges, e.g.
+// those of health checks or averages, aren't aggregated.
+var (
+	maxServerFields = map[int]bool{3: true, 5: true, 35: true, qtimeMaxMsField: true, ctimeMaxMsField: true, rtimeMaxMsField: true, ttimeMaxMsField: true}
+	sumServerFields = map[int]bool{2: true, 4: true, slimField: true, statusField: true, 18: true, 19: true, 20: true, qlimitField: true, 33: true, 88: true, 89: true}
+)
+
+// aggregatedServerHelp is the help of the aggregated server metrics whose
+// meaning changes by summing them.
+var aggregatedServerHelp = map[int]string{
+	statusField: "Number of servers of the backend which are UP.",
+	19:          "Number of active servers of the backend.",
+	20:          "Number of backup servers of the backend.",
+}
+
+// aggregatedServerMetrics returns the metrics of m which are aggregated over
+// the servers of a backend, without the label server.
+func aggregatedServerMetrics(m metrics) metrics {
+	res := metrics{}
+	for field, metric := range m {
+		if metric.Type != prometheus.CounterValue && !maxServerFields[field] && !sumServerFields[field] {
+			continue
+		}
+		res[field] = metric.withoutVariableLabel("server", aggregatedServerHelp[field])
+	}
+	return res
+}
+
+// withoutVariableLabel returns m without the variable label name, and with the
+// given help if not empty.
+func (m metricInfo) withoutVariableLabel(name, help string) metricInfo {
+	labels := make([]string, 0, len(m.variableLabels))
+	for _, l := range m.variableLabels {
+		if l != name {
+			labels = append(labels, l)
+		}
+	}
+	if help == "" {
+		help = m.help
+	}
+	res := newMetricInfo(m.fqName, help, m.Type, labels, m.constLabels)
+	res.divisor = m.divisor
+	if m.also != nil {
+		also := m.also.withoutVariableLabel(name, help)
+		res.also = &also
+	}
+	return res
+}
+
+// exportAggregatedServers sends the server metrics aggregated over the
+// exported servers of every backend, and of every process in the label mode
+// of MultiProcess. Limits are unlimited if a server has none.
+func (e *Exporter) exportAggregatedServers(rows []parser.Row, ch chan<- prometheus.Metric) {
+	type key struct{ pxname, process string }
+	type aggregate struct {
+		row       []string // Of the first server, for the labels.
+		values    map[int]float64
+		unlimited map[int]bool
+	}
+	var order []key
+	aggregates := map[key]*aggregate{}
+	for _, row := range rows {
+		f := row.Fields
+		if f[typeField] != "2" || !e.serverExported(f) {
+			continue
+		}
+		k := key{f[pxnameField], f[pidField]}
+		a, ok := aggregates[k]
+		if !ok {
+			a = &aggregate{row: f, values: map[int]float64{}, unlimited: map[int]bool{}}
+			aggregates[k] = a
+			order = append(order, k)
+		}
+		for _, fm := range e.rowMetricsOf(k.process).aggregatedServerFields {
+			field := fm.field
+			if field >= len(f) {
+				break
+			}
+			if f[field] == "" {
+				if isLimitField(field) {
+					a.unlimited[field] = true
+				}
+				continue
+			}
+			v, err := parseFieldValue(field, f[field])
+			if err != nil {
+				level.Error(e.logger).Log("msg", "Can't parse CSV field value", "value", f[field], "err", err)
+				e.csvParseFailures.WithLabelValues(parseFailureBadNumber).Inc()
+				continue
+			}
+			old, seen := a.values[field]
+			switch {
+			case !seen:
+				a.values[field] = v
+			case maxServerFields[field]:
+				a.values[field] = math.Max(old, v)
+			default:
+				a.values[field] = old + v
+			}
+		}
+	}
+
+	for _, k := range order {
+		a := aggregates[k]
+		row := e.labelPairs.resetRow(e.labels, a.row, false)
+		for _, fm := range e.rowMetricsOf(k.process).aggregatedServerFields {
+			switch v, ok := a.values[fm.field]; {
+			case a.unlimited[fm.field]:
+				if e.unlimitedValue != nil {
+					fm.metric.sendRow(ch, row, *e.unlimitedValue)
+				}
+			case ok:
+				fm.metric.sendRow(ch, row, v)
+			}
+		}
+	}
+}
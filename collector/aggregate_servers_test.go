@@ -0,0 +1,74 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAggregateServers(t *testing.T) {
+	// The averages of the field 60 aren't aggregated.
+	rows := newRow("be", "a", "2", map[int]string{statusField: "UP", 4: "1", 5: "7", 7: "10", 40: "8", 60: "20"}) +
+		newRow("be", "b", "2", map[int]string{statusField: "DOWN", 4: "2", 5: "4", 7: "5", 40: "3", 60: "40"}) +
+		newRow("be", "c", "2", map[int]string{statusField: "MAINT", 4: "9", 7: "100", 60: "10"}) +
+		newRow("other", "a", "2", map[int]string{statusField: "UP", 4: "3", 7: "1", 60: "10"})
+	h := newHaproxy([]byte(rows))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{
+		ServerMetrics:        serverMetrics,
+		ExcludedServerStates: "MAINT",
+		Timeout:              5 * time.Second,
+		AggregateServers:     true,
+	}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The aggregated metrics are described in place of the per-server ones.
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(e); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_server_current_sessions Current number of active sessions.
+# TYPE haproxy_server_current_sessions gauge
+haproxy_server_current_sessions{backend="be"} 3
+haproxy_server_current_sessions{backend="other"} 3
+# HELP haproxy_server_http_responses_total Total of HTTP responses.
+# TYPE haproxy_server_http_responses_total counter
+haproxy_server_http_responses_total{backend="be",code="2xx"} 11
+# HELP haproxy_server_max_sessions Maximum observed number of active sessions.
+# TYPE haproxy_server_max_sessions gauge
+haproxy_server_max_sessions{backend="be"} 7
+# HELP haproxy_server_sessions_total Total number of sessions.
+# TYPE haproxy_server_sessions_total counter
+haproxy_server_sessions_total{backend="be"} 15
+haproxy_server_sessions_total{backend="other"} 1
+# HELP haproxy_server_up Number of servers of the backend which are UP.
+# TYPE haproxy_server_up gauge
+haproxy_server_up{backend="be"} 1
+haproxy_server_up{backend="other"} 1
+`
+	names := []string{"haproxy_server_current_sessions", "haproxy_server_http_responses_total", "haproxy_server_max_sessions", "haproxy_server_sessions_total", "haproxy_server_up", "haproxy_server_http_response_time_average_seconds", "haproxy_server_status"}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+}
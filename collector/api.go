@@ -0,0 +1,132 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// snapshot is the state of an HAProxy as of the last successful scrape.
+type snapshot struct {
+	Target     string          `json:"target"`
+	LastScrape *time.Time      `json:"last_scrape"`
+	Proxies    []snapshotProxy `json:"proxies"`
+}
+
+// snapshotProxy holds the fields of the frontend, backend and servers of a
+// proxy, keyed by their names in the HAProxy stats.
+type snapshotProxy struct {
+	Name     string                 `json:"name"`
+	Frontend map[string]interface{} `json:"frontend,omitempty"`
+	Backend  map[string]interface{} `json:"backend,omitempty"`
+	Servers  []snapshotServer       `json:"servers,omitempty"`
+}
+
+type snapshotServer struct {
+	Name   string                 `json:"name"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+// snapshotFields returns the non-empty fields of a canonical CSV row, as
+// numbers where possible. Fields without a known name are called
+// column_<index>.
+func snapshotFields(row []string, cols columns) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for i, v := range row {
+		if i <= svnameField || v == "" {
+			continue
+		}
+		name := cols.name(i)
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			fields[name] = n
+		} else if f, err := strconv.ParseFloat(v, 64); err == nil {
+			fields[name] = f
+		} else {
+			fields[name] = v
+		}
+	}
+	return fields
+}
+
+// snapshot returns the state of e's HAProxy as of its last successful scrape.
+// Proxies and servers are in the order of the stats.
+func (e *Exporter) snapshot() snapshot {
+	// The slice of the rows is reused by a later scrape once it replaces
+	// them, so they are only read while holding lastMutex.
+	e.lastMutex.Lock()
+	defer e.lastMutex.Unlock()
+	rows, cols, t := e.lastRows, e.lastColumns, e.lastScrape
+	s := snapshot{Target: e.URI, Proxies: []snapshotProxy{}}
+	if !t.IsZero() {
+		s.LastScrape = &t
+	}
+
+	proxies := map[string]int{}
+	for _, r := range rows {
+		row := r.Fields
+		name := row[pxnameField]
+		i, ok := proxies[name]
+		if !ok {
+			i = len(s.Proxies)
+			proxies[name] = i
+			s.Proxies = append(s.Proxies, snapshotProxy{Name: name})
+		}
+
+		p := &s.Proxies[i]
+		switch row[typeField] {
+		case "0":
+			p.Frontend = snapshotFields(row, cols)
+		case "1":
+			p.Backend = snapshotFields(row, cols)
+		case "2":
+			p.Servers = append(p.Servers, snapshotServer{Name: row[svnameField], Fields: snapshotFields(row, cols)})
+		}
+	}
+	return s
+}
+
+// SnapshotHandler returns a handler responding with the state of the HAProxy
+// targets as of their last successful scrape, as JSON. A single target is
+// selected with the target query parameter.
+func SnapshotHandler(exporters func() []*Exporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selected := exporters()
+		if r.URL.Query().Get("target") != "" {
+			e, err := selectExporter(selected, r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			selected = []*Exporter{e}
+		}
+
+		// Without exporters the response is an empty array, not null.
+		snapshots := make([]snapshot, 0, len(selected))
+		for _, e := range selected {
+			snapshots = append(snapshots, e.snapshot())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		// The handler has no logger of its own, so errors are logged with that
+		// of the first exporter, if there is one.
+		if err := json.NewEncoder(w).Encode(snapshots); err != nil && len(selected) > 0 {
+			level.Error(selected[0].logger).Log("msg", "Error encoding snapshot", "err", err)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// failingWriter is a ResponseWriter whose writes fail, as if the client went
+// away.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+func TestSnapshotHandlerNoExporters(t *testing.T) {
+	handler := SnapshotHandler(func() []*Exporter { return nil })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/metrics", nil))
+	if want, have := "[]\n", rec.Body.String(); want != have {
+		t.Errorf("want body %q, have %q", want, have)
+	}
+
+	// A failing write is not logged without an exporter to log it with.
+	handler.ServeHTTP(failingWriter{httptest.NewRecorder()}, httptest.NewRequest("GET", "/api/v1/metrics", nil))
+}
+
+func TestSnapshotHandler(t *testing.T) {
+	const data = `foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,
+foo,foo-instance-0,0,0,3,4,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+`
+	h := newHaproxy([]byte(data))
+	defer h.Close()
+
+	e, _ := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	handler := SnapshotHandler(func() []*Exporter { return []*Exporter{e} })
+
+	get := func() []snapshot {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/metrics", nil))
+		if want, have := http.StatusOK, rec.Code; want != have {
+			t.Fatalf("want status %d, have %d", want, have)
+		}
+		var snapshots []snapshot
+		if err := json.Unmarshal(rec.Body.Bytes(), &snapshots); err != nil {
+			t.Fatal(err)
+		}
+		return snapshots
+	}
+
+	if s := get(); len(s) != 1 || s[0].LastScrape != nil || len(s[0].Proxies) != 0 {
+		t.Errorf("want empty snapshot before the first scrape, have %+v", s)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+	if _, err := registry.Gather(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := get()[0]
+	if s.LastScrape == nil {
+		t.Fatalf("want time of last scrape")
+	}
+	if want, have := 1, len(s.Proxies); want != have {
+		t.Fatalf("want %d proxies, have %d", want, have)
+	}
+	p := s.Proxies[0]
+	if want, have := "OPEN", p.Frontend["status"]; want != have {
+		t.Errorf("want frontend status %q, have %v", want, have)
+	}
+	if want, have := 1, len(p.Servers); want != have {
+		t.Fatalf("want %d servers, have %d", want, have)
+	}
+	if want, have := "foo-instance-0", p.Servers[0].Name; want != have {
+		t.Errorf("want server %q, have %q", want, have)
+	}
+	// Numbers are decoded as float64 from JSON.
+	if want, have := 3.0, p.Servers[0].Fields["scur"]; want != have {
+		t.Errorf("want server scur %v, have %v", want, have)
+	}
+}
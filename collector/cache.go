@@ -0,0 +1,120 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ScrapeCache shares the stats fetched from HAProxy between scrapes of the
+// same target, e.g. by a pair of HA Prometheus servers, as long as they happen
+// within the TTL of the cache. Failed fetches are never cached.
+type ScrapeCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mutex   sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	mutex   sync.Mutex // Held while fetching, so that concurrent scrapes share one fetch.
+	data    []byte
+	fetched time.Time
+}
+
+// NewScrapeCache returns a ScrapeCache serving fetched stats for ttl.
+func NewScrapeCache(ttl time.Duration) *ScrapeCache {
+	return &ScrapeCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+// cacheKey returns the key of the output of the command cmd of the target uri
+// fetched with opts. Exporters of the same target with other credentials or
+// TLS and proxy settings, e.g. those of other modules, have keys of their own,
+// so that they are never served stats they couldn't fetch themselves. The
+// credentials are hashed, to not keep them in another place.
+func cacheKey(uri, cmd string, opts ExporterOpts) string {
+	credentials := sha256.Sum256([]byte(strconv.Quote(opts.Username) + strconv.Quote(opts.Password)))
+	return fmt.Sprintf("%s %q %x %t %t", uri, cmd, credentials, opts.SSLVerify, opts.ProxyFromEnv)
+}
+
+// entry returns the entry for key, creating it if necessary. Expired entries
+// of other keys are dropped, so that targets which are no longer scraped don't
+// pile up.
+func (c *ScrapeCache) entry(key string) *cacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	for k, e := range c.entries {
+		if k == key || !e.mutex.TryLock() {
+			continue
+		}
+		if now.Sub(e.fetched) >= c.ttl {
+			delete(c.entries, k)
+		}
+		e.mutex.Unlock()
+	}
+
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cacheEntry{}
+		c.entries[key] = e
+	}
+	return e
+}
+
+// wrap returns a Fetcher which serves the result of fetch from the cache entry
+// for key while it is fresh. If served is not nil, it is called with whether
+// the result came from the cache, and its age, whenever one is served.
+func (c *ScrapeCache) wrap(key string, fetch Fetcher, served func(hit bool, age time.Duration)) Fetcher {
+	return FetcherFunc(func(ctx context.Context) (io.ReadCloser, error) {
+		e := c.entry(key)
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+
+		var age time.Duration
+		hit := e.data != nil && c.now().Sub(e.fetched) < c.ttl
+		if hit {
+			age = c.now().Sub(e.fetched)
+		} else {
+			body, err := fetch.Fetch(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer body.Close()
+
+			data, err := io.ReadAll(body)
+			if err != nil {
+				return nil, err
+			}
+			e.data, e.fetched = data, c.now()
+		}
+		if served != nil {
+			served(hit, age)
+		}
+		return io.NopCloser(bytes.NewReader(e.data)), nil
+	})
+}
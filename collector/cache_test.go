@@ -0,0 +1,135 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestScrapeCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewScrapeCache(10 * time.Second)
+	c.now = func() time.Time { return now }
+
+	fetches, hits := 0, 0
+	var age time.Duration
+	var fetchErr error
+	fetch := c.wrap("target", FetcherFunc(func(context.Context) (io.ReadCloser, error) {
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		fetches++
+		return io.NopCloser(strings.NewReader("payload")), nil
+	}), func(hit bool, a time.Duration) {
+		if hit {
+			hits++
+		}
+		age = a
+	})
+
+	read := func() string {
+		t.Helper()
+		r, err := fetch.Fetch(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		b, _ := io.ReadAll(r)
+		return string(b)
+	}
+
+	if have := read(); have != "payload" {
+		t.Fatalf("want payload, have %q", have)
+	}
+	now = now.Add(5 * time.Second)
+	read()
+	if want, have := 1, fetches; want != have {
+		t.Errorf("want %d fetches within TTL, have %d", want, have)
+	}
+	if want, have := 1, hits; want != have {
+		t.Errorf("want %d cache hits within TTL, have %d", want, have)
+	}
+	if want, have := 5*time.Second, age; want != have {
+		t.Errorf("want served stats aged %s, have %s", want, have)
+	}
+
+	now = now.Add(5 * time.Second)
+	read()
+	if want, have := 2, fetches; want != have {
+		t.Errorf("want %d fetches after TTL, have %d", want, have)
+	}
+	if want, have := time.Duration(0), age; want != have {
+		t.Errorf("want fetched stats aged %s, have %s", want, have)
+	}
+
+	now = now.Add(10 * time.Second)
+	fetchErr = errors.New("down")
+	if _, err := fetch.Fetch(context.Background()); err == nil {
+		t.Errorf("expected error of expired entry to be returned")
+	}
+}
+
+func TestScrapeCacheEviction(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewScrapeCache(time.Second)
+	c.now = func() time.Time { return now }
+
+	fetch := FetcherFunc(func(context.Context) (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("")), nil })
+	c.wrap("a", fetch, nil).Fetch(context.Background())
+	c.wrap("b", fetch, nil).Fetch(context.Background())
+
+	now = now.Add(time.Second)
+	c.wrap("b", fetch, nil).Fetch(context.Background())
+	if _, ok := c.entries["a"]; ok {
+		t.Errorf("expected expired entry to be evicted")
+	}
+	if want, have := 1, len(c.entries); want != have {
+		t.Errorf("want %d cache entries, have %d", want, have)
+	}
+}
+
+func TestExporterCacheMetrics(t *testing.T) {
+	h := newHaproxy([]byte(newRow("fe", "FRONTEND", "0", map[int]string{statusField: "OPEN"})))
+	defer h.Close()
+
+	now := time.Unix(0, 0)
+	c := NewScrapeCache(10 * time.Second)
+	c.now = func() time.Time { return now }
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, Cache: c}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.CollectAndCount(e)
+	now = now.Add(3 * time.Second)
+
+	expected := `
+# HELP haproxy_exporter_cache_age_seconds Age of the stats served by the last scrape, 0 if they were fetched from HAProxy.
+# TYPE haproxy_exporter_cache_age_seconds gauge
+haproxy_exporter_cache_age_seconds 3
+# HELP haproxy_exporter_cache_hits_total Number of scrapes whose stats were served from the scrape cache instead of HAProxy.
+# TYPE haproxy_exporter_cache_hits_total counter
+haproxy_exporter_cache_hits_total 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_exporter_cache_age_seconds", "haproxy_exporter_cache_hits_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// capturedErrorMetrics are the metrics of show errors. HAProxy only keeps the
+// last captured error of every proxy, so the number of errors is only known
+// for all proxies together. The ID of the last error of a proxy changes with
+// every new one.
+var capturedErrorMetrics = map[string]metricInfo{
+	"total":    newProcessMetric("captured_errors_total", "Total number of malformed requests and responses captured.", prometheus.CounterValue),
+	"frontend": newFrontendMetric("last_captured_error_event", "ID of the last malformed request captured by the frontend.", prometheus.GaugeValue, nil),
+	"backend":  newBackendMetric("last_captured_error_event", "ID of the last malformed response captured by the backend.", prometheus.GaugeValue, nil),
+}
+
+var (
+	capturedErrorRE = regexp.MustCompile(`^\[[^\]]*\] (frontend|backend) (.+) \(#-?\d+\): `)
+	errorEventRE    = regexp.MustCompile(`, event #(\d+)`)
+)
+
+// parseCapturedErrors sends the metrics of the output of show errors:
+//
+//	Total events captured on [10/Jul/2023:12:00:00.123] : 2
+//
+//	[10/Jul/2023:11:59:00.000] frontend fe (#2): invalid request
+//	  backend <NONE> (#-1), server <NONE> (#-1), event #1, src 127.0.0.1:5678
+//	  ...
+func parseCapturedErrors(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	var kind, proxy string // Of the last error, until its event is found.
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "Total events captured on ") {
+			_, total, _ := strings.Cut(line, "] : ")
+			v, err := strconv.ParseFloat(strings.TrimSpace(total), 64)
+			if err != nil {
+				return fmt.Errorf("invalid total of captured errors: %w", err)
+			}
+			metrics["total"].send(ch, v)
+			continue
+		}
+		if m := capturedErrorRE.FindStringSubmatch(line); m != nil {
+			kind, proxy = m[1], m[2]
+			continue
+		}
+		if m := errorEventRE.FindStringSubmatch(line); m != nil && proxy != "" {
+			v, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				return err
+			}
+			metrics[kind].send(ch, v, proxy)
+			proxy = ""
+		}
+	}
+	return s.Err()
+}
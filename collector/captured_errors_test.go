@@ -0,0 +1,52 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testErrors = `Total events captured on [10/Jul/2023:12:00:00.123] : 7
+
+[10/Jul/2023:11:59:00.000] frontend fe (#2): invalid request
+  backend <NONE> (#-1), server <NONE> (#-1), event #6, src 127.0.0.1:5678
+  buffer starts at 0 (including 0 out), 16384 free,
+  len 16, wraps at 16336, error at position 5
+  00000  GET /\x01 HTTP/1.1\r\n
+
+[10/Jul/2023:11:58:30.000] backend be (#3): invalid response
+  frontend fe (#2), server srv1 (#1), event #4, src 127.0.0.1:5679
+  00000  HTTP/1.1 200 \x01K\r\n
+`
+
+func TestParseCapturedErrors(t *testing.T) {
+	p := parsedRuntime{collector: runtimeCollectors["errors"], output: testErrors}
+	expected := `
+# HELP haproxy_backend_last_captured_error_event ID of the last malformed response captured by the backend.
+# TYPE haproxy_backend_last_captured_error_event gauge
+haproxy_backend_last_captured_error_event{backend="be"} 4
+# HELP haproxy_frontend_last_captured_error_event ID of the last malformed request captured by the frontend.
+# TYPE haproxy_frontend_last_captured_error_event gauge
+haproxy_frontend_last_captured_error_event{frontend="fe"} 6
+# HELP haproxy_process_captured_errors_total Total number of malformed requests and responses captured.
+# TYPE haproxy_process_captured_errors_total counter
+haproxy_process_captured_errors_total 7
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,2109 @@
+// Copyright 2018 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package collector scrapes the stats of HAProxy and exports them as
+// Prometheus metrics. The haproxy_exporter binary serves the metrics of an
+// Exporter, and other programs can register it with their own registry.
+package collector
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/haproxy_exporter/parser"
+)
+
+const (
+	namespace = "haproxy" // For Prometheus metrics.
+
+	// HAProxy 1.4
+	// # pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,
+	// HAProxy 1.5
+	// pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,
+	// HAProxy 1.5.19
+	// pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,last_chk,last_agt,qtime,ctime,rtime,ttime,
+	// HAProxy 1.7
+	// pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,last_chk,last_agt,qtime,ctime,rtime,ttime,agent_status,agent_code,agent_duration,check_desc,agent_desc,check_rise,check_fall,check_health,agent_rise,agent_fall,agent_health,addr,cookie,mode,algo,conn_rate,conn_rate_max,conn_tot,intercepted,dcon,dses
+	minimumCsvFieldCount = parser.MinFields
+
+	pxnameField        = parser.ProxyField
+	svnameField        = parser.ServerField
+	statusField        = 17
+	typeField          = parser.TypeField
+	slimField          = 6
+	qlimitField        = 25
+	rateLimField       = 34
+	checkStatusField   = 36
+	checkDurationField = 38
+	modeField          = 75
+	algoField          = 76
+	qtimeMsField       = 58
+	ctimeMsField       = 59
+	rtimeMsField       = 60
+	ttimeMsField       = 61
+	qtimeMaxMsField    = 90
+	ctimeMaxMsField    = 91
+	rtimeMaxMsField    = 92
+	ttimeMaxMsField    = 93
+	quicRxbufFullField = 113
+	iidField           = 27
+	sidField           = 28
+	addrField          = 73
+
+	excludedServerStates = ""
+	showStatCmd          = "show stat\n"
+	showInfoCmd          = "show info\n"
+)
+
+// csvFieldNames holds the names of the CSV fields, as found in the header of
+// the HAProxy 2.8 stats, indexed by field number. Stats of older versions have
+// a prefix of them. The aggregated states of the servers, the runtime ids of
+// servers and the protocols of frontends have no metrics. They are followed
+// by the fields of the QUIC stats module of HAProxy 2.6 and later, which come
+// after other columns in the stats, so that only their header tells their
+// position.
+var csvFieldNames = []string{
+	"pxname", "svname", "qcur", "qmax", "scur", "smax", "slim", "stot", "bin", "bout",
+	"dreq", "dresp", "ereq", "econ", "eresp", "wretr", "wredis", "status", "weight", "act",
+	"bck", "chkfail", "chkdown", "lastchg", "downtime", "qlimit", "pid", "iid", "sid", "throttle",
+	"lbtot", "tracked", "type", "rate", "rate_lim", "rate_max", "check_status", "check_code", "check_duration", "hrsp_1xx",
+	"hrsp_2xx", "hrsp_3xx", "hrsp_4xx", "hrsp_5xx", "hrsp_other", "hanafail", "req_rate", "req_rate_max", "req_tot", "cli_abrt",
+	"srv_abrt", "comp_in", "comp_out", "comp_byp", "comp_rsp", "lastsess", "last_chk", "last_agt", "qtime", "ctime",
+	"rtime", "ttime", "agent_status", "agent_code", "agent_duration", "check_desc", "agent_desc", "check_rise", "check_fall", "check_health",
+	"agent_rise", "agent_fall", "agent_health", "addr", "cookie", "mode", "algo", "conn_rate", "conn_rate_max", "conn_tot",
+	"intercepted", "dcon", "dses", "wrew", "connect", "reuse", "cache_lookups", "cache_hits", "srv_icur", "srv_ilim",
+	"qtime_max", "ctime_max", "rtime_max", "ttime_max", "eint", "idle_conn_cur", "safe_conn_cur", "used_conn_cur", "need_conn_est", "uweight",
+	"agg_server_status", "agg_server_check_status", "agg_check_status", "srid", "sess_other", "h1sess", "h2sess", "h3sess", "req_other", "h1req",
+	"h2req", "h3req", "proto",
+	"quic_rxbuf_full", "quic_dropped_pkt", "quic_dropped_pkt_bufoverrun", "quic_dropped_parsing", "quic_socket_full", "quic_sendto_err", "quic_sendto_err_unknwn", "quic_sent_pkt", "quic_lost_pkt", "quic_too_short_dgram",
+	"quic_retry_sent", "quic_retry_validated", "quic_retry_error", "quic_half_open_conn", "quic_hdshk_fail", "quic_stless_rst_sent", "quic_conn_migration_done",
+}
+
+// Sections of the exported metrics, which can be selected per request with the
+// collect[] query parameter.
+const (
+	infoSection     = "info"
+	frontendSection = "frontend"
+	backendSection  = "backend"
+	serverSection   = "server"
+	runtimeSection  = "runtime"
+)
+
+// TargetLabel is the label of haproxy_up and of the haproxy_exporter_* metrics
+// telling apart several targets, see ExporterOpts.Target.
+const TargetLabel = "target"
+
+var allSections = map[string]bool{infoSection: true, frontendSection: true, backendSection: true, serverSection: true, runtimeSection: true}
+
+// IsSection returns whether name is a section of the metrics, of info,
+// frontend, backend, server and runtime.
+func IsSection(name string) bool {
+	return allSections[name]
+}
+
+// statsSections are the sections of the stats rows, by their bit in the type
+// mask of show stat.
+var statsSections = map[string]int{frontendSection: 1, backendSection: 2, serverSection: 4}
+
+var (
+	frontendLabelNames = []string{"frontend"}
+	backendLabelNames  = []string{"backend"}
+	serverLabelNames   = []string{"backend", "server"}
+)
+
+type metricInfo struct {
+	Desc *prometheus.Desc
+	Type prometheus.ValueType
+
+	// The arguments Desc was created with, to derive descriptors with
+	// additional constant labels from it.
+	fqName         string
+	help           string
+	variableLabels []string
+	constLabels    prometheus.Labels
+	// layout orders the label pairs of the metrics of rows of the stats.
+	layout *labelLayout
+
+	// divisor, if not 0, divides the values of the metric, e.g. to convert
+	// milliseconds to seconds.
+	divisor float64
+	// also, if not nil, is exported along with the metric, e.g. under the
+	// name of another naming scheme.
+	also *metricInfo
+}
+
+func newMetricInfo(fqName string, docString string, t prometheus.ValueType, variableLabels []string, constLabels prometheus.Labels) metricInfo {
+	return metricInfo{
+		Desc:           prometheus.NewDesc(fqName, docString, variableLabels, constLabels),
+		Type:           t,
+		fqName:         fqName,
+		help:           docString,
+		variableLabels: variableLabels,
+		constLabels:    constLabels,
+		layout:         newLabelLayout(variableLabels, constLabels),
+	}
+}
+
+// describe sends the descriptors of m.
+func (m metricInfo) describe(ch chan<- *prometheus.Desc) {
+	ch <- m.Desc
+	if m.also != nil {
+		m.also.describe(ch)
+	}
+}
+
+// value returns the value of m for the value of a field.
+func (m metricInfo) value(field float64) float64 {
+	if m.divisor != 0 {
+		return field / m.divisor
+	}
+	return field
+}
+
+// send sends the metric m for the value of a field.
+func (m metricInfo) send(ch chan<- prometheus.Metric, field float64, labels ...string) {
+	ch <- prometheus.MustNewConstMetric(m.Desc, m.Type, m.value(field), labels...)
+	if m.also != nil {
+		m.also.send(ch, field, labels...)
+	}
+}
+
+// sendRow is like send for the metric of a row of the stats, with the label
+// pairs of the row.
+func (m metricInfo) sendRow(ch chan<- prometheus.Metric, row *rowLabelPairs, field float64) {
+	ch <- row.metric(m, m.value(field))
+	if m.also != nil {
+		m.also.sendRow(ch, row, field)
+	}
+}
+
+// withAlso returns m with other exported along with it.
+func (m metricInfo) withAlso(other metricInfo) metricInfo {
+	if m.also != nil {
+		other = m.also.withAlso(other)
+	}
+	m.also = &other
+	return m
+}
+
+// withMilliseconds returns the timing metrics of m, which are in seconds, with
+// a copy in milliseconds exported along with each.
+func (m metrics) withMilliseconds() metrics {
+	res := make(metrics, len(m))
+	for field, metric := range m {
+		switch field {
+		case qtimeMsField, ctimeMsField, rtimeMsField, ttimeMsField,
+			qtimeMaxMsField, ctimeMaxMsField, rtimeMaxMsField, ttimeMaxMsField:
+			ms := newMetricInfo(strings.TrimSuffix(metric.fqName, "_seconds")+"_milliseconds", strings.TrimSuffix(metric.help, ".")+", in milliseconds.", metric.Type, metric.variableLabels, metric.constLabels)
+			metric = metric.withAlso(ms)
+		}
+		res[field] = metric
+	}
+	return res
+}
+
+// fromMilliseconds returns m converting the milliseconds of its field to
+// seconds.
+func fromMilliseconds(m metricInfo) metricInfo {
+	m.divisor = 1000
+	return m
+}
+
+// fromMicroseconds returns m converting the microseconds of its field to
+// seconds.
+func fromMicroseconds(m metricInfo) metricInfo {
+	m.divisor = 1e6
+	return m
+}
+
+func newFrontendMetric(metricName string, docString string, t prometheus.ValueType, constLabels prometheus.Labels) metricInfo {
+	return newMetricInfo(prometheus.BuildFQName(namespace, "frontend", metricName), docString, t, frontendLabelNames, constLabels)
+}
+
+func newBackendMetric(metricName string, docString string, t prometheus.ValueType, constLabels prometheus.Labels) metricInfo {
+	return newMetricInfo(prometheus.BuildFQName(namespace, "backend", metricName), docString, t, backendLabelNames, constLabels)
+}
+
+func newServerMetric(metricName string, docString string, t prometheus.ValueType, constLabels prometheus.Labels) metricInfo {
+	return newMetricInfo(prometheus.BuildFQName(namespace, "server", metricName), docString, t, serverLabelNames, constLabels)
+}
+
+func newProcessMetric(metricName string, docString string, t prometheus.ValueType) metricInfo {
+	return newMetricInfo(prometheus.BuildFQName(namespace, "process", metricName), docString, t, nil, nil)
+}
+
+// withConstLabels returns m with labels added to its constant labels.
+func (m metricInfo) withConstLabels(labels prometheus.Labels) metricInfo {
+	if len(labels) == 0 {
+		return m
+	}
+	constLabels := prometheus.Labels{}
+	for k, v := range m.constLabels {
+		constLabels[k] = v
+	}
+	for k, v := range labels {
+		constLabels[k] = v
+	}
+	res := newMetricInfo(m.fqName, m.help, m.Type, m.variableLabels, constLabels)
+	res.divisor = m.divisor
+	if m.also != nil {
+		also := m.also.withConstLabels(labels)
+		res.also = &also
+	}
+	return res
+}
+
+// withVariableLabels returns m with the variable labels names inserted after
+// the first n.
+func (m metricInfo) withVariableLabels(n int, names []string) metricInfo {
+	if len(names) == 0 {
+		return m
+	}
+	labels := append(append(append([]string(nil), m.variableLabels[:n]...), names...), m.variableLabels[n:]...)
+	res := newMetricInfo(m.fqName, m.help, m.Type, labels, m.constLabels)
+	res.divisor = m.divisor
+	if m.also != nil {
+		also := m.also.withVariableLabels(n, names)
+		res.also = &also
+	}
+	return res
+}
+
+type metrics map[int]metricInfo
+
+// withVariableLabels returns m with the variable labels names inserted after
+// the first n of every metric.
+func (m metrics) withVariableLabels(n int, names []string) metrics {
+	if len(names) == 0 {
+		return m
+	}
+	res := make(metrics, len(m))
+	for field, metric := range m {
+		res[field] = metric.withVariableLabels(n, names)
+	}
+	return res
+}
+
+// withConstLabels returns m with labels added to the constant labels of every
+// metric.
+func (m metrics) withConstLabels(labels prometheus.Labels) metrics {
+	if len(labels) == 0 {
+		return m
+	}
+	res := make(metrics, len(m))
+	for field, metric := range m {
+		res[field] = metric.withConstLabels(labels)
+	}
+	return res
+}
+
+// fieldMetric is the metric of a CSV field.
+type fieldMetric struct {
+	field  int
+	metric metricInfo
+}
+
+// sorted returns the metrics of m ordered by field.
+func (m metrics) sorted() []fieldMetric {
+	res := make([]fieldMetric, 0, len(m))
+	for field, metric := range m {
+		res = append(res, fieldMetric{field, metric})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].field < res[j].field })
+	return res
+}
+
+// DefaultServerMetricFields lists the fields of all server metrics, which are
+// exported by default, for FilterServerMetrics.
+var DefaultServerMetricFields = serverMetrics.String()
+
+func (m metrics) String() string {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	s := make([]string, len(keys))
+	for i, k := range keys {
+		s[i] = strconv.Itoa(k)
+	}
+	return strings.Join(s, ",")
+}
+
+var (
+	serverMetrics = ServerMetrics{
+		2:  newServerMetric("current_queue", "Current number of queued requests assigned to this server.", prometheus.GaugeValue, nil),
+		3:  newServerMetric("max_queue", "Maximum observed number of queued requests assigned to this server.", prometheus.GaugeValue, nil),
+		4:  newServerMetric("current_sessions", "Current number of active sessions.", prometheus.GaugeValue, nil),
+		5:  newServerMetric("max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue, nil),
+		6:  newServerMetric("limit_sessions", "Configured session limit.", prometheus.GaugeValue, nil),
+		7:  newServerMetric("sessions_total", "Total number of sessions.", prometheus.CounterValue, nil),
+		8:  newServerMetric("bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue, nil),
+		9:  newServerMetric("bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue, nil),
+		11: newServerMetric("responses_denied_total", "Total of responses denied for security.", prometheus.CounterValue, nil),
+		13: newServerMetric("connection_errors_total", "Total of connection errors.", prometheus.CounterValue, nil),
+		14: newServerMetric("response_errors_total", "Total of response errors.", prometheus.CounterValue, nil),
+		15: newServerMetric("retry_warnings_total", "Total of retry warnings.", prometheus.CounterValue, nil),
+		16: newServerMetric("redispatch_warnings_total", "Total of redispatch warnings.", prometheus.CounterValue, nil),
+		17: newServerMetric("up", "Current health status of the server (1 = UP, 0 = DOWN).", prometheus.GaugeValue, nil),
+		18: newServerMetric("weight", "Current weight of the server.", prometheus.GaugeValue, nil),
+		19: newServerMetric("active", "Whether the server is an active server (1) or not (0).", prometheus.GaugeValue, nil),
+		20: newServerMetric("backup", "Whether the server is a backup server (1) or not (0).", prometheus.GaugeValue, nil),
+		21: newServerMetric("check_failures_total", "Total number of failed health checks.", prometheus.CounterValue, nil),
+		22: newServerMetric("down_transitions_total", "Total number of transitions of the server from UP to DOWN.", prometheus.CounterValue, nil),
+		23: newServerMetric("state_change_seconds", "Number of seconds since the last change of the state of the server.", prometheus.GaugeValue, nil),
+		24: newServerMetric("downtime_seconds_total", "Total downtime in seconds.", prometheus.CounterValue, nil),
+		25: newServerMetric("limit_queue", "Configured queue limit.", prometheus.GaugeValue, nil),
+		29: newServerMetric("throttle_percent", "Current throttle of the server during slowstart, in percent of its weight.", prometheus.GaugeValue, nil),
+		30: newServerMetric("server_selected_total", "Total number of times a server was selected, either for new sessions, or when re-dispatching.", prometheus.CounterValue, nil),
+		33: newServerMetric("current_session_rate", "Current number of sessions per second over last elapsed second.", prometheus.GaugeValue, nil),
+		35: newServerMetric("max_session_rate", "Maximum observed number of sessions per second.", prometheus.GaugeValue, nil),
+		37: newServerMetric("check_code", "Status code of the last layer 5-7 health check, e.g. the HTTP status of HTTP checks.", prometheus.GaugeValue, nil),
+		38: fromMilliseconds(newServerMetric("check_duration_seconds", "Previously run health check duration, in seconds", prometheus.GaugeValue, nil)),
+		39: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "1xx"}),
+		40: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "2xx"}),
+		41: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "3xx"}),
+		42: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "4xx"}),
+		43: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "5xx"}),
+		44: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "other"}),
+		45: newServerMetric("check_failed_hana_total", "Total number of failed health analyses of traffic, enabled with observe.", prometheus.CounterValue, nil),
+		48: newServerMetric("http_requests_total", "Total HTTP requests.", prometheus.CounterValue, nil),
+		49: newServerMetric("client_aborts_total", "Total number of data transfers aborted by the client.", prometheus.CounterValue, nil),
+		50: newServerMetric("server_aborts_total", "Total number of data transfers aborted by the server.", prometheus.CounterValue, nil),
+		55: newServerMetric("last_session_seconds", "Number of seconds since the last session assigned to the server, -1 if there was none.", prometheus.GaugeValue, nil),
+		58: fromMilliseconds(newServerMetric("http_queue_time_average_seconds", "Avg. HTTP queue time for last 1024 successful connections.", prometheus.GaugeValue, nil)),
+		59: fromMilliseconds(newServerMetric("http_connect_time_average_seconds", "Avg. HTTP connect time for last 1024 successful connections.", prometheus.GaugeValue, nil)),
+		60: fromMilliseconds(newServerMetric("http_response_time_average_seconds", "Avg. HTTP response time for last 1024 successful connections.", prometheus.GaugeValue, nil)),
+		61: fromMilliseconds(newServerMetric("http_total_time_average_seconds", "Avg. HTTP total time for last 1024 successful connections.", prometheus.GaugeValue, nil)),
+		63: newServerMetric("agent_code", "Code reported by the last agent check of the server.", prometheus.GaugeValue, nil),
+		64: fromMilliseconds(newServerMetric("agent_duration_seconds", "Duration of the last agent check of the server, in seconds.", prometheus.GaugeValue, nil)),
+		67: newServerMetric("check_rise", "Configured number of consecutive successful health checks to consider the server UP.", prometheus.GaugeValue, nil),
+		68: newServerMetric("check_fall", "Configured number of consecutive failed health checks to consider the server DOWN.", prometheus.GaugeValue, nil),
+		69: newServerMetric("check_health", "Health of the server as counted by health checks, between 0 and rise+fall-1. The server is UP from rise on.", prometheus.GaugeValue, nil),
+		70: newServerMetric("agent_rise", "Configured number of consecutive successful agent checks to consider the server UP.", prometheus.GaugeValue, nil),
+		71: newServerMetric("agent_fall", "Configured number of consecutive failed agent checks to consider the server DOWN.", prometheus.GaugeValue, nil),
+		72: newServerMetric("agent_health", "Health of the server as counted by agent checks, between 0 and rise+fall-1.", prometheus.GaugeValue, nil),
+		83: newServerMetric("failed_header_rewrites_total", "Total number of failed HTTP header rewrites.", prometheus.CounterValue, nil),
+		84: newServerMetric("connection_attempts_total", "Total number of attempts to establish a connection to the server.", prometheus.CounterValue, nil),
+		85: newServerMetric("connection_reuses_total", "Total number of connections to the server reused instead of established.", prometheus.CounterValue, nil),
+		88: newServerMetric("idle_connections", "Current number of idle connections available for reuse.", prometheus.GaugeValue, nil),
+		89: newServerMetric("idle_connections_limit", "Configured limit on the number of idle connections available for reuse.", prometheus.GaugeValue, nil),
+		90: fromMilliseconds(newServerMetric("http_queue_time_max_seconds", "Maximum observed HTTP queue time.", prometheus.GaugeValue, nil)),
+		91: fromMilliseconds(newServerMetric("http_connect_time_max_seconds", "Maximum observed HTTP connect time.", prometheus.GaugeValue, nil)),
+		92: fromMilliseconds(newServerMetric("http_response_time_max_seconds", "Maximum observed HTTP response time.", prometheus.GaugeValue, nil)),
+		93: fromMilliseconds(newServerMetric("http_total_time_max_seconds", "Maximum observed HTTP total time.", prometheus.GaugeValue, nil)),
+		94: newServerMetric("internal_errors_total", "Total number of internal errors.", prometheus.CounterValue, nil),
+		95: newServerMetric("unsafe_idle_connections", "Current number of unsafe idle connections.", prometheus.GaugeValue, nil),
+		96: newServerMetric("safe_idle_connections", "Current number of safe idle connections.", prometheus.GaugeValue, nil),
+		97: newServerMetric("used_connections", "Current number of connections in use.", prometheus.GaugeValue, nil),
+		98: newServerMetric("needed_connections_estimate", "Estimated number of connections needed.", prometheus.GaugeValue, nil),
+		99: newServerMetric("user_weight", "Weight of the server as configured or set on the CLI, which the current weight differs from e.g. during slowstart.", prometheus.GaugeValue, nil),
+	}
+
+	frontendMetrics = metrics{
+		4:   newFrontendMetric("current_sessions", "Current number of active sessions.", prometheus.GaugeValue, nil),
+		5:   newFrontendMetric("max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue, nil),
+		6:   newFrontendMetric("limit_sessions", "Configured session limit.", prometheus.GaugeValue, nil),
+		7:   newFrontendMetric("sessions_total", "Total number of sessions.", prometheus.CounterValue, nil),
+		8:   newFrontendMetric("bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue, nil),
+		9:   newFrontendMetric("bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue, nil),
+		10:  newFrontendMetric("requests_denied_total", "Total of requests denied for security.", prometheus.CounterValue, nil),
+		11:  newFrontendMetric("responses_denied_total", "Total of responses denied for security.", prometheus.CounterValue, nil),
+		12:  newFrontendMetric("request_errors_total", "Total of request errors.", prometheus.CounterValue, nil),
+		33:  newFrontendMetric("current_session_rate", "Current number of sessions per second over last elapsed second.", prometheus.GaugeValue, nil),
+		34:  newFrontendMetric("limit_session_rate", "Configured limit on new sessions per second.", prometheus.GaugeValue, nil),
+		35:  newFrontendMetric("max_session_rate", "Maximum observed number of sessions per second.", prometheus.GaugeValue, nil),
+		39:  newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "1xx"}),
+		40:  newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "2xx"}),
+		41:  newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "3xx"}),
+		42:  newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "4xx"}),
+		43:  newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "5xx"}),
+		44:  newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "other"}),
+		46:  newFrontendMetric("current_http_request_rate", "Current number of HTTP requests per second over last elapsed second.", prometheus.GaugeValue, nil),
+		47:  newFrontendMetric("max_http_request_rate", "Maximum observed number of HTTP requests per second.", prometheus.GaugeValue, nil),
+		48:  newFrontendMetric("http_requests_total", "Total HTTP requests.", prometheus.CounterValue, nil),
+		51:  newFrontendMetric("compressor_bytes_in_total", "Number of HTTP response bytes fed to the compressor", prometheus.CounterValue, nil),
+		52:  newFrontendMetric("compressor_bytes_out_total", "Number of HTTP response bytes emitted by the compressor", prometheus.CounterValue, nil),
+		53:  newFrontendMetric("compressor_bytes_bypassed_total", "Number of bytes that bypassed the HTTP compressor", prometheus.CounterValue, nil),
+		54:  newFrontendMetric("http_responses_compressed_total", "Number of HTTP responses that were compressed", prometheus.CounterValue, nil),
+		77:  newFrontendMetric("current_connection_rate", "Current number of connections per second over last elapsed second.", prometheus.GaugeValue, nil),
+		78:  newFrontendMetric("max_connection_rate", "Maximum observed number of connections per second.", prometheus.GaugeValue, nil),
+		79:  newFrontendMetric("connections_total", "Total number of connections", prometheus.CounterValue, nil),
+		80:  newFrontendMetric("intercepted_requests_total", "Total number of HTTP requests intercepted by HAProxy itself, e.g. for the stats page, redirects or cache hits.", prometheus.CounterValue, nil),
+		81:  newFrontendMetric("connections_denied_total", "Total number of connections denied by tcp-request connection rules.", prometheus.CounterValue, nil),
+		82:  newFrontendMetric("sessions_denied_total", "Total number of sessions denied by tcp-request session rules.", prometheus.CounterValue, nil),
+		83:  newFrontendMetric("failed_header_rewrites_total", "Total number of failed HTTP header rewrites.", prometheus.CounterValue, nil),
+		86:  newFrontendMetric("http_cache_lookups_total", "Total number of HTTP cache lookups.", prometheus.CounterValue, nil),
+		87:  newFrontendMetric("http_cache_hits_total", "Total number of HTTP cache hits.", prometheus.CounterValue, nil),
+		94:  newFrontendMetric("internal_errors_total", "Total number of internal errors.", prometheus.CounterValue, nil),
+		104: newFrontendMetric("protocol_sessions_total", "Total number of sessions by protocol: http1, http2, http3 or other.", prometheus.CounterValue, prometheus.Labels{"protocol": "other"}),
+		105: newFrontendMetric("protocol_sessions_total", "Total number of sessions by protocol: http1, http2, http3 or other.", prometheus.CounterValue, prometheus.Labels{"protocol": "http1"}),
+		106: newFrontendMetric("protocol_sessions_total", "Total number of sessions by protocol: http1, http2, http3 or other.", prometheus.CounterValue, prometheus.Labels{"protocol": "http2"}),
+		107: newFrontendMetric("protocol_sessions_total", "Total number of sessions by protocol: http1, http2, http3 or other.", prometheus.CounterValue, prometheus.Labels{"protocol": "http3"}),
+		108: newFrontendMetric("protocol_requests_total", "Total number of requests by protocol: http1, http2, http3 or other.", prometheus.CounterValue, prometheus.Labels{"protocol": "other"}),
+		109: newFrontendMetric("protocol_requests_total", "Total number of requests by protocol: http1, http2, http3 or other.", prometheus.CounterValue, prometheus.Labels{"protocol": "http1"}),
+		110: newFrontendMetric("protocol_requests_total", "Total number of requests by protocol: http1, http2, http3 or other.", prometheus.CounterValue, prometheus.Labels{"protocol": "http2"}),
+		111: newFrontendMetric("protocol_requests_total", "Total number of requests by protocol: http1, http2, http3 or other.", prometheus.CounterValue, prometheus.Labels{"protocol": "http3"}),
+		113: newFrontendMetric("quic_rx_buffer_full_total", "Total number of QUIC datagrams dropped because the receive buffer was full.", prometheus.CounterValue, nil),
+		114: newFrontendMetric("quic_dropped_packets_total", "Total number of dropped QUIC packets.", prometheus.CounterValue, nil),
+		115: newFrontendMetric("quic_dropped_packets_buffer_overrun_total", "Total number of QUIC packets dropped because of a buffer overrun.", prometheus.CounterValue, nil),
+		116: newFrontendMetric("quic_dropped_parsing_total", "Total number of QUIC packets dropped because they couldn't be parsed.", prometheus.CounterValue, nil),
+		117: newFrontendMetric("quic_socket_full_total", "Total number of QUIC datagrams not sent because the socket was full.", prometheus.CounterValue, nil),
+		118: newFrontendMetric("quic_send_errors_total", "Total number of errors sending QUIC datagrams.", prometheus.CounterValue, nil),
+		119: newFrontendMetric("quic_send_unknown_errors_total", "Total number of unknown errors sending QUIC datagrams.", prometheus.CounterValue, nil),
+		120: newFrontendMetric("quic_sent_packets_total", "Total number of sent QUIC packets.", prometheus.CounterValue, nil),
+		121: newFrontendMetric("quic_lost_packets_total", "Total number of lost QUIC packets.", prometheus.CounterValue, nil),
+		122: newFrontendMetric("quic_too_short_datagrams_total", "Total number of received QUIC datagrams too short to be valid.", prometheus.CounterValue, nil),
+		123: newFrontendMetric("quic_retries_sent_total", "Total number of sent QUIC Retry packets.", prometheus.CounterValue, nil),
+		124: newFrontendMetric("quic_retries_validated_total", "Total number of validated QUIC Retry tokens.", prometheus.CounterValue, nil),
+		125: newFrontendMetric("quic_retry_errors_total", "Total number of invalid QUIC Retry tokens.", prometheus.CounterValue, nil),
+		126: newFrontendMetric("quic_half_open_connections", "Current number of QUIC connections with an unfinished handshake.", prometheus.GaugeValue, nil),
+		127: newFrontendMetric("quic_failed_handshakes_total", "Total number of failed QUIC handshakes.", prometheus.CounterValue, nil),
+		128: newFrontendMetric("quic_stateless_resets_sent_total", "Total number of sent QUIC stateless resets.", prometheus.CounterValue, nil),
+		129: newFrontendMetric("quic_connection_migrations_total", "Total number of QUIC connection migrations.", prometheus.CounterValue, nil),
+	}
+	backendMetrics = metrics{
+		2:  newBackendMetric("current_queue", "Current number of queued requests not assigned to any server.", prometheus.GaugeValue, nil),
+		3:  newBackendMetric("max_queue", "Maximum observed number of queued requests not assigned to any server.", prometheus.GaugeValue, nil),
+		4:  newBackendMetric("current_sessions", "Current number of active sessions.", prometheus.GaugeValue, nil),
+		5:  newBackendMetric("max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue, nil),
+		6:  newBackendMetric("limit_sessions", "Configured session limit.", prometheus.GaugeValue, nil),
+		7:  newBackendMetric("sessions_total", "Total number of sessions.", prometheus.CounterValue, nil),
+		8:  newBackendMetric("bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue, nil),
+		9:  newBackendMetric("bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue, nil),
+		10: newBackendMetric("requests_denied_total", "Total of requests denied for security.", prometheus.CounterValue, nil),
+		11: newBackendMetric("responses_denied_total", "Total of responses denied for security.", prometheus.CounterValue, nil),
+		13: newBackendMetric("connection_errors_total", "Total of connection errors.", prometheus.CounterValue, nil),
+		14: newBackendMetric("response_errors_total", "Total of response errors.", prometheus.CounterValue, nil),
+		15: newBackendMetric("retry_warnings_total", "Total of retry warnings.", prometheus.CounterValue, nil),
+		16: newBackendMetric("redispatch_warnings_total", "Total of redispatch warnings.", prometheus.CounterValue, nil),
+		17: newBackendMetric("up", "Current health status of the backend (1 = UP, 0 = DOWN).", prometheus.GaugeValue, nil),
+		18: newBackendMetric("weight", "Total weight of the servers in the backend.", prometheus.GaugeValue, nil),
+		19: newBackendMetric("current_server", "Current number of active servers", prometheus.GaugeValue, nil),
+		20: newBackendMetric("backup_servers", "Current number of backup servers.", prometheus.GaugeValue, nil),
+		22: newBackendMetric("down_transitions_total", "Total number of transitions of the backend from UP to DOWN.", prometheus.CounterValue, nil),
+		23: newBackendMetric("state_change_seconds", "Number of seconds since the last change of the state of the backend.", prometheus.GaugeValue, nil),
+		24: newBackendMetric("downtime_seconds_total", "Total downtime in seconds.", prometheus.CounterValue, nil),
+		30: newBackendMetric("server_selected_total", "Total number of times a server was selected, either for new sessions, or when re-dispatching.", prometheus.CounterValue, nil),
+		33: newBackendMetric("current_session_rate", "Current number of sessions per second over last elapsed second.", prometheus.GaugeValue, nil),
+		35: newBackendMetric("max_session_rate", "Maximum number of sessions per second.", prometheus.GaugeValue, nil),
+		39: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "1xx"}),
+		40: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "2xx"}),
+		41: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "3xx"}),
+		42: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "4xx"}),
+		43: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "5xx"}),
+		44: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "other"}),
+		48: newBackendMetric("http_requests_total", "Total HTTP requests.", prometheus.CounterValue, nil),
+		49: newBackendMetric("client_aborts_total", "Total number of data transfers aborted by the client.", prometheus.CounterValue, nil),
+		50: newBackendMetric("server_aborts_total", "Total number of data transfers aborted by the server.", prometheus.CounterValue, nil),
+		51: newBackendMetric("compressor_bytes_in_total", "Number of HTTP response bytes fed to the compressor", prometheus.CounterValue, nil),
+		52: newBackendMetric("compressor_bytes_out_total", "Number of HTTP response bytes emitted by the compressor", prometheus.CounterValue, nil),
+		53: newBackendMetric("compressor_bytes_bypassed_total", "Number of bytes that bypassed the HTTP compressor", prometheus.CounterValue, nil),
+		54: newBackendMetric("http_responses_compressed_total", "Number of HTTP responses that were compressed", prometheus.CounterValue, nil),
+		55: newBackendMetric("last_session_seconds", "Number of seconds since the last session assigned to a server of the backend, -1 if there was none.", prometheus.GaugeValue, nil),
+		58: fromMilliseconds(newBackendMetric("http_queue_time_average_seconds", "Avg. HTTP queue time for last 1024 successful connections.", prometheus.GaugeValue, nil)),
+		59: fromMilliseconds(newBackendMetric("http_connect_time_average_seconds", "Avg. HTTP connect time for last 1024 successful connections.", prometheus.GaugeValue, nil)),
+		60: fromMilliseconds(newBackendMetric("http_response_time_average_seconds", "Avg. HTTP response time for last 1024 successful connections.", prometheus.GaugeValue, nil)),
+		61: fromMilliseconds(newBackendMetric("http_total_time_average_seconds", "Avg. HTTP total time for last 1024 successful connections.", prometheus.GaugeValue, nil)),
+		83: newBackendMetric("failed_header_rewrites_total", "Total number of failed HTTP header rewrites.", prometheus.CounterValue, nil),
+		84: newBackendMetric("connection_attempts_total", "Total number of attempts to establish a connection to a server of the backend.", prometheus.CounterValue, nil),
+		85: newBackendMetric("connection_reuses_total", "Total number of connections to the servers of the backend reused instead of established.", prometheus.CounterValue, nil),
+		86: newBackendMetric("http_cache_lookups_total", "Total number of HTTP cache lookups.", prometheus.CounterValue, nil),
+		87: newBackendMetric("http_cache_hits_total", "Total number of HTTP cache hits.", prometheus.CounterValue, nil),
+		90: fromMilliseconds(newBackendMetric("http_queue_time_max_seconds", "Maximum observed HTTP queue time.", prometheus.GaugeValue, nil)),
+		91: fromMilliseconds(newBackendMetric("http_connect_time_max_seconds", "Maximum observed HTTP connect time.", prometheus.GaugeValue, nil)),
+		92: fromMilliseconds(newBackendMetric("http_response_time_max_seconds", "Maximum observed HTTP response time.", prometheus.GaugeValue, nil)),
+		93: fromMilliseconds(newBackendMetric("http_total_time_max_seconds", "Maximum observed HTTP total time.", prometheus.GaugeValue, nil)),
+		94: newBackendMetric("internal_errors_total", "Total number of internal errors.", prometheus.CounterValue, nil),
+		99: newBackendMetric("user_weight", "Total weight of the servers in the backend as configured or set on the CLI.", prometheus.GaugeValue, nil),
+	}
+
+	frontendStatus = stateSet{
+		metric: newMetricInfo(prometheus.BuildFQName(namespace, "frontend", "status"), "Current status of the frontend, 1 for the state it is in.", prometheus.GaugeValue, append(frontendLabelNames, "state"), nil),
+		states: []string{"OPEN", "FULL", "STOP", "PAUSED"},
+	}
+	backendStatus = stateSet{
+		metric: newMetricInfo(prometheus.BuildFQName(namespace, "backend", "status"), "Current status of the backend, 1 for the state it is in.", prometheus.GaugeValue, append(backendLabelNames, "state"), nil),
+		states: []string{"UP", "DOWN"},
+	}
+	serverStatus = stateSet{
+		metric: newMetricInfo(prometheus.BuildFQName(namespace, "server", "status"), "Current status of the server, 1 for the state it is in.", prometheus.GaugeValue, append(serverLabelNames, "state"), nil),
+		states: []string{"UP", "DOWN", "MAINT", "DRAIN", "NOLB", "no_check"},
+	}
+
+	serverCheckStatus = stateSet{
+		metric: newMetricInfo(prometheus.BuildFQName(namespace, "server", "check_status"), "Result of the last health check of the server, 1 for the current result.", prometheus.GaugeValue, append(serverLabelNames, "result"), nil),
+		states: []string{
+			"UNK", "INI", "SOCKERR",
+			"L4OK", "L4TOUT", "L4CON",
+			"L6OK", "L6TOUT", "L6RSP",
+			"L7OK", "L7OKC", "L7TOUT", "L7RSP", "L7STS",
+			"PROCERR", "PROCTOUT", "PROCOK",
+		},
+	}
+	serverCheckEnabled    = newMetricInfo(prometheus.BuildFQName(namespace, "server", "check_enabled"), "Whether health checks are enabled for the server (1 = enabled, 0 = disabled).", prometheus.GaugeValue, serverLabelNames, nil)
+	serverCheckTransition = newMetricInfo(prometheus.BuildFQName(namespace, "server", "check_transition"), "Progress of the health checks towards changing the state of the server, from 0 to 1.", prometheus.GaugeValue, serverLabelNames, nil)
+
+	frontendInfo = newMetricInfo(prometheus.BuildFQName(namespace, "frontend", "info"), "Information about the frontend, the mode it runs in.", prometheus.GaugeValue, append(frontendLabelNames, "mode"), nil)
+	backendInfo  = newMetricInfo(prometheus.BuildFQName(namespace, "backend", "info"), "Information about the backend, the mode it runs in and its load balancing algorithm.", prometheus.GaugeValue, append(backendLabelNames, "mode", "algo"), nil)
+
+	haproxyInfo    = newMetricInfo(prometheus.BuildFQName(namespace, "version", "info"), "HAProxy version info.", prometheus.GaugeValue, []string{"release_date", "version"}, nil)
+	haproxyUp      = newMetricInfo(prometheus.BuildFQName(namespace, "", "up"), "Was the last scrape of HAProxy successful.", prometheus.GaugeValue, nil, nil)
+	haproxyIdlePct = newMetricInfo(prometheus.BuildFQName(namespace, "process_idle_time", "percent"), "Time spent waiting for events instead of processing them.", prometheus.GaugeValue, nil, nil)
+
+	// infoMetrics are the metrics of numeric fields of show info, by the name
+	// of the field.
+	infoMetrics = map[string]metricInfo{
+		"Ulimit-n":                    newProcessMetric("fd_limit", "Maximum number of open file descriptors.", prometheus.GaugeValue),
+		"Maxsock":                     newProcessMetric("sockets_limit", "Maximum number of open sockets, which is derived from the connection limit.", prometheus.GaugeValue),
+		"Maxconn":                     newProcessMetric("connections_limit", "Maximum number of concurrent connections.", prometheus.GaugeValue),
+		"CurrConns":                   newProcessMetric("connections", "Current number of connections.", prometheus.GaugeValue),
+		"CumConns":                    newProcessMetric("connections_total", "Total number of connections.", prometheus.CounterValue),
+		"Jobs":                        newProcessMetric("jobs", "Current number of active jobs, i.e. connections, listeners and peers.", prometheus.GaugeValue),
+		"SslFrontendKeyRate":          newProcessMetric("ssl_frontend_key_rate", "Current number of SSL keys computed per second by frontends.", prometheus.GaugeValue),
+		"SslFrontendMaxKeyRate":       newProcessMetric("ssl_frontend_max_key_rate", "Maximum observed number of SSL keys computed per second by frontends.", prometheus.GaugeValue),
+		"SslFrontendSessionReuse_pct": newProcessMetric("ssl_frontend_session_reuse_percent", "Percentage of SSL sessions of frontends resumed from the cache.", prometheus.GaugeValue),
+		"SslBackendKeyRate":           newProcessMetric("ssl_backend_key_rate", "Current number of SSL keys computed per second by backends.", prometheus.GaugeValue),
+		"SslBackendMaxKeyRate":        newProcessMetric("ssl_backend_max_key_rate", "Maximum observed number of SSL keys computed per second by backends.", prometheus.GaugeValue),
+		"SslCacheLookups":             newProcessMetric("ssl_cache_lookups_total", "Total number of SSL session cache lookups.", prometheus.CounterValue),
+		"SslCacheMisses":              newProcessMetric("ssl_cache_misses_total", "Total number of SSL session cache misses.", prometheus.CounterValue),
+		"TotalWarnings":               newProcessMetric("warnings_total", "Total number of warnings emitted, including those of the configuration.", prometheus.CounterValue),
+		"Start_time_sec":              newProcessMetric("last_reload_time_seconds", "Time of the start or last reload of the process since the epoch in seconds.", prometheus.GaugeValue),
+		"Stopping":                    newProcessMetric("stopping", "Whether the process is stopping, e.g. after a reload (1 = stopping, 0 = not).", prometheus.GaugeValue),
+	}
+)
+
+// stateSet is a metric with a label for the state, which is 1 for the state a
+// proxy or server is in and 0 for all others.
+type stateSet struct {
+	metric metricInfo
+	states []string
+}
+
+func (s stateSet) withConstLabels(labels prometheus.Labels) stateSet {
+	return stateSet{metric: s.metric.withConstLabels(labels), states: s.states}
+}
+
+func (s stateSet) withVariableLabels(n int, names []string) stateSet {
+	return stateSet{metric: s.metric.withVariableLabels(n, names), states: s.states}
+}
+
+// has returns whether state is one of the states of s.
+func (s stateSet) has(state string) bool {
+	for _, st := range s.states {
+		if st == state {
+			return true
+		}
+	}
+	return false
+}
+
+// export sends a metric of a row for every state, given the current one.
+func (s stateSet) export(ch chan<- prometheus.Metric, current string, row *rowLabelPairs) {
+	for _, state := range s.states {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+		ch <- row.metric(s.metric, value, state)
+	}
+}
+
+// statusState returns the state of a value of the status field, without the
+// progress of health checks or the reason of the state, e.g. "UP" for
+// "UP 1/3" and "MAINT" for "MAINT(via)".
+func statusState(status string) string {
+	if status == "no check" {
+		return "no_check"
+	}
+	if i := strings.IndexAny(status, " ("); i != -1 {
+		return status[:i]
+	}
+	return status
+}
+
+// Fetcher fetches the output of a command of HAProxy, e.g. the CSV stats.
+// Custom implementations allow scraping HAProxy over other transports.
+type Fetcher interface {
+	Fetch(ctx context.Context) (io.ReadCloser, error)
+}
+
+// FetcherFunc adapts a function to a Fetcher.
+type FetcherFunc func(ctx context.Context) (io.ReadCloser, error)
+
+// Fetch calls f(ctx).
+func (f FetcherFunc) Fetch(ctx context.Context) (io.ReadCloser, error) {
+	return f(ctx)
+}
+
+// Exporter collects HAProxy stats from the given URI and exports them using
+// the prometheus metrics package.
+//
+// An Exporter is a prometheus.Collector and is never registered implicitly.
+// Register it with any prometheus.Registerer, e.g. a registry of its own. Several
+// Exporters share a Registerer if their metrics are told apart by the
+// ConstLabels of their ExporterOpts, or by wrapping the Registerer with
+// prometheus.WrapRegistererWith.
+type Exporter struct {
+	URI       string
+	mutex     sync.Mutex // Serializes scrapes of this target only.
+	fetchInfo Fetcher
+	fetchStat Fetcher
+	timeout   time.Duration
+
+	up               prometheus.Gauge
+	totalScrapes     prometheus.Counter
+	csvParseFailures *prometheus.CounterVec
+	seriesLimited    *prometheus.CounterVec
+	scrapeDuration   prometheus.Histogram
+	rowsParsed       prometheus.Gauge
+	scrapeBytes      prometheus.Counter
+	fetchPhases      *prometheus.GaugeVec
+	lastErrorInfo    *prometheus.GaugeVec
+	lastErrorTime    prometheus.Gauge
+	cacheHits        prometheus.Counter // Nil without a scrape cache.
+	cacheAge         prometheus.Gauge   // Nil without a scrape cache.
+	scrapeErrors     *prometheus.CounterVec
+	runtimeSuccess   *prometheus.GaugeVec
+	runtimeFailing   map[string]bool // The runtime collectors whose last run failed, protected by mutex.
+	rowMetrics
+	shared                  *sharedMetrics // The metrics above and below are those of shared.
+	info, upMetric, idlePct metricInfo
+	infoMetrics             map[string]metricInfo
+	runtimeCollectors       []runtimeCollector
+	schemaMetrics           *schemaMetrics // Nil unless enabled, protected by mutex.
+	unlimitedValue          *float64       // Exported for empty limits, if not nil.
+	excludedServerStates    map[string]struct{}
+	serverNameInclude       *regexp.Regexp  // Nil if all names are included.
+	serverNameExclude       *regexp.Regexp  // Nil if no names are excluded.
+	scrapeLevel             map[string]bool // Nil if all stats sections are exported.
+	proxies                 map[string]bool // Nil if all proxies are exported.
+	missingProxies          map[string]bool // Of proxies, missing from the last stats, protected by mutex.
+	aggregateServers        bool
+	includeUnprovisioned    bool
+	maxServersPerBackend    int
+	maxServers              int
+	limitedBackends         map[string]bool // Whose server metrics were dropped by the last stats, protected by mutex.
+	labels                  rowLabels
+	labelPairs              rowLabelPairs // Reused for the rows exported by the scrape itself, protected by mutex.
+	multiProcess            string
+	streamStats             bool
+	parseWorkers            int
+	metricBuffer            int
+	logger                  log.Logger
+	statsVersion            string // Detected from the stats header, protected by mutex.
+	scrapeErrorLogEvery     int
+	failures                int    // Consecutive failed scrapes, protected by mutex.
+	failureType             string // The error type of the last failed scrape, protected by mutex.
+
+	lastMutex   sync.Mutex   // Protects lastRows, lastColumns and lastScrape.
+	lastRows    []parser.Row // The canonical rows of the last successful scrape.
+	lastColumns columns
+	lastScrape  time.Time
+}
+
+// rowMetrics are the metrics of the rows of the stats.
+type rowMetrics struct {
+	frontendMetrics               metrics
+	backendMetrics                metrics
+	serverMetrics                 metrics
+	uncheckedServerMetrics        metrics // Exported for servers without health checks.
+	aggregatedServerMetrics       metrics // Exported per backend if the servers are aggregated.
+	frontendStatus, backendStatus stateSet
+	frontendInfo, backendInfo     metricInfo
+	serverStatus                  stateSet
+	serverCheckStatus             stateSet
+	serverCheckEnabled            metricInfo
+	serverCheckTransition         metricInfo
+	// process is the value of the const label process of the metrics, if
+	// they are those of a process.
+	process string
+
+	// The metrics of the maps above ordered by field, set by withFields.
+	frontendFields, backendFields                               []fieldMetric
+	serverFields, uncheckedServerFields, aggregatedServerFields []fieldMetric
+}
+
+// withFields returns r with the metrics of its maps ordered by field, as the
+// rows are exported.
+func (r rowMetrics) withFields() rowMetrics {
+	r.frontendFields = r.frontendMetrics.sorted()
+	r.backendFields = r.backendMetrics.sorted()
+	r.serverFields = r.serverMetrics.sorted()
+	r.uncheckedServerFields = r.uncheckedServerMetrics.sorted()
+	r.aggregatedServerFields = r.aggregatedServerMetrics.sorted()
+	return r
+}
+
+func (r rowMetrics) withConstLabels(labels prometheus.Labels) rowMetrics {
+	return rowMetrics{
+		frontendMetrics:         r.frontendMetrics.withConstLabels(labels),
+		backendMetrics:          r.backendMetrics.withConstLabels(labels),
+		serverMetrics:           r.serverMetrics.withConstLabels(labels),
+		uncheckedServerMetrics:  r.uncheckedServerMetrics.withConstLabels(labels),
+		aggregatedServerMetrics: r.aggregatedServerMetrics.withConstLabels(labels),
+		frontendStatus:          r.frontendStatus.withConstLabels(labels),
+		backendStatus:           r.backendStatus.withConstLabels(labels),
+		frontendInfo:            r.frontendInfo.withConstLabels(labels),
+		backendInfo:             r.backendInfo.withConstLabels(labels),
+		serverStatus:            r.serverStatus.withConstLabels(labels),
+		serverCheckStatus:       r.serverCheckStatus.withConstLabels(labels),
+		serverCheckEnabled:      r.serverCheckEnabled.withConstLabels(labels),
+		serverCheckTransition:   r.serverCheckTransition.withConstLabels(labels),
+		process:                 r.process,
+	}.withFields()
+}
+
+// withRowLabels returns r with the labels of l added after the proxy and
+// server labels of the metrics.
+func (r rowMetrics) withRowLabels(l rowLabels) rowMetrics {
+	proxy, server := l.names(false), l.names(true)
+	return rowMetrics{
+		frontendMetrics:         r.frontendMetrics.withVariableLabels(len(frontendLabelNames), proxy),
+		backendMetrics:          r.backendMetrics.withVariableLabels(len(backendLabelNames), proxy),
+		serverMetrics:           r.serverMetrics.withVariableLabels(len(serverLabelNames), server),
+		uncheckedServerMetrics:  r.uncheckedServerMetrics.withVariableLabels(len(serverLabelNames), server),
+		aggregatedServerMetrics: r.aggregatedServerMetrics.withVariableLabels(len(backendLabelNames), proxy),
+		frontendStatus:          r.frontendStatus.withVariableLabels(len(frontendLabelNames), proxy),
+		backendStatus:           r.backendStatus.withVariableLabels(len(backendLabelNames), proxy),
+		frontendInfo:            r.frontendInfo.withVariableLabels(len(frontendLabelNames), proxy),
+		backendInfo:             r.backendInfo.withVariableLabels(len(backendLabelNames), proxy),
+		serverStatus:            r.serverStatus.withVariableLabels(len(serverLabelNames), server),
+		serverCheckStatus:       r.serverCheckStatus.withVariableLabels(len(serverLabelNames), server),
+		serverCheckEnabled:      r.serverCheckEnabled.withVariableLabels(len(serverLabelNames), server),
+		serverCheckTransition:   r.serverCheckTransition.withVariableLabels(len(serverLabelNames), server),
+		process:                 r.process,
+	}.withFields()
+}
+
+// ExporterOpts are the settings of an Exporter.
+type ExporterOpts struct {
+	// SSLVerify enables certificate verification for HTTPS scrape URIs.
+	SSLVerify bool
+	// ProxyFromEnv makes HTTP scrapes use the proxy settings from the
+	// environment.
+	ProxyFromEnv bool
+	// Username and Password are used for basic authentication of HTTP
+	// scrapes, if Username is not empty.
+	Username, Password string
+	// ServerMetrics are the exported server metrics, keyed by CSV field.
+	ServerMetrics ServerMetrics
+	// ExcludedServerStates is a comma-separated list of server states whose
+	// servers are not exported.
+	ExcludedServerStates string
+	// IncludeUnprovisionedServers exports the slots of server templates which
+	// have no address yet, which are left out by default.
+	IncludeUnprovisionedServers bool
+	// ServerNameInclude and ServerNameExclude, if not empty, are regular
+	// expressions the names of the exported servers must match and must not
+	// match, respectively. They are anchored at both ends.
+	ServerNameInclude, ServerNameExclude string
+	// ExcludeUncheckedServerUp leaves out the up metric of servers without
+	// health checks, which would otherwise always be 1.
+	ExcludeUncheckedServerUp bool
+	// NamingScheme is the naming scheme of the metrics: legacy, which is the
+	// default, v2, or both.
+	NamingScheme string
+	// ScrapeErrorLogEvery, if greater than 1, limits the failed scrapes
+	// logged as errors to the first and every ScrapeErrorLogEvery-th of
+	// consecutive ones, and to those failing with another type of error. The
+	// others are logged at debug level.
+	ScrapeErrorLogEvery int
+	// TimingMilliseconds exports the average queue, connect, response and
+	// total times in milliseconds as well, as HAProxy reports them.
+	TimingMilliseconds bool
+	// UnlimitedValue, if not empty, is exported for limits HAProxy leaves
+	// empty because there is none, e.g. "+Inf". Otherwise their metrics are
+	// left out.
+	UnlimitedValue string
+	// RuntimeCollectors is a comma-separated list of the runtime collectors
+	// to enable, e.g. "activity". They need a stats socket.
+	RuntimeCollectors string
+	// ScrapeLevel is a comma-separated list of the sections of the stats to
+	// export, of frontend, backend and server. Empty exports all of them.
+	// Stats sockets are only asked for the rows of these sections.
+	ScrapeLevel string
+	// Proxies is a comma-separated list of the names of the proxies to
+	// export. Empty exports all of them. Stats sockets are only asked for the
+	// rows of these proxies.
+	Proxies string
+	// MaxServersPerBackend, if positive, is the number of servers of a
+	// backend above which its server metrics are dropped. MaxServers, if
+	// positive, is the number of servers above which the server metrics of
+	// further backends are dropped.
+	MaxServersPerBackend, MaxServers int
+	// AggregateServers exports the server metrics aggregated over the servers
+	// of every backend, without the label server, instead of per server.
+	AggregateServers bool
+	// LabelRules extract labels from the names of proxies and servers.
+	LabelRules LabelRules
+	// IDLabels labels the stats metrics with the numeric IDs of the proxy,
+	// server and process, as used by the runtime API.
+	IDLabels bool
+	// MultiProcess is how rows of several HAProxy processes for the same proxy
+	// or server are exported: none, which is the default and fails on them,
+	// label, which labels the metrics with the process, or aggregate.
+	MultiProcess string
+	// SchemaMetrics exports the columns of the stats unknown to the exporter
+	// as well, named after them and typed by show stat typed. It needs a
+	// stats socket.
+	SchemaMetrics bool
+	// StreamStats exports the rows of the stats as they are read, without
+	// keeping them, so that memory use doesn't grow with the stats. The JSON
+	// API then has no rows. AggregateServers, the server limits and the
+	// aggregate mode of MultiProcess read all rows first regardless.
+	StreamStats bool
+	// ParseWorkers, if greater than 1, is the number of goroutines exporting
+	// the rows of the stats of a scrape in parallel.
+	ParseWorkers int
+	// MetricBuffer, if greater than 0, is the number of metrics of a scrape
+	// buffered on their way to the registry, so that the scrape can finish
+	// while the metrics before are still being encoded.
+	MetricBuffer int
+	// Timeout for getting the stats from HAProxy.
+	Timeout time.Duration
+	// Cache, if not nil, is used to share the fetched stats with other
+	// scrapes of the same target.
+	Cache *ScrapeCache
+	// Counters, if not nil, keeps the counters about the scrapes, e.g.
+	// haproxy_exporter_scrapes_total, of Exporters replacing each other.
+	Counters *Counters
+	// ConstLabels are added to every metric of the Exporter.
+	ConstLabels prometheus.Labels
+	// Target, if not empty, is the value of the label target of haproxy_up
+	// and of the haproxy_exporter_* metrics, telling apart the targets of the
+	// metrics endpoint even where their other labels are aggregated away.
+	Target string
+	// StatFetcher, if not nil, fetches the CSV stats instead of the URI,
+	// which then only identifies the target. InfoFetcher optionally fetches
+	// the output of "show info" along with them.
+	StatFetcher, InfoFetcher Fetcher
+}
+
+// NewExporter returns an initialized Exporter.
+func NewExporter(uri string, opts ExporterOpts, logger log.Logger) (*Exporter, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	scrapeLevel, err := parseScrapeLevel(opts.ScrapeLevel)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := parseProxies(opts.Proxies)
+	if err != nil {
+		return nil, err
+	}
+	statCmd := showStatCmdOf(scrapeLevel, proxies)
+
+	fetchInfo, fetchStat := opts.InfoFetcher, opts.StatFetcher
+	switch {
+	case fetchStat != nil:
+	case u.Scheme == "http", u.Scheme == "https", u.Scheme == "file":
+		fetchStat = fetchHTTP(uri, opts)
+	case u.Scheme == "unix":
+		fetchInfo = SocketFetcher("unix", u.Path, showInfoCmd, opts.Timeout)
+		fetchStat = SocketFetcher("unix", u.Path, statCmd, opts.Timeout)
+	case u.Scheme == "tcp":
+		fetchInfo = SocketFetcher("tcp", u.Host, showInfoCmd, opts.Timeout)
+		fetchStat = SocketFetcher("tcp", u.Host, statCmd, opts.Timeout)
+	default:
+		return nil, fmt.Errorf("unsupported scheme: %q", u.Scheme)
+	}
+
+	// The metrics of the scrapes tell the targets of the metrics endpoint
+	// apart by the label target, in addition to their constant labels.
+	scrapeLabels := opts.ConstLabels
+	if opts.Target != "" {
+		if _, ok := opts.ConstLabels[TargetLabel]; ok {
+			return nil, fmt.Errorf("label %q is a constant label as well", TargetLabel)
+		}
+		scrapeLabels = prometheus.Labels{TargetLabel: opts.Target}
+		for name, value := range opts.ConstLabels {
+			scrapeLabels[name] = value
+		}
+	}
+
+	var (
+		cacheHits prometheus.Counter
+		cacheAge  prometheus.Gauge
+	)
+	counters := opts.Counters.get(uri, scrapeLabels)
+	if opts.Cache != nil {
+		cacheHits = counters.cacheHits
+		cacheAge = prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "exporter_cache_age_seconds",
+			Help:        "Age of the stats served by the last scrape, 0 if they were fetched from HAProxy.",
+			ConstLabels: scrapeLabels,
+		})
+		if fetchInfo != nil {
+			fetchInfo = opts.Cache.wrap(cacheKey(uri, showInfoCmd, opts), fetchInfo, nil)
+		}
+		fetchStat = opts.Cache.wrap(cacheKey(uri, statCmd, opts), fetchStat, func(hit bool, age time.Duration) {
+			if hit {
+				cacheHits.Inc()
+			}
+			cacheAge.Set(age.Seconds())
+		})
+	}
+
+	excludedServerStatesMap := map[string]struct{}{}
+	for _, f := range strings.Split(opts.ExcludedServerStates, ",") {
+		excludedServerStatesMap[f] = struct{}{}
+	}
+
+	serverNameInclude, err := compileAnchored(opts.ServerNameInclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server name include filter: %w", err)
+	}
+	serverNameExclude, err := compileAnchored(opts.ServerNameExclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server name exclude filter: %w", err)
+	}
+
+	if err := checkNamingScheme(opts.NamingScheme); err != nil {
+		return nil, err
+	}
+	if err := opts.LabelRules.check(opts.ConstLabels); err != nil {
+		return nil, err
+	}
+	labels := rowLabels{rules: opts.LabelRules, ids: opts.IDLabels}
+	if err := checkMultiProcess(opts.MultiProcess); err != nil {
+		return nil, err
+	}
+	shared := sharedMetricsFor(opts, labels)
+	labels.interned = shared.interned
+
+	runtimeCollectors, err := newRuntimeCollectors(opts.RuntimeCollectors, u, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema *schemaMetrics
+	if opts.SchemaMetrics {
+		if opts.StatFetcher != nil || (u.Scheme != "unix" && u.Scheme != "tcp") {
+			return nil, errors.New("schema metrics need a stats socket to scrape")
+		}
+		address := u.Host
+		if u.Scheme == "unix" {
+			address = u.Path
+		}
+		schema = newSchemaMetrics(SocketFetcher(u.Scheme, address, showStatTypedCmd, opts.Timeout), opts.ConstLabels, labels)
+	}
+
+	var unlimitedValue *float64
+	if opts.UnlimitedValue != "" {
+		v, err := strconv.ParseFloat(opts.UnlimitedValue, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for unlimited limits %q: %w", opts.UnlimitedValue, err)
+		}
+		unlimitedValue = &v
+	}
+
+	fetchPhaseDurations := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "exporter_fetch_phase_seconds",
+		Help:        "Duration of the phases of fetching the stats by the last scrape: dial, tls_handshake, first_byte or body_read.",
+		ConstLabels: scrapeLabels,
+	}, []string{"phase"})
+	for _, phase := range fetchPhaseNames {
+		fetchPhaseDurations.WithLabelValues(phase)
+	}
+
+	return &Exporter{
+		URI:       uri,
+		fetchInfo: fetchInfo,
+		fetchStat: fetchStat,
+		timeout:   opts.Timeout,
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "up",
+			Help:      "Was the last scrape of haproxy successful.",
+		}),
+		totalScrapes:     counters.totalScrapes,
+		csvParseFailures: counters.csvParseFailures,
+		scrapeErrors:     counters.scrapeErrors,
+		scrapeDuration:   counters.scrapeDuration,
+		rowsParsed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "exporter_csv_rows_parsed",
+			Help:        "Number of rows of the stats parsed by the last scrape.",
+			ConstLabels: scrapeLabels,
+		}),
+		scrapeBytes: counters.scrapeBytes,
+		fetchPhases: fetchPhaseDurations,
+		lastErrorInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "exporter_last_error_info",
+			Help:        "Message and type of the error of the last failed scrape, truncated to 256 bytes.",
+			ConstLabels: scrapeLabels,
+		}, []string{"message", "type"}),
+		lastErrorTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "exporter_last_error_timestamp_seconds",
+			Help:        "Timestamp of the last failed scrape, 0 if none failed.",
+			ConstLabels: scrapeLabels,
+		}),
+		cacheHits:     cacheHits,
+		cacheAge:      cacheAge,
+		seriesLimited: counters.seriesLimited,
+		runtimeSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "exporter_runtime_collector_success",
+			Help:        "Whether the last run of a runtime collector succeeded.",
+			ConstLabels: scrapeLabels,
+		}, []string{"collector"}),
+		runtimeFailing:       map[string]bool{},
+		rowMetrics:           shared.rowMetrics,
+		shared:               shared,
+		labels:               labels,
+		unlimitedValue:       unlimitedValue,
+		info:                 shared.info,
+		upMetric:             haproxyUp.withConstLabels(scrapeLabels),
+		idlePct:              shared.idlePct,
+		infoMetrics:          shared.infoMetrics,
+		runtimeCollectors:    runtimeCollectors,
+		schemaMetrics:        schema,
+		excludedServerStates: excludedServerStatesMap,
+		serverNameInclude:    serverNameInclude,
+		serverNameExclude:    serverNameExclude,
+		scrapeLevel:          scrapeLevel,
+		proxies:              proxySet(proxies),
+		missingProxies:       map[string]bool{},
+		aggregateServers:     opts.AggregateServers,
+		includeUnprovisioned: opts.IncludeUnprovisionedServers,
+		maxServersPerBackend: opts.MaxServersPerBackend,
+		maxServers:           opts.MaxServers,
+		multiProcess:         opts.MultiProcess,
+		streamStats:          opts.StreamStats,
+		parseWorkers:         opts.ParseWorkers,
+		metricBuffer:         opts.MetricBuffer,
+		scrapeErrorLogEvery:  opts.ScrapeErrorLogEvery,
+		logger:               logger,
+	}, nil
+}
+
+// Describe describes all the metrics ever exported by the HAProxy exporter,
+// except those of ExporterOpts.SchemaMetrics and those labeled with the
+// process by ExporterOpts.MultiProcess, which depend on the stats. It
+// implements prometheus.Collector.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range e.frontendMetrics {
+		m.describe(ch)
+	}
+	for _, m := range e.backendMetrics {
+		m.describe(ch)
+	}
+	ch <- e.frontendStatus.metric.Desc
+	ch <- e.backendStatus.metric.Desc
+	ch <- e.frontendInfo.Desc
+	ch <- e.backendInfo.Desc
+	if e.aggregateServers {
+		for _, m := range e.aggregatedServerMetrics {
+			m.describe(ch)
+		}
+	} else {
+		for _, m := range e.serverMetrics {
+			m.describe(ch)
+		}
+		if _, ok := e.serverMetrics[statusField]; ok {
+			ch <- e.serverStatus.metric.Desc
+			ch <- e.serverCheckStatus.metric.Desc
+			ch <- e.serverCheckEnabled.Desc
+			ch <- e.serverCheckTransition.Desc
+		}
+	}
+	ch <- e.info.Desc
+	ch <- e.upMetric.Desc
+	e.idlePct.describe(ch)
+	for _, m := range e.infoMetrics {
+		m.describe(ch)
+	}
+	for _, c := range e.runtimeCollectors {
+		c.describe(ch)
+	}
+	ch <- e.totalScrapes.Desc()
+	e.csvParseFailures.Describe(ch)
+	e.scrapeErrors.Describe(ch)
+	e.seriesLimited.Describe(ch)
+	e.runtimeSuccess.Describe(ch)
+	ch <- e.scrapeDuration.Desc()
+	ch <- e.rowsParsed.Desc()
+	ch <- e.scrapeBytes.Desc()
+	e.fetchPhases.Describe(ch)
+	e.lastErrorInfo.Describe(ch)
+	ch <- e.lastErrorTime.Desc()
+	if e.cacheHits != nil {
+		ch <- e.cacheHits.Desc()
+		ch <- e.cacheAge.Desc()
+	}
+}
+
+// Collect fetches the stats from configured HAProxy location and delivers them
+// as Prometheus metrics. It implements prometheus.Collector.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.collect(ch, allSections)
+}
+
+// CollectSections is Collect restricted to the given sections of the metrics,
+// see IsSection. The metrics about the scrape are always collected.
+func (e *Exporter) CollectSections(ch chan<- prometheus.Metric, sections map[string]bool) {
+	e.collect(ch, sections)
+}
+
+// collect is like Collect, but only delivers the metrics of the given
+// sections.
+func (e *Exporter) collect(ch chan<- prometheus.Metric, sections map[string]bool) {
+	if e.scrapeLevel != nil {
+		sections = restrictSections(sections, e.scrapeLevel)
+	}
+	scrapeCh, flush := ch, func() {}
+	if e.metricBuffer > 0 {
+		scrapeCh, flush = bufferMetrics(ch, e.metricBuffer)
+	}
+	// Only concurrent collects of the same HAProxy are serialized, so that a
+	// slow target never holds up scrapes of another Exporter.
+	e.mutex.Lock()
+	start := time.Now()
+	err := e.scrape(scrapeCh, sections)
+	e.scrapeDuration.Observe(time.Since(start).Seconds())
+	e.logScrape(err)
+	if err != nil {
+		e.recordError(err)
+	}
+	e.mutex.Unlock()
+	flush()
+
+	up := 1.0
+	if err != nil {
+		up = 0
+		e.scrapeErrors.WithLabelValues(errorType(err)).Inc()
+	}
+
+	ch <- prometheus.MustNewConstMetric(e.upMetric.Desc, e.upMetric.Type, up)
+	ch <- e.totalScrapes
+	e.csvParseFailures.Collect(ch)
+	e.scrapeErrors.Collect(ch)
+	e.seriesLimited.Collect(ch)
+	e.runtimeSuccess.Collect(ch)
+	ch <- e.scrapeDuration
+	ch <- e.rowsParsed
+	ch <- e.scrapeBytes
+	e.fetchPhases.Collect(ch)
+	e.lastErrorInfo.Collect(ch)
+	ch <- e.lastErrorTime
+	if e.cacheHits != nil {
+		ch <- e.cacheHits
+		ch <- e.cacheAge
+	}
+}
+
+// maxErrorMessageLength bounds the length of the message label of
+// haproxy_exporter_last_error_info.
+const maxErrorMessageLength = 256
+
+// recordError exports err as the last error of a scrape.
+func (e *Exporter) recordError(err error) {
+	msg := err.Error()
+	if len(msg) > maxErrorMessageLength {
+		msg = strings.ToValidUTF8(msg[:maxErrorMessageLength], "") + "..."
+	}
+	e.lastErrorInfo.Reset()
+	e.lastErrorInfo.WithLabelValues(msg, errorType(err)).Set(1)
+	e.lastErrorTime.SetToCurrentTime()
+}
+
+// logScrape logs the result of a scrape. Of consecutive failed scrapes failing
+// with the same type of error, only the first and every
+// scrapeErrorLogEvery-th one are logged as errors, and recovering from them
+// is logged as well. haproxy_exporter_scrape_errors_total still counts every
+// failed scrape.
+func (e *Exporter) logScrape(err error) {
+	if err == nil {
+		if e.failures > 0 {
+			level.Info(e.logger).Log("msg", "Scraped HAProxy again", "failures", e.failures)
+		}
+		e.failures, e.failureType = 0, ""
+		return
+	}
+	e.failures++
+	typ := errorType(err)
+	logger := level.Debug(e.logger)
+	if e.failures == 1 || typ != e.failureType || e.scrapeErrorLogEvery <= 1 || e.failures%e.scrapeErrorLogEvery == 0 {
+		logger = level.Error(e.logger)
+	}
+	e.failureType = typ
+	logger.Log("msg", "Can't scrape HAProxy", "err", err, "error_type", typ, "failures", e.failures)
+}
+
+func fetchHTTP(uri string, opts ExporterOpts) Fetcher {
+	tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: !opts.SSLVerify}}
+	if opts.ProxyFromEnv {
+		tr.Proxy = http.ProxyFromEnvironment
+	}
+	client := http.Client{
+		Timeout:   opts.Timeout,
+		Transport: tr,
+	}
+
+	return FetcherFunc(func(ctx context.Context) (io.ReadCloser, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Username != "" {
+			req.SetBasicAuth(opts.Username, opts.Password)
+		}
+		phases := fetchPhasesFrom(ctx)
+		if phases != nil {
+			req = req.WithContext(traceHTTP(ctx, phases))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, classifyError(err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			resp.Body.Close()
+			return nil, &AuthError{StatusCode: resp.StatusCode}
+		}
+		if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("HTTP status %d", resp.StatusCode)
+		}
+		if phases != nil {
+			return &phaseReader{ReadCloser: resp.Body, p: phases}, nil
+		}
+		return resp.Body, nil
+	})
+}
+
+// SocketFetcher returns a Fetcher sending cmd, which ends with a newline, to
+// the stats socket of HAProxy at address, dialed with scheme, unix or tcp, and
+// returning its answer.
+func SocketFetcher(scheme, address, cmd string, timeout time.Duration) Fetcher {
+	return FetcherFunc(func(ctx context.Context) (io.ReadCloser, error) {
+		d := net.Dialer{Timeout: timeout}
+		start := time.Now()
+		f, err := d.DialContext(ctx, scheme, address)
+		if err != nil {
+			return nil, classifyError(err)
+		}
+		phases := fetchPhasesFrom(ctx)
+		if phases != nil {
+			phases.dial = time.Since(start)
+		}
+		if err := f.SetDeadline(time.Now().Add(timeout)); err != nil {
+			f.Close()
+			return nil, err
+		}
+		n, err := io.WriteString(f, cmd)
+		if err != nil {
+			f.Close()
+			return nil, classifyError(err)
+		}
+		if n != len(cmd) {
+			f.Close()
+			return nil, errors.New("write error")
+		}
+		if phases != nil {
+			return &phaseReader{ReadCloser: f, p: phases, sent: time.Now()}, nil
+		}
+		return f, nil
+	})
+}
+
+// context returns the context for fetching the stats of a scrape, which ends
+// after the timeout of the Exporter.
+func (e *Exporter) context() (context.Context, context.CancelFunc) {
+	if e.timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), e.timeout)
+}
+
+// bufferMetrics returns a channel forwarding the metrics sent to it to ch,
+// buffering up to n of them, and a function waiting for all metrics to be
+// forwarded once the last was sent.
+func bufferMetrics(ch chan<- prometheus.Metric, n int) (chan<- prometheus.Metric, func()) {
+	buffered := make(chan prometheus.Metric, n)
+	done := make(chan struct{})
+	go func() {
+		for m := range buffered {
+			ch <- m
+		}
+		close(done)
+	}()
+	return buffered, func() {
+		close(buffered)
+		<-done
+	}
+}
+
+// scrape fetches the stats and sends the metrics of the given sections to ch.
+// Errors are one of the error types of this package, if they are known.
+func (e *Exporter) scrape(ch chan<- prometheus.Metric, sections map[string]bool) error {
+	e.totalScrapes.Inc()
+	var err error
+	ctx, cancel := e.context()
+	defer cancel()
+
+	infoExported := false
+	if e.fetchInfo != nil && sections[infoSection] {
+		infoReader, err := e.fetchInfo.Fetch(ctx)
+		if err != nil {
+			return err
+		}
+		defer infoReader.Close()
+
+		info, err := e.parseInfo(infoReader)
+		if err != nil {
+			level.Debug(e.logger).Log("msg", "Failed parsing show info", "err", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(e.info.Desc, e.info.Type, 1, info.ReleaseDate, info.Version)
+			infoExported = true
+			if info.IdlePct != -1 {
+				e.idlePct.send(ch, info.IdlePct)
+			}
+			for field, value := range info.Values {
+				e.infoMetrics[field].send(ch, value)
+			}
+		}
+	}
+
+	if sections[runtimeSection] {
+		e.collectRuntime(ctx, ch)
+	}
+
+	phases := &fetchPhases{}
+	defer e.observeFetchPhases(phases)
+	body, err := e.fetchStat.Fetch(withFetchPhases(ctx, phases))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	counted := &countingReader{r: body}
+	defer func() { e.scrapeBytes.Add(float64(counted.n)) }()
+	stats := parser.NewReader(counted)
+	defer stats.Close()
+	header, err := stats.Header()
+	if err != nil {
+		return fmt.Errorf("error reading CSV: %w", classifyError(err))
+	}
+	cols, err := newColumns(header)
+	if err != nil {
+		return &ParseError{Err: err}
+	}
+	if header != nil {
+		e.detectVersion(header)
+		if e.schemaMetrics != nil {
+			if err := e.schemaMetrics.update(ctx, header); err != nil {
+				return err
+			}
+		}
+	}
+	// Without show info, which is only available on the stats socket, the
+	// version is what the header of the stats tells.
+	if !infoExported && sections[infoSection] && e.statsVersion != "" {
+		ch <- prometheus.MustNewConstMetric(e.info.Desc, e.info.Type, 1, "", e.statsVersion)
+	}
+
+	// The commands of several proxies are answered with error messages for
+	// unknown ones.
+	stats.SkipMessages = e.proxies != nil
+	seen := e.seenProxies(cols)
+
+	var rows []parser.Row
+	if e.streamStats && !e.needsAllRows(sections) {
+		// The rows are exported as they are read, and not kept.
+		stats.ReuseFields = true
+		x := e.newRowExport(cols, ch, sections, nil)
+		err := e.readRows(stats, seen.wrap(x.addRead))
+		x.wait()
+		if err != nil {
+			return err
+		}
+	} else {
+		rows = (*rowsPool.Get().(*[]parser.Row))[:0]
+		if err := e.readRows(stats, seen.wrap(func(row parser.Row) { rows = append(rows, row) })); err != nil {
+			return err
+		}
+		cols.canonicalRows(rows)
+		if e.multiProcess == MultiProcessAggregate {
+			rows = e.aggregateProcesses(rows)
+		}
+		var limited map[string]bool
+		switch {
+		case !sections[serverSection]:
+		case e.aggregateServers:
+			e.exportAggregatedServers(rows, ch)
+		default:
+			limited = e.limitServers(rows)
+		}
+		x := e.newRowExport(cols, ch, sections, limited)
+		for _, row := range rows {
+			x.add(row)
+		}
+		x.wait()
+		if e.streamStats {
+			releaseRows(rows)
+			rows = nil
+		}
+	}
+	seen.check()
+
+	e.lastMutex.Lock()
+	old := e.lastRows
+	e.lastRows, e.lastColumns, e.lastScrape = rows, cols, time.Now()
+	e.lastMutex.Unlock()
+	releaseRows(old)
+	return nil
+}
+
+// collectRuntime runs the runtime collectors. A failing collector doesn't
+// fail the scrape, as the stats don't depend on it: it is logged when it
+// starts failing, and its haproxy_exporter_runtime_collector_success is 0.
+func (e *Exporter) collectRuntime(ctx context.Context, ch chan<- prometheus.Metric) {
+	for _, c := range e.runtimeCollectors {
+		err := c.collect(ctx, ch)
+		if err != nil {
+			logger := level.Debug(e.logger)
+			if !e.runtimeFailing[c.name] {
+				logger = level.Warn(e.logger)
+			}
+			logger.Log("msg", "Runtime collector failed", "collector", c.name, "err", err)
+		} else if e.runtimeFailing[c.name] {
+			level.Info(e.logger).Log("msg", "Runtime collector recovered", "collector", c.name)
+		}
+		e.runtimeFailing[c.name] = err != nil
+		e.runtimeSuccess.WithLabelValues(c.name).Set(boolToFloat(err == nil))
+	}
+}
+
+// rowsPool holds the slices of the rows of earlier scrapes, for the rows of
+// later scrapes.
+var rowsPool = sync.Pool{
+	New: func() any { return new([]parser.Row) },
+}
+
+// releaseRows returns the slice of rows to rowsPool. The rows are dropped, so
+// that the pool doesn't keep their fields.
+func releaseRows(rows []parser.Row) {
+	if cap(rows) == 0 {
+		return
+	}
+	rows = rows[:cap(rows)]
+	clear(rows)
+	rows = rows[:0]
+	rowsPool.Put(&rows)
+}
+
+// readRows calls f for every row read from stats. Skipped rows are logged and
+// counted as parse failures.
+func (e *Exporter) readRows(stats *parser.Reader, f func(parser.Row)) error {
+	n := 0
+	defer func() { e.rowsParsed.Set(float64(n)) }()
+	for {
+		row, err := stats.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var rowErr *parser.RowError
+		if errors.As(err, &rowErr) {
+			level.Error(e.logger).Log("msg", "Can't read CSV", "err", &ParseError{Err: rowErr})
+			e.csvParseFailures.WithLabelValues(rowErrorReason(rowErr)).Inc()
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading CSV: %w", classifyError(err))
+		}
+		n++
+		f(row)
+	}
+}
+
+// needsAllRows returns whether exporting the given sections needs all rows
+// of the stats at once, e.g. to aggregate them.
+func (e *Exporter) needsAllRows(sections map[string]bool) bool {
+	if e.multiProcess == MultiProcessAggregate {
+		return true
+	}
+	return sections[serverSection] && (e.aggregateServers || e.maxServersPerBackend > 0 || e.maxServers > 0)
+}
+
+// observeFetchPhases exports the durations of the phases of the last fetch of
+// the stats.
+func (e *Exporter) observeFetchPhases(p *fetchPhases) {
+	for phase, d := range p.byName() {
+		e.fetchPhases.WithLabelValues(phase).Set(d.Seconds())
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// detectVersion updates the HAProxy version detected from the header of the
+// stats. Columns the detected version doesn't emit are absent from all rows,
+// so their metrics are skipped by exportCsvFields.
+func (e *Exporter) detectVersion(header []string) {
+	v := versionFromHeader(header)
+	if v == e.statsVersion {
+		return
+	}
+	level.Info(e.logger).Log("msg", "Detected HAProxy version from stats header", "version", v, "columns", len(header))
+	e.statsVersion = v
+}
+
+type versionInfo struct {
+	ReleaseDate string
+	Version     string
+	IdlePct     float64
+	Values      map[string]float64 // The fields of infoMetrics.
+}
+
+func (e *Exporter) parseInfo(i io.Reader) (versionInfo, error) {
+	var version, releaseDate string
+	// idlePct value of -1 is used to indicate it's unset
+	var idlePct float64 = -1
+	values := map[string]float64{}
+	s := bufio.NewScanner(i)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.Contains(line, ":") {
+			continue
+		}
+
+		field := strings.SplitN(line, ": ", 2)
+		switch field[0] {
+		case "Release_date":
+			releaseDate = field[1]
+		case "Version":
+			version = field[1]
+		case "Idle_pct":
+			i, err := strconv.ParseFloat(field[1], 64)
+			if err == nil && i >= 0 && i <= 100 {
+				idlePct = i
+			}
+		default:
+			if _, ok := e.infoMetrics[field[0]]; ok && len(field) == 2 {
+				if v, err := strconv.ParseFloat(field[1], 64); err == nil {
+					values[field[0]] = v
+				}
+			}
+		}
+	}
+	return versionInfo{ReleaseDate: releaseDate, Version: version, IdlePct: idlePct, Values: values}, s.Err()
+}
+
+// parseRow sends the metrics of the given sections for a row of the stats,
+// which has at least parser.MinFields fields, building their label pairs in
+// pairs. The server metrics of the limited backends are dropped.
+func (e *Exporter) parseRow(csvRow []string, cols columns, ch chan<- prometheus.Metric, sections map[string]bool, limited map[string]bool, pairs *rowLabelPairs) {
+	if !e.proxyExported(csvRow[pxnameField]) {
+		return
+	}
+	status, typ := csvRow[statusField], csvRow[typeField]
+	rm := e.rowMetricsOf(csvRow[pidField])
+
+	const (
+		frontend = "0"
+		backend  = "1"
+		server   = "2"
+	)
+
+	switch typ {
+	case frontend:
+		if sections[frontendSection] {
+			row := pairs.resetRow(e.labels, csvRow, false)
+			e.exportCsvFields(rm.frontendFields, csvRow, ch, row)
+			if e.schemaMetrics != nil {
+				e.schemaMetrics.export(ch, csvRow, cols, "frontend", rm.process, row)
+			}
+			rm.frontendStatus.export(ch, statusState(status), row)
+			e.checkStatus(rm.frontendStatus, csvRow)
+			// The mode and algo fields were added in HAProxy 1.7.
+			if len(csvRow) > algoField {
+				ch <- row.metric(rm.frontendInfo, 1, csvRow[modeField])
+			}
+		}
+	case backend:
+		if sections[backendSection] {
+			row := pairs.resetRow(e.labels, csvRow, false)
+			e.exportCsvFields(rm.backendFields, csvRow, ch, row)
+			if e.schemaMetrics != nil {
+				e.schemaMetrics.export(ch, csvRow, cols, "backend", rm.process, row)
+			}
+			rm.backendStatus.export(ch, statusState(status), row)
+			e.checkStatus(rm.backendStatus, csvRow)
+			if len(csvRow) > algoField {
+				ch <- row.metric(rm.backendInfo, 1, csvRow[modeField], csvRow[algoField])
+			}
+		}
+	case server:
+		if !sections[serverSection] || e.aggregateServers {
+			return
+		}
+		if !e.serverExported(csvRow) || limited[csvRow[pxnameField]] {
+			return
+		}
+		row := pairs.resetRow(e.labels, csvRow, true)
+		e.checkStatus(rm.serverStatus, csvRow)
+		checked := checkEnabled(csvRow)
+		if checked {
+			e.exportCsvFields(rm.serverFields, csvRow, ch, row)
+		} else {
+			e.exportCsvFields(rm.uncheckedServerFields, csvRow, ch, row)
+		}
+		if e.schemaMetrics != nil {
+			e.schemaMetrics.export(ch, csvRow, cols, "server", rm.process, row)
+		}
+		// The state set comes with the up metric of the status field.
+		if _, ok := rm.serverMetrics[statusField]; ok {
+			rm.serverStatus.export(ch, statusState(status), row)
+			ch <- row.metric(rm.serverCheckEnabled, boolToFloat(checked))
+			ch <- row.metric(rm.serverCheckTransition, checkTransition(status))
+			if checked && len(csvRow) > checkStatusField && csvRow[checkStatusField] != "" {
+				rm.serverCheckStatus.export(ch, checkResult(csvRow[checkStatusField]), row)
+			}
+		}
+	}
+}
+
+// parseScrapeLevel returns the stats sections of a comma-separated list, or nil
+// for all of them if it is empty.
+func parseScrapeLevel(level string) (map[string]bool, error) {
+	if level == "" {
+		return nil, nil
+	}
+	res := map[string]bool{}
+	for _, s := range strings.Split(level, ",") {
+		s = strings.TrimSpace(s)
+		if _, ok := statsSections[s]; !ok {
+			return nil, fmt.Errorf("unknown section %q in scrape level, must be frontend, backend or server", s)
+		}
+		res[s] = true
+	}
+	return res, nil
+}
+
+// parseProxies returns the proxy names of a comma-separated list, without
+// duplicates, or nil for all proxies if it is empty. The names may only have
+// the characters HAProxy allows in them, so that they can't change the show
+// stat commands they are passed to.
+func parseProxies(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var res []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.IndexFunc(name, invalidProxyNameChar) >= 0 {
+			return nil, fmt.Errorf("invalid proxy name %q", name)
+		}
+		// HAProxy takes numbers for the IDs of proxies.
+		if _, err := strconv.Atoi(name); err == nil {
+			return nil, fmt.Errorf("proxy name %q is taken for a proxy ID by HAProxy", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+// invalidProxyNameChar returns whether HAProxy rejects c in the names of
+// proxies.
+func invalidProxyNameChar(c rune) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return false
+	}
+	return !strings.ContainsRune(".:_-", c)
+}
+
+// proxySet returns the set of the given proxy names, or nil if there are none.
+func proxySet(proxies []string) map[string]bool {
+	if proxies == nil {
+		return nil
+	}
+	res := make(map[string]bool, len(proxies))
+	for _, name := range proxies {
+		res[name] = true
+	}
+	return res
+}
+
+// showStatCmdOf returns the show stat command of the rows of the given stats
+// sections and proxies, all of them if nil. The IDs -1 select all proxies,
+// sections and servers. The commands of several proxies are sent at once,
+// separated by semicolons, each of their outputs starting with a header.
+func showStatCmdOf(level map[string]bool, proxies []string) string {
+	if level == nil && proxies == nil {
+		return showStatCmd
+	}
+	mask := -1
+	if level != nil {
+		mask = 0
+		for s := range level {
+			mask |= statsSections[s]
+		}
+	}
+	if proxies == nil {
+		return fmt.Sprintf("show stat -1 %d -1\n", mask)
+	}
+	cmds := make([]string, len(proxies))
+	for i, name := range proxies {
+		cmds[i] = fmt.Sprintf("show stat %s %d -1", name, mask)
+	}
+	return strings.Join(cmds, ";") + "\n"
+}
+
+// proxyExported returns whether the rows of the proxy of the given name are
+// exported. HTTP stats URLs return the rows of all proxies regardless.
+func (e *Exporter) proxyExported(name string) bool {
+	return e.proxies == nil || e.proxies[name]
+}
+
+// seenProxies records the proxies of e.proxies the rows of a scrape are of,
+// to tell those missing from the stats, e.g. as they were misspelled.
+type seenProxies struct {
+	e        *Exporter
+	position int // Of the proxy name in the rows.
+	seen     map[string]bool
+}
+
+// seenProxies returns the seenProxies of a scrape of stats with the given
+// columns, or nil if all proxies are exported.
+func (e *Exporter) seenProxies(cols columns) *seenProxies {
+	if e.proxies == nil {
+		return nil
+	}
+	return &seenProxies{e: e, position: cols.position(pxnameField), seen: make(map[string]bool, len(e.proxies))}
+}
+
+// wrap returns f recording the proxies of the rows it is called with.
+func (s *seenProxies) wrap(f func(parser.Row)) func(parser.Row) {
+	if s == nil {
+		return f
+	}
+	return func(row parser.Row) {
+		if name := row.Fields[s.position]; s.e.proxies[name] {
+			s.seen[name] = true
+		}
+		f(row)
+	}
+}
+
+// check logs a warning for every proxy which goes missing from the stats, and
+// the proxies which are back.
+func (s *seenProxies) check() {
+	if s == nil {
+		return
+	}
+	for name := range s.e.proxies {
+		switch missing := !s.seen[name]; {
+		case missing && !s.e.missingProxies[name]:
+			level.Warn(s.e.logger).Log("msg", "Proxy of --haproxy.proxies missing from the stats", "proxy", name)
+		case !missing && s.e.missingProxies[name]:
+			level.Info(s.e.logger).Log("msg", "Proxy of --haproxy.proxies back in the stats", "proxy", name)
+		}
+		s.e.missingProxies[name] = !s.seen[name]
+	}
+}
+
+// restrictSections returns the sections without the stats sections not in
+// level.
+func restrictSections(sections, level map[string]bool) map[string]bool {
+	res := make(map[string]bool, len(sections))
+	for s, ok := range sections {
+		if _, stats := statsSections[s]; stats && !level[s] {
+			continue
+		}
+		res[s] = ok
+	}
+	return res
+}
+
+// unprovisioned returns whether the server of a canonical row is a slot of a
+// server template which has no address yet: it is in maintenance as its name
+// doesn't resolve, "MAINT (resolution)", or in maintenance with an
+// unspecified address. An empty address doesn't tell, as HAProxy only shows
+// the addresses of servers to operators, or with stats show-legends.
+func unprovisioned(csvRow []string) bool {
+	status := csvRow[statusField]
+	if status == "MAINT (resolution)" {
+		return true
+	}
+	if len(csvRow) <= addrField || statusState(status) != "MAINT" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(csvRow[addrField])
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// serverNameFilter returns the flag of the filter leaving out the server of the
+// given name, or "" if it is exported.
+func (e *Exporter) serverNameFilter(name string) string {
+	if e.serverNameInclude != nil && !e.serverNameInclude.MatchString(name) {
+		return "--haproxy.server-name-include"
+	}
+	if e.serverNameExclude != nil && e.serverNameExclude.MatchString(name) {
+		return "--haproxy.server-name-exclude"
+	}
+	return ""
+}
+
+// compileAnchored compiles the regular expression expr anchored at both ends,
+// or returns nil if it is empty.
+func compileAnchored(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// parseStatusField returns 1 if the status is up. Servers in transition, e.g.
+// "UP 1/3" or "DOWN 2/5", are in the state they are moving away from until the
+// health checks complete the transition.
+func parseStatusField(value string) int64 {
+	switch statusState(value) {
+	case "UP", "OPEN", "no_check", "DRAIN":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// checkStatus counts a parse failure if the status of a canonical row isn't
+// one of the states of s.
+func (e *Exporter) checkStatus(s stateSet, csvRow []string) {
+	if s.has(statusState(csvRow[statusField])) {
+		return
+	}
+	level.Error(e.logger).Log("msg", "Can't parse CSV status", "value", csvRow[statusField], "proxy", csvRow[pxnameField], "server", csvRow[svnameField])
+	e.csvParseFailures.WithLabelValues(parseFailureBadStatus).Inc()
+}
+
+// checkResult returns the result of a value of the check_status field, which
+// is prefixed with "* " while a check is running.
+func checkResult(checkStatus string) string {
+	return strings.TrimPrefix(checkStatus, "* ")
+}
+
+// checkEnabled returns whether health checks are enabled for the server of a
+// row. HAProxy only reports a check status for servers with health checks.
+func checkEnabled(csvRow []string) bool {
+	if csvRow[statusField] == "no check" {
+		return false
+	}
+	return len(csvRow) <= checkStatusField || csvRow[checkStatusField] != ""
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// checkTransition returns the progress of the health checks towards changing
+// the state of a server with the given status, from 0 to 1. HAProxy shows the
+// progress as "UP n/fall", n being the number of failed checks still
+// needed to go down, and "DOWN n/rise", n being the number of successful
+// checks so far.
+func checkTransition(status string) float64 {
+	state, progress, ok := strings.Cut(status, " ")
+	if !ok {
+		return 0
+	}
+	n, m, ok := strings.Cut(progress, "/")
+	if !ok {
+		return 0
+	}
+	done, err := strconv.Atoi(n)
+	if err != nil {
+		return 0
+	}
+	total, err := strconv.Atoi(m)
+	if err != nil || total <= 0 || done < 0 || done > total {
+		return 0
+	}
+	switch state {
+	case "UP":
+		return float64(total-done) / float64(total)
+	case "DOWN":
+		return float64(done) / float64(total)
+	default:
+		return 0
+	}
+}
+
+// parseFieldValue converts the raw value of the CSV field at fieldIdx to a
+// number, which metricInfo.value converts to the value exported for it.
+func parseFieldValue(fieldIdx int, valueStr string) (float64, error) {
+	switch fieldIdx {
+	case statusField:
+		return float64(parseStatusField(valueStr)), nil
+	case checkDurationField, qtimeMsField, ctimeMsField, rtimeMsField, ttimeMsField,
+		qtimeMaxMsField, ctimeMaxMsField, rtimeMaxMsField, ttimeMaxMsField:
+		return strconv.ParseFloat(valueStr, 64)
+	default:
+		valueInt, err := strconv.ParseInt(valueStr, 10, 64)
+		return float64(valueInt), err
+	}
+}
+
+// isLimitField returns whether the field at fieldIdx is a limit, which is
+// empty if there is none.
+func isLimitField(fieldIdx int) bool {
+	switch fieldIdx {
+	case slimField, qlimitField, rateLimField:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *Exporter) exportCsvFields(fields []fieldMetric, csvRow []string, ch chan<- prometheus.Metric, row *rowLabelPairs) {
+	for _, f := range fields {
+		fieldIdx, metric := f.field, f.metric
+		if fieldIdx > len(csvRow)-1 {
+			// The fields are sorted, the row has none of the rest.
+			break
+		}
+		valueStr := csvRow[fieldIdx]
+		if valueStr == "" {
+			if e.unlimitedValue != nil && isLimitField(fieldIdx) {
+				metric.sendRow(ch, row, *e.unlimitedValue)
+			}
+			continue
+		}
+
+		value, err := parseFieldValue(fieldIdx, valueStr)
+		if err != nil {
+			level.Error(e.logger).Log("msg", "Can't parse CSV field value", "value", valueStr, "err", err)
+			e.csvParseFailures.WithLabelValues(parseFailureBadNumber).Inc()
+			continue
+		}
+		metric.sendRow(ch, row, value)
+	}
+}
+
+// ServerMetrics is a set of server metrics, keyed by CSV field, as returned by
+// FilterServerMetrics.
+type ServerMetrics map[int]metricInfo
+
+// String returns the comma separated fields of m, as accepted by
+// FilterServerMetrics.
+func (m ServerMetrics) String() string {
+	return metrics(m).String()
+}
+
+// FilterServerMetrics returns the set of server metrics specified by the comma
+// separated filter, of CSV field numbers or metric names. See
+// serverMetricFields for the names.
+func FilterServerMetrics(filter string) (ServerMetrics, error) {
+	metrics := ServerMetrics{}
+	if len(filter) == 0 {
+		return metrics, nil
+	}
+
+	for _, f := range strings.Split(filter, ",") {
+		f = strings.TrimSpace(f)
+		if field, err := strconv.Atoi(f); err == nil {
+			if metric, ok := serverMetrics[field]; ok {
+				metrics[field] = metric
+			}
+			continue
+		}
+		fields := serverMetricFields(f)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid server metric field number or name: %v", f)
+		}
+		for _, field := range fields {
+			metrics[field] = serverMetrics[field]
+		}
+	}
+
+	return metrics, nil
+}
+
+// serverMetricFields returns the CSV fields of the server metric of the given
+// name, with or without the prefix haproxy_server_, in either naming scheme.
+// Several fields have the same metric, e.g. http_responses_total.
+func serverMetricFields(name string) []int {
+	name = prometheus.BuildFQName(namespace, "server", strings.TrimPrefix(name, "haproxy_server_"))
+	var fields []int
+	for field, m := range serverMetrics {
+		if m.fqName == name || v2Names[m.fqName].name == name {
+			fields = append(fields, field)
+		}
+	}
+	sort.Ints(fields)
+	return fields
+}
+
+// ListServerFields writes the CSV fields of the server metrics along with
+// their column and metric names, for picking --haproxy.server-metric-fields.
+func ListServerFields(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tCOLUMN\tMETRIC\tV2 METRIC")
+	fields := make([]int, 0, len(serverMetrics))
+	for field := range serverMetrics {
+		fields = append(fields, field)
+	}
+	sort.Ints(fields)
+	for _, field := range fields {
+		m := serverMetrics[field]
+		v2 := m.fqName
+		if n, ok := v2Names[m.fqName]; ok {
+			v2 = n.name
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", field, csvFieldNames[field], strings.TrimPrefix(m.fqName, "haproxy_server_"), strings.TrimPrefix(v2, "haproxy_server_"))
+	}
+	return tw.Flush()
+}
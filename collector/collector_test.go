@@ -0,0 +1,1652 @@
+// Copyright 2018 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	testSocket = "/tmp/haproxyexportertest.sock"
+	testInfo   = "Release_date: test date\nVersion: test version\nIdle_pct: 100\n"
+)
+
+type haproxy struct {
+	*httptest.Server
+	response []byte
+}
+
+func newHaproxy(response []byte) *haproxy {
+	h := &haproxy{response: response}
+	h.Server = httptest.NewServer(handler(h))
+	return h
+}
+
+func handler(h *haproxy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write(h.response)
+	}
+}
+
+func handlerStale(exit chan bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		<-exit
+	}
+}
+
+// variableMetrics vary from run to run, and are left out of the comparisons
+// with the fixtures.
+var variableMetrics = map[string]bool{
+	"haproxy_exporter_scrape_duration_seconds":      true,
+	"haproxy_exporter_fetch_phase_seconds":          true,
+	"haproxy_exporter_last_error_info":              true, // The messages contain the addresses of the test servers.
+	"haproxy_exporter_last_error_timestamp_seconds": true,
+}
+
+func expectMetrics(t *testing.T, c prometheus.Collector, fixture string) {
+	exp, err := os.Open(path.Join("test", fixture))
+	if err != nil {
+		t.Fatalf("Error opening fixture file %q: %v", fixture, err)
+	}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		res := mfs[:0]
+		for _, mf := range mfs {
+			if !variableMetrics[mf.GetName()] {
+				res = append(res, mf)
+			}
+		}
+		return res, nil
+	})
+	if err := testutil.GatherAndCompare(g, exp); err != nil {
+		t.Fatal("Unexpected metrics returned:", err)
+	}
+}
+
+// newRow returns a CSV row of the given type with the given fields set, and
+// all others up to the last field set empty.
+func newRow(pxname, svname, typ string, fields map[int]string) string {
+	n := minimumCsvFieldCount
+	for i := range fields {
+		if i >= n {
+			n = i + 1
+		}
+	}
+	row := make([]string, n)
+	row[pxnameField], row[svnameField], row[typeField] = pxname, svname, typ
+	for i, v := range fields {
+		row[i] = v
+	}
+	return strings.Join(row, ",") + ",\n"
+}
+
+// expectRowMetrics checks the metrics with the given names exported for the
+// CSV rows.
+func expectRowMetrics(t *testing.T, rows string, expected string, names ...string) {
+	t.Helper()
+	h := newHaproxy([]byte(rows))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	h := newHaproxy([]byte("not,enough,fields"))
+	defer h.Close()
+
+	e, _ := NewExporter(h.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}, log.NewNopLogger())
+
+	expectMetrics(t, e, "invalid_config.metrics")
+}
+
+func TestServerWithoutChecks(t *testing.T) {
+	h := newHaproxy([]byte("test,127.0.0.1:8080,0,0,0,0,0,0,0,0,,0,,0,0,0,0,no check,1,1,0,0,,,0,,1,1,1,,0,,2,0,,0,,,,0,0,0,0,0,0,0,,,,0,0,,,,,,,,,,,"))
+	defer h.Close()
+
+	e, _ := NewExporter(h.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}, log.NewNopLogger())
+
+	expectMetrics(t, e, "server_without_checks.metrics")
+}
+
+func TestExcludeUncheckedServerUp(t *testing.T) {
+	h := newHaproxy([]byte("test,127.0.0.1:8080,0,0,0,0,0,0,0,0,,0,,0,0,0,0,no check,1,1,0,0,,,0,,1,1,1,,0,,2,0,,0,,,,0,0,0,0,0,0,0,,,,0,0,,,,,,,,,,,\n" +
+		"test,127.0.0.2:8080,0,0,0,0,0,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,,,0,,1,1,1,,0,,2,0,,0,L4OK,,,0,0,0,0,0,0,0,,,,0,0,,,,,,,,,,,\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, ExcludeUncheckedServerUp: true}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_server_check_enabled Whether health checks are enabled for the server (1 = enabled, 0 = disabled).
+# TYPE haproxy_server_check_enabled gauge
+haproxy_server_check_enabled{backend="test",server="127.0.0.1:8080"} 0
+haproxy_server_check_enabled{backend="test",server="127.0.0.2:8080"} 1
+# HELP haproxy_server_up Current health status of the server (1 = UP, 0 = DOWN).
+# TYPE haproxy_server_up gauge
+haproxy_server_up{backend="test",server="127.0.0.2:8080"} 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_server_check_enabled", "haproxy_server_up"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServerNameFilters(t *testing.T) {
+	rows := newRow("test", "web-1", "2", map[int]string{statusField: "UP"}) +
+		newRow("test", "web-2", "2", map[int]string{statusField: "UP"}) +
+		newRow("test", "canary-web-3", "2", map[int]string{statusField: "UP"}) +
+		newRow("test", "placeholder", "2", map[int]string{statusField: "MAINT"})
+	h := newHaproxy([]byte(rows))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{
+		ServerMetrics:     serverMetrics,
+		Timeout:           5 * time.Second,
+		ServerNameInclude: ".*web-.*",
+		ServerNameExclude: "canary-.*",
+	}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_server_up Current health status of the server (1 = UP, 0 = DOWN).
+# TYPE haproxy_server_up gauge
+haproxy_server_up{backend="test",server="web-1"} 1
+haproxy_server_up{backend="test",server="web-2"} 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_server_up"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewExporter(h.URL, ExporterOpts{ServerNameExclude: "("}, log.NewNopLogger()); err == nil {
+		t.Error("expected an error for an invalid server name filter")
+	}
+}
+
+func TestScrapeDuration(t *testing.T) {
+	h := newHaproxy([]byte(newRow("fe", "FRONTEND", "0", nil)))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(e)
+	for i := 0; i < 2; i++ {
+		if _, err := registry.Gather(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "haproxy_exporter_scrape_duration_seconds" {
+			continue
+		}
+		if want, have := uint64(3), mf.GetMetric()[0].GetHistogram().GetSampleCount(); want != have {
+			t.Errorf("want %d scrape durations, have %d", want, have)
+		}
+		return
+	}
+	t.Error("no scrape durations exported")
+}
+
+func TestScrapeErrorLogging(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(newRow("fe", "FRONTEND", "0", map[int]string{statusField: "OPEN"})))
+	}))
+	defer h.Close()
+
+	var buf strings.Builder
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, ScrapeErrorLogEvery: 3}, log.NewLogfmtLogger(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		testutil.CollectAndCount(e)
+	}
+	down.Store(false)
+	testutil.CollectAndCount(e)
+
+	want := []string{
+		`level=error msg="Can't scrape HAProxy"`,
+		`level=debug msg="Can't scrape HAProxy"`,
+		`level=error msg="Can't scrape HAProxy"`,
+		`level=debug msg="Can't scrape HAProxy"`,
+		`level=info msg="Scraped HAProxy again" failures=4`,
+	}
+	have := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(have) != len(want) {
+		t.Fatalf("want %d log lines, have %q", len(want), have)
+	}
+	for i := range want {
+		if !strings.HasPrefix(have[i], want[i]) {
+			t.Errorf("want log line %d to start with %q, have %q", i, want[i], have[i])
+		}
+	}
+	if want, have := 4.0, testutil.ToFloat64(e.scrapeErrors.WithLabelValues("other")); want != have {
+		t.Errorf("want %v scrape errors, have %v", want, have)
+	}
+}
+
+func TestLastError(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+# HELP haproxy_exporter_last_error_info Message and type of the error of the last failed scrape, truncated to 256 bytes.
+# TYPE haproxy_exporter_last_error_info gauge
+haproxy_exporter_last_error_info{message="HTTP status 503",type="other"} 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_exporter_last_error_info"); err != nil {
+		t.Error(err)
+	}
+	if testutil.ToFloat64(e.lastErrorTime) <= 0 {
+		t.Error("want the timestamp of the last error")
+	}
+
+	// Long messages are truncated without splitting a character.
+	e.recordError(errors.New("x" + strings.Repeat("é", maxErrorMessageLength)))
+	expected = `
+# HELP haproxy_exporter_last_error_info Message and type of the error of the last failed scrape, truncated to 256 bytes.
+# TYPE haproxy_exporter_last_error_info gauge
+haproxy_exporter_last_error_info{message="x` + strings.Repeat("é", maxErrorMessageLength/2-1) + `...",type="other"} 1
+`
+	if err := testutil.CollectAndCompare(e.lastErrorInfo, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnprovisionedServers(t *testing.T) {
+	server := func(name, status, addr string) string {
+		return newRow("be", name, "2", map[int]string{statusField: status, addrField: addr})
+	}
+	rows := server("web1", "UP", "10.0.0.1:80") +
+		server("web2", "MAINT", "10.0.0.2:80") +
+		server("web3", "MAINT (resolution)", "") +
+		server("web4", "MAINT", "0.0.0.0:80") +
+		// Without stats show-legends, HAProxy leaves the addresses empty.
+		server("web5", "MAINT", "")
+
+	for _, include := range []bool{false, true} {
+		h := newHaproxy([]byte(rows))
+		e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, IncludeUnprovisionedServers: include}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `
+# HELP haproxy_server_up Current health status of the server (1 = UP, 0 = DOWN).
+# TYPE haproxy_server_up gauge
+haproxy_server_up{backend="be",server="web1"} 1
+haproxy_server_up{backend="be",server="web2"} 0
+haproxy_server_up{backend="be",server="web5"} 0
+`
+		if include {
+			expected += `haproxy_server_up{backend="be",server="web3"} 0
+haproxy_server_up{backend="be",server="web4"} 0
+`
+		}
+		if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_server_up"); err != nil {
+			t.Errorf("include %v: %s", include, err)
+		}
+		h.Close()
+	}
+}
+
+func TestScrapeLevel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+	// The server row is only sent to check that it is skipped anyway.
+	rows := newRow("fe", "FRONTEND", "0", map[int]string{4: "1"}) +
+		newRow("be", "BACKEND", "1", map[int]string{4: "2"}) +
+		newRow("be", "srv", "2", map[int]string{4: "3", statusField: "UP"})
+	srv, err := newRuntimeSocket(testSocket, map[string]string{
+		"show stat -1 3 -1": rows,
+		"show info":         testInfo,
+	})
+	if err != nil {
+		t.Fatalf("can't start test server: %v", err)
+	}
+	defer srv.Close()
+
+	e, err := NewExporter("unix:"+testSocket, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, ScrapeLevel: "frontend,backend"}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_backend_current_sessions Current number of active sessions.
+# TYPE haproxy_backend_current_sessions gauge
+haproxy_backend_current_sessions{backend="be"} 2
+# HELP haproxy_frontend_current_sessions Current number of active sessions.
+# TYPE haproxy_frontend_current_sessions gauge
+haproxy_frontend_current_sessions{frontend="fe"} 1
+# HELP haproxy_up Was the last scrape of HAProxy successful.
+# TYPE haproxy_up gauge
+haproxy_up 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_frontend_current_sessions", "haproxy_backend_current_sessions", "haproxy_server_current_sessions", "haproxy_up"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewExporter("unix:"+testSocket, ExporterOpts{ScrapeLevel: "frontend,listener"}, log.NewNopLogger()); err == nil {
+		t.Error("expected an error for an unknown section")
+	}
+}
+
+func TestProxies(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+	header := "# " + strings.Join(csvFieldNames[:minimumCsvFieldCount], ",") + ",\n"
+	fe := newRow("fe", "FRONTEND", "0", map[int]string{4: "1", statusField: "OPEN"})
+	be := newRow("be", "BACKEND", "1", map[int]string{4: "2", statusField: "UP"}) +
+		newRow("be", "srv", "2", map[int]string{4: "3", statusField: "UP"})
+	other := newRow("other", "BACKEND", "1", map[int]string{4: "4", statusField: "UP"}) +
+		newRow("other", "srv", "2", map[int]string{4: "5", statusField: "UP"})
+	// HAProxy answers the command of an unknown proxy with an error, and ends
+	// the output of every other command with an empty line.
+	srv, err := newRuntimeSocket(testSocket, map[string]string{
+		"show stat fe -1 -1;show stat gone -1 -1;show stat be -1 -1": header + fe + "\n" + "No such proxy.\n" + header + be + "\n",
+		"show stat gone -1 -1;show stat fe -1 -1":                    "No such proxy.\n" + header + fe + "\n",
+		"show info": testInfo,
+	})
+	if err != nil {
+		t.Fatalf("can't start test server: %v", err)
+	}
+	defer srv.Close()
+	h := newHaproxy([]byte(header + fe + be + other))
+	defer h.Close()
+
+	expected := `
+# HELP haproxy_backend_current_sessions Current number of active sessions.
+# TYPE haproxy_backend_current_sessions gauge
+haproxy_backend_current_sessions{backend="be"} 2
+# HELP haproxy_exporter_csv_parse_failures_total Number of errors while parsing CSV by reason: short_row, bad_number, bad_status or csv_error.
+# TYPE haproxy_exporter_csv_parse_failures_total counter
+haproxy_exporter_csv_parse_failures_total{reason="bad_number"} 0
+haproxy_exporter_csv_parse_failures_total{reason="bad_status"} 0
+haproxy_exporter_csv_parse_failures_total{reason="csv_error"} 0
+haproxy_exporter_csv_parse_failures_total{reason="short_row"} 0
+# HELP haproxy_frontend_current_sessions Current number of active sessions.
+# TYPE haproxy_frontend_current_sessions gauge
+haproxy_frontend_current_sessions{frontend="fe"} 1
+# HELP haproxy_server_current_sessions Current number of active sessions.
+# TYPE haproxy_server_current_sessions gauge
+haproxy_server_current_sessions{backend="be",server="srv"} 3
+`
+	for _, uri := range []string{"unix:" + testSocket, h.URL} {
+		var logs bytes.Buffer
+		e, err := NewExporter(uri, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, Proxies: "fe,gone, be,fe"}, log.NewLogfmtLogger(&logs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_frontend_current_sessions", "haproxy_backend_current_sessions", "haproxy_server_current_sessions", "haproxy_exporter_csv_parse_failures_total"); err != nil {
+				t.Errorf("%s: %s", uri, err)
+			}
+		}
+		if n := strings.Count(logs.String(), "proxy=gone"); n != 1 {
+			t.Errorf("%s: expected one warning about the missing proxy, got %d:\n%s", uri, n, logs.String())
+		}
+	}
+
+	// The header following the message of an unknown first proxy is kept.
+	e, err := NewExporter("unix:"+testSocket, ExporterOpts{Timeout: 5 * time.Second, Proxies: "gone,fe"}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testutil.CollectAndCompare(e, strings.NewReader(`
+# HELP haproxy_frontend_current_sessions Current number of active sessions.
+# TYPE haproxy_frontend_current_sessions gauge
+haproxy_frontend_current_sessions{frontend="fe"} 1
+`), "haproxy_frontend_current_sessions"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewExporter("unix:"+testSocket, ExporterOpts{Proxies: "fe;show info"}, log.NewNopLogger()); err == nil {
+		t.Error("expected an error for an invalid proxy name")
+	}
+	if _, err := NewExporter("unix:"+testSocket, ExporterOpts{Proxies: "fe,42"}, log.NewNopLogger()); err == nil {
+		t.Error("expected an error for a proxy ID")
+	}
+}
+
+// TestServerBrokenCSV ensures bugs in CSV format are handled gracefully. List of known bugs:
+//
+//   - http://permalink.gmane.org/gmane.comp.web.haproxy/26561
+func TestServerBrokenCSV(t *testing.T) {
+	const data = `foo,FRONTEND,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,0,,0,L4OK,,0,,,,,,,0,,,,0,0,,,,,,,,,,,
+foo,bug-missing-comma,0,0,0,0,,0,0,0,,0,,0,0,0,0,DRAIN (agent)1,1,0,0,0,5007,0,,1,8,1,,0,,2,0,,0,L4OK,,0,,,,,,,0,,,,0,0,,,,,,,,,,,
+foo,foo-instance-0,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,0,,0,L4OK,,0,,,,,,,0,,,,0,0,,,,,,,,,,,
+foo,BACKEND,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,0,,0,L4OK,,0,,,,,,,0,,,,0,0,,,,,,,,,,,
+`
+	h := newHaproxy([]byte(data))
+	defer h.Close()
+
+	e, _ := NewExporter(h.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}, log.NewNopLogger())
+
+	expectMetrics(t, e, "server_broken_csv.metrics")
+}
+
+func TestCSVParseFailureReasons(t *testing.T) {
+	rows := newRow("fe", "FRONTEND", "0", map[int]string{4: "many", statusField: "OPEN"}) +
+		newRow("be", "BACKEND", "1", map[int]string{4: "1", statusField: "SLEEPING"}) +
+		newRow("be", "srv", "2", map[int]string{4: "1", statusField: "UP 1/3"})
+	expected := `
+# HELP haproxy_exporter_csv_parse_failures_total Number of errors while parsing CSV by reason: short_row, bad_number, bad_status or csv_error.
+# TYPE haproxy_exporter_csv_parse_failures_total counter
+haproxy_exporter_csv_parse_failures_total{reason="bad_number"} 1
+haproxy_exporter_csv_parse_failures_total{reason="bad_status"} 1
+haproxy_exporter_csv_parse_failures_total{reason="csv_error"} 0
+haproxy_exporter_csv_parse_failures_total{reason="short_row"} 0
+`
+	expectRowMetrics(t, rows, expected, "haproxy_exporter_csv_parse_failures_total")
+}
+
+func TestOlderHaproxyVersions(t *testing.T) {
+	const data = `foo,FRONTEND,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+foo,foo-instance-0,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+foo,BACKEND,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+`
+	h := newHaproxy([]byte(data))
+	defer h.Close()
+
+	e, _ := NewExporter(h.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}, log.NewNopLogger())
+
+	expectMetrics(t, e, "older_haproxy_versions.metrics")
+}
+
+func TestConfigChangeDetection(t *testing.T) {
+	h := newHaproxy([]byte(""))
+	defer h.Close()
+
+	e, _ := NewExporter(h.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}, log.NewNopLogger())
+	ch := make(chan prometheus.Metric)
+
+	go func() {
+		defer close(ch)
+		e.Collect(ch)
+	}()
+
+	// TODO: Add a proper test here. Vet the possibilities of the new
+	// client_golang to do this easily. If better test support is needed,
+	// add it to client_golang first. (See also readCounter() above.)
+
+	// Suck up the remaining metrics.
+	for range ch {
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	exit := make(chan bool)
+	s := httptest.NewServer(handlerStale(exit))
+	defer func() {
+		// s.Close() will block until the handler
+		// returns, so we need to make it exit.
+		exit <- true
+		s.Close()
+	}()
+
+	e, err := NewExporter(s.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 1 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMetrics(t, e, "deadline.metrics")
+}
+
+func TestCollectWhileOtherTargetBlocks(t *testing.T) {
+	started, exit := make(chan bool), make(chan bool)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		<-exit
+	}))
+	defer slow.Close()
+	fast := newHaproxy([]byte("foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"))
+	defer fast.Close()
+
+	var exporters []*Exporter
+	for _, uri := range []string{slow.URL, fast.URL} {
+		e, err := NewExporter(uri, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		exporters = append(exporters, e)
+	}
+
+	slowDone := make(chan bool)
+	go func() {
+		testutil.CollectAndCount(exporters[0])
+		close(slowDone)
+	}()
+	<-started
+
+	// The scrape of the slow target doesn't hold up that of the other one.
+	fastDone := make(chan bool)
+	go func() {
+		testutil.CollectAndCount(exporters[1])
+		close(fastDone)
+	}()
+	select {
+	case <-fastDone:
+	case <-time.After(2 * time.Second):
+		t.Error("scrape of a target blocked by the scrape of another target")
+	}
+	exit <- true
+	<-slowDone
+	<-fastDone
+}
+
+func TestNotFound(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	defer s.Close()
+
+	e, err := NewExporter(s.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 1 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMetrics(t, e, "not_found.metrics")
+}
+
+func newHaproxyUnix(file, statsPayload string, infoPayload string) (io.Closer, error) {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	l, err := net.Listen("unix", file)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch l {
+					case "show info\n":
+						c.Write([]byte(infoPayload))
+						return
+					case "show stat\n":
+						c.Write([]byte(statsPayload))
+						return
+					default:
+						// invalid command
+						return
+					}
+				}
+			}(c)
+		}
+	}()
+	return l, nil
+}
+
+func TestUnixDomain(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+	srv, err := newHaproxyUnix(testSocket, "test,127.0.0.1:8080,0,0,0,0,0,0,0,0,,0,,0,0,0,0,no check,1,1,0,0,,,0,,1,1,1,,0,,2,0,,0,,,,0,0,0,0,0,0,0,,,,0,0,,,,,,,,,,,\n", testInfo)
+	if err != nil {
+		t.Fatalf("can't start test server: %v", err)
+	}
+	defer srv.Close()
+
+	e, err := NewExporter("unix:"+testSocket, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMetrics(t, e, "unix_domain.metrics")
+}
+
+func TestInfoMetrics(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+	info := testInfo + "Ulimit-n: 4033\nMaxsock: 4033\nMaxconn: 2000\nCurrConns: 12\nSslFrontendKeyRate: 3\nSslFrontendSessionReuse_pct: 50\nSslCacheLookups: 10\nSslCacheMisses: 2\nSslBackendKeyRate: invalid\nTotalWarnings: 3\nStart_time_sec: 1700000000\n"
+	srv, err := newHaproxyUnix(testSocket, "test,127.0.0.1:8080,0,0,0,0,0,0,0,0,,0,,0,0,0,0,no check,1,1,0,0,,,0,,1,1,1,,0,,2,0,,0,,,,0,0,0,0,0,0,0,,,,0,0,,,,,,,,,,,\n", info)
+	if err != nil {
+		t.Fatalf("can't start test server: %v", err)
+	}
+	defer srv.Close()
+
+	e, err := NewExporter("unix:"+testSocket, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, NamingScheme: NamingBoth}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_process_connections Current number of connections.
+# TYPE haproxy_process_connections gauge
+haproxy_process_connections 12
+# HELP haproxy_process_connections_limit Maximum number of concurrent connections.
+# TYPE haproxy_process_connections_limit gauge
+haproxy_process_connections_limit 2000
+# HELP haproxy_process_fd_limit Maximum number of open file descriptors.
+# TYPE haproxy_process_fd_limit gauge
+haproxy_process_fd_limit 4033
+# HELP haproxy_process_ssl_cache_lookups_total Total number of SSL session cache lookups.
+# TYPE haproxy_process_ssl_cache_lookups_total counter
+haproxy_process_ssl_cache_lookups_total 10
+# HELP haproxy_process_ssl_cache_misses_total Total number of SSL session cache misses.
+# TYPE haproxy_process_ssl_cache_misses_total counter
+haproxy_process_ssl_cache_misses_total 2
+# HELP haproxy_process_ssl_frontend_key_rate Current number of SSL keys computed per second by frontends.
+# TYPE haproxy_process_ssl_frontend_key_rate gauge
+haproxy_process_ssl_frontend_key_rate 3
+# HELP haproxy_process_ssl_frontend_session_reuse_ratio Percentage of SSL sessions of frontends resumed from the cache.
+# TYPE haproxy_process_ssl_frontend_session_reuse_ratio gauge
+haproxy_process_ssl_frontend_session_reuse_ratio 0.5
+# HELP haproxy_process_last_reload_time_seconds Time of the start or last reload of the process since the epoch in seconds.
+# TYPE haproxy_process_last_reload_time_seconds gauge
+haproxy_process_last_reload_time_seconds 1.7e+09
+# HELP haproxy_process_warnings_total Total number of warnings emitted, including those of the configuration.
+# TYPE haproxy_process_warnings_total counter
+haproxy_process_warnings_total 3
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected),
+		"haproxy_process_connections",
+		"haproxy_process_connections_limit",
+		"haproxy_process_fd_limit",
+		"haproxy_process_ssl_backend_key_rate",
+		"haproxy_process_ssl_cache_lookups_total",
+		"haproxy_process_ssl_cache_misses_total",
+		"haproxy_process_ssl_frontend_key_rate",
+		"haproxy_process_ssl_frontend_session_reuse_ratio",
+		"haproxy_process_last_reload_time_seconds",
+		"haproxy_process_warnings_total",
+	); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnixDomainNotFound(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+
+	if err := os.Remove(testSocket); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	e, _ := NewExporter("unix:"+testSocket, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 1 * time.Second}, log.NewNopLogger())
+	expectMetrics(t, e, "unix_domain_not_found.metrics")
+}
+
+func TestUnixDomainDeadline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+
+	exit := make(chan struct{})
+	defer close(exit)
+
+	if err := os.Remove(testSocket); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", testSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			if _, err := l.Accept(); err != nil {
+				return
+			}
+			go func() {
+				// block
+				<-exit
+			}()
+		}
+	}()
+
+	e, _ := NewExporter("unix:"+testSocket, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 1 * time.Second}, log.NewNopLogger())
+
+	expectMetrics(t, e, "unix_domain_deadline.metrics")
+}
+
+func TestInvalidScheme(t *testing.T) {
+	e, err := NewExporter("gopher://gopher.quux.org", ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 1 * time.Second}, log.NewNopLogger())
+	if expect, got := (*Exporter)(nil), e; expect != got {
+		t.Errorf("expected %v, got %v", expect, got)
+	}
+	if err == nil {
+		t.Fatalf("expected non-nil error")
+	}
+	if expect, got := err.Error(), `unsupported scheme: "gopher"`; expect != got {
+		t.Errorf("expected %q, got %q", expect, got)
+	}
+}
+
+func TestCustomFetcher(t *testing.T) {
+	const data = `foo,FRONTEND,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+foo,foo-instance-0,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+foo,BACKEND,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+`
+	fetcher := FetcherFunc(func(ctx context.Context) (io.ReadCloser, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("expected fetch context with deadline")
+		}
+		return io.NopCloser(strings.NewReader(data)), nil
+	})
+	e, err := NewExporter("ssh://haproxy.example.com", ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second, StatFetcher: fetcher}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMetrics(t, e, "older_haproxy_versions.metrics")
+}
+
+func TestParseStatusField(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"UP", 1},
+		{"UP 1/3", 1},
+		{"UP 2/3", 1},
+		{"OPEN", 1},
+		{"no check", 1},
+		{"DOWN", 0},
+		{"UP 4/5", 1},
+		{"DOWN 1/2", 0},
+		{"DOWN 7/10", 0},
+		{"DRAIN", 1},
+		{"NOLB", 0},
+		{"MAINT(via)", 0},
+		{"MAINT", 0}, // prometheus/haproxy_exporter#35
+		{"unknown", 0},
+	}
+
+	for _, tt := range tests {
+		if have := parseStatusField(tt.input); tt.want != have {
+			t.Errorf("want status value %d for input %s, have %d",
+				tt.want,
+				tt.input,
+				have,
+			)
+		}
+	}
+}
+
+// TestParseFieldValue checks the values exported for CSV fields.
+func TestParseFieldValue(t *testing.T) {
+	tests := []struct {
+		field int
+		value string
+		want  float64
+	}{
+		{field: checkDurationField, value: "12", want: 0.012},
+		{field: qtimeMsField, value: "1500", want: 1.5},
+		{field: rtimeMsField, value: "1001", want: 1.001},
+		{field: ttimeMsField, value: "0", want: 0},
+		{field: statusField, value: "UP 1/3", want: 1},
+		{field: 7, value: "42", want: 42},
+	}
+
+	for _, tt := range tests {
+		value, err := parseFieldValue(tt.field, tt.value)
+		if err != nil {
+			t.Errorf("field %d: %v", tt.field, err)
+			continue
+		}
+		if have := serverMetrics[tt.field].value(value); tt.want != have {
+			t.Errorf("field %d: want %v for %q, have %v", tt.field, tt.want, tt.value, have)
+		}
+	}
+}
+
+func TestCheckTransition(t *testing.T) {
+	tests := map[string]float64{
+		"UP":         0,
+		"no check":   0,
+		"UP 1/4":     0.75,
+		"UP 4/4":     0,
+		"DOWN 1/4":   0.25,
+		"DOWN 0/2":   0,
+		"DOWN x/2":   0,
+		"DOWN 3/2":   0,
+		"MAINT(via)": 0,
+	}
+	for status, want := range tests {
+		if have := checkTransition(status); want != have {
+			t.Errorf("%q: want %v, have %v", status, want, have)
+		}
+	}
+}
+
+func TestMetricTypes(t *testing.T) {
+	for _, m := range []metrics{frontendMetrics, backendMetrics, metrics(serverMetrics)} {
+		for field, metric := range m {
+			isCounter := metric.Type == prometheus.CounterValue
+			if hasSuffix := strings.HasSuffix(metric.fqName, "_total"); isCounter != hasSuffix {
+				t.Errorf("field %d: %s has type %v", field, metric.fqName, metric.Type)
+			}
+		}
+	}
+}
+
+func TestBackendAvailability(t *testing.T) {
+	row := make([]string, minimumCsvFieldCount)
+	row[pxnameField], row[svnameField], row[statusField], row[typeField] = "foo", "BACKEND", "DOWN", "1"
+	row[22], row[23], row[24] = "2", "60", "3600"
+	h := newHaproxy([]byte(strings.Join(row, ",") + ",\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_backend_down_transitions_total Total number of transitions of the backend from UP to DOWN.
+# TYPE haproxy_backend_down_transitions_total counter
+haproxy_backend_down_transitions_total{backend="foo"} 2
+# HELP haproxy_backend_downtime_seconds_total Total downtime in seconds.
+# TYPE haproxy_backend_downtime_seconds_total counter
+haproxy_backend_downtime_seconds_total{backend="foo"} 3600
+# HELP haproxy_backend_state_change_seconds Number of seconds since the last change of the state of the backend.
+# TYPE haproxy_backend_state_change_seconds gauge
+haproxy_backend_state_change_seconds{backend="foo"} 60
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_backend_down_transitions_total", "haproxy_backend_downtime_seconds_total", "haproxy_backend_state_change_seconds"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTimingMilliseconds(t *testing.T) {
+	row := make([]string, ttimeMsField+1)
+	row[pxnameField], row[svnameField], row[statusField], row[typeField] = "foo", "BACKEND", "UP", "1"
+	row[rtimeMsField] = "1001"
+	h := newHaproxy([]byte(strings.Join(row, ",") + ",\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, TimingMilliseconds: true}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_backend_http_response_time_average_milliseconds Avg. HTTP response time for last 1024 successful connections, in milliseconds.
+# TYPE haproxy_backend_http_response_time_average_milliseconds gauge
+haproxy_backend_http_response_time_average_milliseconds{backend="foo"} 1001
+# HELP haproxy_backend_http_response_time_average_seconds Avg. HTTP response time for last 1024 successful connections.
+# TYPE haproxy_backend_http_response_time_average_seconds gauge
+haproxy_backend_http_response_time_average_seconds{backend="foo"} 1.001
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_backend_http_response_time_average_milliseconds", "haproxy_backend_http_response_time_average_seconds"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	row := make([]string, minimumCsvFieldCount+6)
+	row[pxnameField], row[svnameField], row[statusField], row[typeField] = "foo", "bar", "DOWN", "2"
+	row[checkStatusField], row[37] = "* L7STS", "503"
+	h := newHaproxy([]byte(strings.Join(row, ",") + ",\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_server_check_code Status code of the last layer 5-7 health check, e.g. the HTTP status of HTTP checks.
+# TYPE haproxy_server_check_code gauge
+haproxy_server_check_code{backend="foo",server="bar"} 503
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_server_check_code"); err != nil {
+		t.Error(err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, mf := range mfs {
+		if mf.GetName() != "haproxy_server_check_status" {
+			continue
+		}
+		found = len(mf.Metric)
+		for _, m := range mf.Metric {
+			result := ""
+			for _, l := range m.Label {
+				if l.GetName() == "result" {
+					result = l.GetValue()
+				}
+			}
+			if want, have := result == "L7STS", m.GetGauge().GetValue() == 1; want != have {
+				t.Errorf("result %s: want %v, have value %v", result, want, m.GetGauge().GetValue())
+			}
+		}
+	}
+	if want := len(serverCheckStatus.states); found != want {
+		t.Errorf("want %d check status series, have %d", want, found)
+	}
+}
+
+func TestServerTimingAverages(t *testing.T) {
+	row := make([]string, ttimeMsField+1)
+	row[pxnameField], row[svnameField], row[statusField], row[typeField] = "foo", "bar", "UP", "2"
+	row[qtimeMsField], row[ctimeMsField], row[rtimeMsField], row[ttimeMsField] = "1", "2", "30", "250"
+	h := newHaproxy([]byte(strings.Join(row, ",") + ",\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_server_http_connect_time_average_seconds Avg. HTTP connect time for last 1024 successful connections.
+# TYPE haproxy_server_http_connect_time_average_seconds gauge
+haproxy_server_http_connect_time_average_seconds{backend="foo",server="bar"} 0.002
+# HELP haproxy_server_http_queue_time_average_seconds Avg. HTTP queue time for last 1024 successful connections.
+# TYPE haproxy_server_http_queue_time_average_seconds gauge
+haproxy_server_http_queue_time_average_seconds{backend="foo",server="bar"} 0.001
+# HELP haproxy_server_http_response_time_average_seconds Avg. HTTP response time for last 1024 successful connections.
+# TYPE haproxy_server_http_response_time_average_seconds gauge
+haproxy_server_http_response_time_average_seconds{backend="foo",server="bar"} 0.03
+# HELP haproxy_server_http_total_time_average_seconds Avg. HTTP total time for last 1024 successful connections.
+# TYPE haproxy_server_http_total_time_average_seconds gauge
+haproxy_server_http_total_time_average_seconds{backend="foo",server="bar"} 0.25
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected),
+		"haproxy_server_http_queue_time_average_seconds",
+		"haproxy_server_http_connect_time_average_seconds",
+		"haproxy_server_http_response_time_average_seconds",
+		"haproxy_server_http_total_time_average_seconds",
+	); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTimingMax(t *testing.T) {
+	row := make([]string, ttimeMaxMsField+1)
+	row[pxnameField], row[svnameField], row[statusField], row[typeField] = "foo", "BACKEND", "UP", "1"
+	row[rtimeMaxMsField] = "2500"
+	h := newHaproxy([]byte(strings.Join(row, ",") + ",\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_backend_http_response_time_max_seconds Maximum observed HTTP response time.
+# TYPE haproxy_backend_http_response_time_max_seconds gauge
+haproxy_backend_http_response_time_max_seconds{backend="foo"} 2.5
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_backend_http_response_time_max_seconds", "haproxy_backend_http_queue_time_max_seconds"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServerConnectionPool(t *testing.T) {
+	row := make([]string, len(csvFieldNames))
+	row[pxnameField], row[svnameField], row[statusField], row[typeField] = "foo", "bar", "UP", "2"
+	row[88], row[89], row[95], row[96], row[97], row[98] = "3", "10", "1", "2", "4", "5"
+	h := newHaproxy([]byte(strings.Join(row, ",") + ",\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_server_idle_connections Current number of idle connections available for reuse.
+# TYPE haproxy_server_idle_connections gauge
+haproxy_server_idle_connections{backend="foo",server="bar"} 3
+# HELP haproxy_server_idle_connections_limit Configured limit on the number of idle connections available for reuse.
+# TYPE haproxy_server_idle_connections_limit gauge
+haproxy_server_idle_connections_limit{backend="foo",server="bar"} 10
+# HELP haproxy_server_needed_connections_estimate Estimated number of connections needed.
+# TYPE haproxy_server_needed_connections_estimate gauge
+haproxy_server_needed_connections_estimate{backend="foo",server="bar"} 5
+# HELP haproxy_server_safe_idle_connections Current number of safe idle connections.
+# TYPE haproxy_server_safe_idle_connections gauge
+haproxy_server_safe_idle_connections{backend="foo",server="bar"} 2
+# HELP haproxy_server_unsafe_idle_connections Current number of unsafe idle connections.
+# TYPE haproxy_server_unsafe_idle_connections gauge
+haproxy_server_unsafe_idle_connections{backend="foo",server="bar"} 1
+# HELP haproxy_server_used_connections Current number of connections in use.
+# TYPE haproxy_server_used_connections gauge
+haproxy_server_used_connections{backend="foo",server="bar"} 4
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected),
+		"haproxy_server_idle_connections",
+		"haproxy_server_idle_connections_limit",
+		"haproxy_server_needed_connections_estimate",
+		"haproxy_server_safe_idle_connections",
+		"haproxy_server_unsafe_idle_connections",
+		"haproxy_server_used_connections",
+	); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestModernColumns(t *testing.T) {
+	rows := newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", 46: "3", 47: "12", 77: "5", 78: "40", 94: "1"}) +
+		newRow("bar", "BACKEND", "1", map[int]string{statusField: "UP", 10: "2", 20: "1", 48: "100", 55: "7", 84: "30", 85: "20", 94: "0"}) +
+		newRow("bar", "baz", "2", map[int]string{statusField: "UP", 29: "50", 48: "100", 55: "7", 63: "200", 64: "15", 70: "2", 71: "3", 72: "4", 84: "30", 85: "20", 94: "0"})
+
+	expected := `
+# HELP haproxy_backend_backup_servers Current number of backup servers.
+# TYPE haproxy_backend_backup_servers gauge
+haproxy_backend_backup_servers{backend="bar"} 1
+# HELP haproxy_backend_connection_reuses_total Total number of connections to the servers of the backend reused instead of established.
+# TYPE haproxy_backend_connection_reuses_total counter
+haproxy_backend_connection_reuses_total{backend="bar"} 20
+# HELP haproxy_backend_last_session_seconds Number of seconds since the last session assigned to a server of the backend, -1 if there was none.
+# TYPE haproxy_backend_last_session_seconds gauge
+haproxy_backend_last_session_seconds{backend="bar"} 7
+# HELP haproxy_backend_requests_denied_total Total of requests denied for security.
+# TYPE haproxy_backend_requests_denied_total counter
+haproxy_backend_requests_denied_total{backend="bar"} 2
+# HELP haproxy_frontend_current_connection_rate Current number of connections per second over last elapsed second.
+# TYPE haproxy_frontend_current_connection_rate gauge
+haproxy_frontend_current_connection_rate{frontend="foo"} 5
+# HELP haproxy_frontend_internal_errors_total Total number of internal errors.
+# TYPE haproxy_frontend_internal_errors_total counter
+haproxy_frontend_internal_errors_total{frontend="foo"} 1
+# HELP haproxy_frontend_max_http_request_rate Maximum observed number of HTTP requests per second.
+# TYPE haproxy_frontend_max_http_request_rate gauge
+haproxy_frontend_max_http_request_rate{frontend="foo"} 12
+# HELP haproxy_server_agent_duration_seconds Duration of the last agent check of the server, in seconds.
+# TYPE haproxy_server_agent_duration_seconds gauge
+haproxy_server_agent_duration_seconds{backend="bar",server="baz"} 0.015
+# HELP haproxy_server_agent_health Health of the server as counted by agent checks, between 0 and rise+fall-1.
+# TYPE haproxy_server_agent_health gauge
+haproxy_server_agent_health{backend="bar",server="baz"} 4
+# HELP haproxy_server_connection_attempts_total Total number of attempts to establish a connection to the server.
+# TYPE haproxy_server_connection_attempts_total counter
+haproxy_server_connection_attempts_total{backend="bar",server="baz"} 30
+# HELP haproxy_server_throttle_percent Current throttle of the server during slowstart, in percent of its weight.
+# TYPE haproxy_server_throttle_percent gauge
+haproxy_server_throttle_percent{backend="bar",server="baz"} 50
+`
+	expectRowMetrics(t, rows, expected,
+		"haproxy_backend_backup_servers",
+		"haproxy_backend_connection_reuses_total",
+		"haproxy_backend_last_session_seconds",
+		"haproxy_backend_requests_denied_total",
+		"haproxy_frontend_current_connection_rate",
+		"haproxy_frontend_internal_errors_total",
+		"haproxy_frontend_max_http_request_rate",
+		"haproxy_server_agent_duration_seconds",
+		"haproxy_server_agent_health",
+		"haproxy_server_connection_attempts_total",
+		"haproxy_server_throttle_percent",
+	)
+}
+
+func TestServerRoles(t *testing.T) {
+	rows := newRow("foo", "primary", "2", map[int]string{statusField: "UP", 19: "1", 20: "0"}) +
+		newRow("foo", "spare", "2", map[int]string{statusField: "UP", 19: "0", 20: "1"})
+	expected := `
+# HELP haproxy_server_active Whether the server is an active server (1) or not (0).
+# TYPE haproxy_server_active gauge
+haproxy_server_active{backend="foo",server="primary"} 1
+haproxy_server_active{backend="foo",server="spare"} 0
+# HELP haproxy_server_backup Whether the server is a backup server (1) or not (0).
+# TYPE haproxy_server_backup gauge
+haproxy_server_backup{backend="foo",server="primary"} 0
+haproxy_server_backup{backend="foo",server="spare"} 1
+`
+	expectRowMetrics(t, rows, expected, "haproxy_server_active", "haproxy_server_backup")
+}
+
+func TestServerFailedHealthAnalyses(t *testing.T) {
+	expected := `
+# HELP haproxy_server_check_failed_hana_total Total number of failed health analyses of traffic, enabled with observe.
+# TYPE haproxy_server_check_failed_hana_total counter
+haproxy_server_check_failed_hana_total{backend="foo",server="bar"} 7
+`
+	expectRowMetrics(t, newRow("foo", "bar", "2", map[int]string{statusField: "UP", 45: "7"}), expected, "haproxy_server_check_failed_hana_total")
+}
+
+func TestServerCheckConfig(t *testing.T) {
+	expected := `
+# HELP haproxy_server_check_fall Configured number of consecutive failed health checks to consider the server DOWN.
+# TYPE haproxy_server_check_fall gauge
+haproxy_server_check_fall{backend="foo",server="bar"} 5
+# HELP haproxy_server_check_health Health of the server as counted by health checks, between 0 and rise+fall-1. The server is UP from rise on.
+# TYPE haproxy_server_check_health gauge
+haproxy_server_check_health{backend="foo",server="bar"} 4
+# HELP haproxy_server_check_rise Configured number of consecutive successful health checks to consider the server UP.
+# TYPE haproxy_server_check_rise gauge
+haproxy_server_check_rise{backend="foo",server="bar"} 1
+`
+	expectRowMetrics(t, newRow("foo", "bar", "2", map[int]string{statusField: "UP 4/5", 67: "1", 68: "5", 69: "4"}), expected, "haproxy_server_check_rise", "haproxy_server_check_fall", "haproxy_server_check_health")
+}
+
+func TestUserWeight(t *testing.T) {
+	rows := newRow("foo", "bar", "2", map[int]string{statusField: "UP", 18: "25", 99: "100"}) +
+		newRow("foo", "BACKEND", "1", map[int]string{statusField: "UP", 18: "25", 99: "100"})
+	expected := `
+# HELP haproxy_backend_user_weight Total weight of the servers in the backend as configured or set on the CLI.
+# TYPE haproxy_backend_user_weight gauge
+haproxy_backend_user_weight{backend="foo"} 100
+# HELP haproxy_backend_weight Total weight of the servers in the backend.
+# TYPE haproxy_backend_weight gauge
+haproxy_backend_weight{backend="foo"} 25
+# HELP haproxy_server_user_weight Weight of the server as configured or set on the CLI, which the current weight differs from e.g. during slowstart.
+# TYPE haproxy_server_user_weight gauge
+haproxy_server_user_weight{backend="foo",server="bar"} 100
+# HELP haproxy_server_weight Current weight of the server.
+# TYPE haproxy_server_weight gauge
+haproxy_server_weight{backend="foo",server="bar"} 25
+`
+	expectRowMetrics(t, rows, expected, "haproxy_backend_user_weight", "haproxy_backend_weight", "haproxy_server_user_weight", "haproxy_server_weight")
+}
+
+func TestProxyInfo(t *testing.T) {
+	rows := newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", modeField: "http", algoField: ""}) +
+		newRow("foo", "BACKEND", "1", map[int]string{statusField: "UP", modeField: "tcp", algoField: "leastconn"})
+	expected := `
+# HELP haproxy_backend_info Information about the backend, the mode it runs in and its load balancing algorithm.
+# TYPE haproxy_backend_info gauge
+haproxy_backend_info{algo="leastconn",backend="foo",mode="tcp"} 1
+# HELP haproxy_frontend_info Information about the frontend, the mode it runs in.
+# TYPE haproxy_frontend_info gauge
+haproxy_frontend_info{frontend="foo",mode="http"} 1
+`
+	expectRowMetrics(t, rows, expected, "haproxy_backend_info", "haproxy_frontend_info")
+}
+
+func TestHTTPCache(t *testing.T) {
+	rows := newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", 86: "10", 87: "4"}) +
+		newRow("foo", "BACKEND", "1", map[int]string{statusField: "UP", 86: "20", 87: "5"})
+	expected := `
+# HELP haproxy_backend_http_cache_hits_total Total number of HTTP cache hits.
+# TYPE haproxy_backend_http_cache_hits_total counter
+haproxy_backend_http_cache_hits_total{backend="foo"} 5
+# HELP haproxy_backend_http_cache_lookups_total Total number of HTTP cache lookups.
+# TYPE haproxy_backend_http_cache_lookups_total counter
+haproxy_backend_http_cache_lookups_total{backend="foo"} 20
+# HELP haproxy_frontend_http_cache_hits_total Total number of HTTP cache hits.
+# TYPE haproxy_frontend_http_cache_hits_total counter
+haproxy_frontend_http_cache_hits_total{frontend="foo"} 4
+# HELP haproxy_frontend_http_cache_lookups_total Total number of HTTP cache lookups.
+# TYPE haproxy_frontend_http_cache_lookups_total counter
+haproxy_frontend_http_cache_lookups_total{frontend="foo"} 10
+`
+	expectRowMetrics(t, rows, expected, "haproxy_backend_http_cache_hits_total", "haproxy_backend_http_cache_lookups_total", "haproxy_frontend_http_cache_hits_total", "haproxy_frontend_http_cache_lookups_total")
+}
+
+func TestQUICCounters(t *testing.T) {
+	// The QUIC fields follow columns unknown to the exporter.
+	header := append(append(append([]string(nil), csvFieldNames[:quicRxbufFullField]...), "-", "ssl_sess"), csvFieldNames[quicRxbufFullField:]...)
+	row := make([]string, len(header))
+	row[pxnameField], row[svnameField], row[typeField], row[statusField] = "foo", "FRONTEND", "0", "OPEN"
+	row[len(header)-17], row[len(header)-4], row[len(header)-2] = "1", "7", "3"
+	stats := "# " + strings.Join(header, ",") + "\n" + strings.Join(row, ",") + "\n"
+	expected := `
+# HELP haproxy_frontend_quic_half_open_connections Current number of QUIC connections with an unfinished handshake.
+# TYPE haproxy_frontend_quic_half_open_connections gauge
+haproxy_frontend_quic_half_open_connections{frontend="foo"} 7
+# HELP haproxy_frontend_quic_rx_buffer_full_total Total number of QUIC datagrams dropped because the receive buffer was full.
+# TYPE haproxy_frontend_quic_rx_buffer_full_total counter
+haproxy_frontend_quic_rx_buffer_full_total{frontend="foo"} 1
+# HELP haproxy_frontend_quic_stateless_resets_sent_total Total number of sent QUIC stateless resets.
+# TYPE haproxy_frontend_quic_stateless_resets_sent_total counter
+haproxy_frontend_quic_stateless_resets_sent_total{frontend="foo"} 3
+`
+	expectRowMetrics(t, stats, expected, "haproxy_frontend_quic_half_open_connections", "haproxy_frontend_quic_rx_buffer_full_total", "haproxy_frontend_quic_stateless_resets_sent_total")
+
+	// Without a header, the same fields are those of the unknown columns.
+	expectRowMetrics(t, strings.Join(row, ",")+"\n", "", "haproxy_frontend_quic_half_open_connections", "haproxy_frontend_quic_rx_buffer_full_total", "haproxy_frontend_quic_stateless_resets_sent_total")
+}
+
+func TestHaproxy28(t *testing.T) {
+	// The stats of HAProxy 2.8 have the columns of the SSL, HTTP/2 and QUIC
+	// stats modules after a "-" column.
+	stats, err := os.ReadFile("test/haproxy28.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+# HELP haproxy_backend_user_weight Total weight of the servers in the backend as configured or set on the CLI.
+# TYPE haproxy_backend_user_weight gauge
+haproxy_backend_user_weight{backend="be_app"} 2
+# HELP haproxy_frontend_protocol_requests_total Total number of requests by protocol: http1, http2, http3 or other.
+# TYPE haproxy_frontend_protocol_requests_total counter
+haproxy_frontend_protocol_requests_total{frontend="fe_main",protocol="http1"} 40112
+haproxy_frontend_protocol_requests_total{frontend="fe_main",protocol="http2"} 22617
+haproxy_frontend_protocol_requests_total{frontend="fe_main",protocol="http3"} 747
+haproxy_frontend_protocol_requests_total{frontend="fe_main",protocol="other"} 0
+# HELP haproxy_frontend_protocol_sessions_total Total number of sessions by protocol: http1, http2, http3 or other.
+# TYPE haproxy_frontend_protocol_sessions_total counter
+haproxy_frontend_protocol_sessions_total{frontend="fe_main",protocol="http1"} 31077
+haproxy_frontend_protocol_sessions_total{frontend="fe_main",protocol="http2"} 16544
+haproxy_frontend_protocol_sessions_total{frontend="fe_main",protocol="http3"} 592
+haproxy_frontend_protocol_sessions_total{frontend="fe_main",protocol="other"} 0
+# HELP haproxy_frontend_quic_sent_packets_total Total number of sent QUIC packets.
+# TYPE haproxy_frontend_quic_sent_packets_total counter
+haproxy_frontend_quic_sent_packets_total{frontend="fe_main"} 183006
+# HELP haproxy_server_idle_connections Current number of idle connections available for reuse.
+# TYPE haproxy_server_idle_connections gauge
+haproxy_server_idle_connections{backend="be_app",server="app1"} 5
+haproxy_server_idle_connections{backend="be_app",server="app2"} 4
+`
+	expectRowMetrics(t, string(stats), expected, "haproxy_backend_user_weight", "haproxy_frontend_protocol_requests_total", "haproxy_frontend_protocol_sessions_total", "haproxy_frontend_quic_sent_packets_total", "haproxy_server_idle_connections")
+}
+
+func TestResponsesDenied(t *testing.T) {
+	rows := newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", 11: "1"}) +
+		newRow("foo", "bar", "2", map[int]string{statusField: "UP", 11: "2"}) +
+		newRow("foo", "BACKEND", "1", map[int]string{statusField: "UP", 11: "3"})
+	expected := `
+# HELP haproxy_backend_responses_denied_total Total of responses denied for security.
+# TYPE haproxy_backend_responses_denied_total counter
+haproxy_backend_responses_denied_total{backend="foo"} 3
+# HELP haproxy_frontend_responses_denied_total Total of responses denied for security.
+# TYPE haproxy_frontend_responses_denied_total counter
+haproxy_frontend_responses_denied_total{frontend="foo"} 1
+# HELP haproxy_server_responses_denied_total Total of responses denied for security.
+# TYPE haproxy_server_responses_denied_total counter
+haproxy_server_responses_denied_total{backend="foo",server="bar"} 2
+`
+	expectRowMetrics(t, rows, expected, "haproxy_backend_responses_denied_total", "haproxy_frontend_responses_denied_total", "haproxy_server_responses_denied_total")
+}
+
+func TestInterceptedRequests(t *testing.T) {
+	rows := newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", 80: "7"})
+	expected := `
+# HELP haproxy_frontend_intercepted_requests_total Total number of HTTP requests intercepted by HAProxy itself, e.g. for the stats page, redirects or cache hits.
+# TYPE haproxy_frontend_intercepted_requests_total counter
+haproxy_frontend_intercepted_requests_total{frontend="foo"} 7
+`
+	expectRowMetrics(t, rows, expected, "haproxy_frontend_intercepted_requests_total")
+}
+
+func TestFrontendDenied(t *testing.T) {
+	rows := newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", 10: "1", 81: "2", 82: "3"})
+	expected := `
+# HELP haproxy_frontend_connections_denied_total Total number of connections denied by tcp-request connection rules.
+# TYPE haproxy_frontend_connections_denied_total counter
+haproxy_frontend_connections_denied_total{frontend="foo"} 2
+# HELP haproxy_frontend_requests_denied_total Total of requests denied for security.
+# TYPE haproxy_frontend_requests_denied_total counter
+haproxy_frontend_requests_denied_total{frontend="foo"} 1
+# HELP haproxy_frontend_sessions_denied_total Total number of sessions denied by tcp-request session rules.
+# TYPE haproxy_frontend_sessions_denied_total counter
+haproxy_frontend_sessions_denied_total{frontend="foo"} 3
+`
+	expectRowMetrics(t, rows, expected, "haproxy_frontend_connections_denied_total", "haproxy_frontend_requests_denied_total", "haproxy_frontend_sessions_denied_total")
+}
+
+func TestFailedHeaderRewrites(t *testing.T) {
+	rows := newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", 83: "1"}) +
+		newRow("foo", "bar", "2", map[int]string{statusField: "UP", 83: "2"}) +
+		newRow("foo", "BACKEND", "1", map[int]string{statusField: "UP", 83: "3"})
+	expected := `
+# HELP haproxy_backend_failed_header_rewrites_total Total number of failed HTTP header rewrites.
+# TYPE haproxy_backend_failed_header_rewrites_total counter
+haproxy_backend_failed_header_rewrites_total{backend="foo"} 3
+# HELP haproxy_frontend_failed_header_rewrites_total Total number of failed HTTP header rewrites.
+# TYPE haproxy_frontend_failed_header_rewrites_total counter
+haproxy_frontend_failed_header_rewrites_total{frontend="foo"} 1
+# HELP haproxy_server_failed_header_rewrites_total Total number of failed HTTP header rewrites.
+# TYPE haproxy_server_failed_header_rewrites_total counter
+haproxy_server_failed_header_rewrites_total{backend="foo",server="bar"} 2
+`
+	expectRowMetrics(t, rows, expected, "haproxy_backend_failed_header_rewrites_total", "haproxy_frontend_failed_header_rewrites_total", "haproxy_server_failed_header_rewrites_total")
+}
+
+func TestUnlimitedValue(t *testing.T) {
+	h := newHaproxy([]byte("foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,0,,0,\nfoo,bar,,,1,2,100,,,,,,,,,,,UP,,,,,,,,,,,,,,,2,,,,\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, UnlimitedValue: "+Inf"}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_frontend_limit_session_rate Configured limit on new sessions per second.
+# TYPE haproxy_frontend_limit_session_rate gauge
+haproxy_frontend_limit_session_rate{frontend="foo"} +Inf
+# HELP haproxy_frontend_limit_sessions Configured session limit.
+# TYPE haproxy_frontend_limit_sessions gauge
+haproxy_frontend_limit_sessions{frontend="foo"} +Inf
+# HELP haproxy_server_limit_queue Configured queue limit.
+# TYPE haproxy_server_limit_queue gauge
+haproxy_server_limit_queue{backend="foo",server="bar"} +Inf
+# HELP haproxy_server_limit_sessions Configured session limit.
+# TYPE haproxy_server_limit_sessions gauge
+haproxy_server_limit_sessions{backend="foo",server="bar"} 100
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_frontend_limit_session_rate", "haproxy_frontend_limit_sessions", "haproxy_server_limit_queue", "haproxy_server_limit_sessions"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewExporter(h.URL, ExporterOpts{UnlimitedValue: "unlimited"}, log.NewNopLogger()); err == nil {
+		t.Errorf("want error for invalid unlimited value")
+	}
+}
+
+func TestStatusState(t *testing.T) {
+	tests := map[string]string{
+		"UP":                "UP",
+		"UP 1/3":            "UP",
+		"DOWN 2/5":          "DOWN",
+		"MAINT(via)":        "MAINT",
+		"MAINT(resolution)": "MAINT",
+		"DRAIN (agent)":     "DRAIN",
+		"no check":          "no_check",
+		"OPEN":              "OPEN",
+	}
+	for status, want := range tests {
+		if have := statusState(status); want != have {
+			t.Errorf("%q: want state %q, have %q", status, want, have)
+		}
+	}
+}
+
+func TestFrontendStatus(t *testing.T) {
+	h := newHaproxy([]byte("foo,FRONTEND,,,1,2,,,,,,,,,,,,FULL,,,,,,,,,,,,,,,0,\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_frontend_status Current status of the frontend, 1 for the state it is in.
+# TYPE haproxy_frontend_status gauge
+haproxy_frontend_status{frontend="foo",state="FULL"} 1
+haproxy_frontend_status{frontend="foo",state="OPEN"} 0
+haproxy_frontend_status{frontend="foo",state="PAUSED"} 0
+haproxy_frontend_status{frontend="foo",state="STOP"} 0
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_frontend_status"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFrontendStatusStopped(t *testing.T) {
+	rows := newRow("foo", "FRONTEND", "0", map[int]string{statusField: "STOP"}) +
+		newRow("bar", "FRONTEND", "0", map[int]string{statusField: "PAUSED"})
+	expected := `
+# HELP haproxy_frontend_status Current status of the frontend, 1 for the state it is in.
+# TYPE haproxy_frontend_status gauge
+haproxy_frontend_status{frontend="bar",state="FULL"} 0
+haproxy_frontend_status{frontend="bar",state="OPEN"} 0
+haproxy_frontend_status{frontend="bar",state="PAUSED"} 1
+haproxy_frontend_status{frontend="bar",state="STOP"} 0
+haproxy_frontend_status{frontend="foo",state="FULL"} 0
+haproxy_frontend_status{frontend="foo",state="OPEN"} 0
+haproxy_frontend_status{frontend="foo",state="PAUSED"} 0
+haproxy_frontend_status{frontend="foo",state="STOP"} 1
+`
+	expectRowMetrics(t, rows, expected, "haproxy_frontend_status")
+}
+
+func TestFilterServerMetrics(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ServerMetrics
+	}{
+		{input: "", want: ServerMetrics{}},
+		{input: "8", want: ServerMetrics{8: serverMetrics[8]}},
+		{input: serverMetrics.String(), want: serverMetrics},
+		{input: "current_sessions,8", want: ServerMetrics{4: serverMetrics[4], 8: serverMetrics[8]}},
+		{input: "haproxy_server_sessions_max", want: ServerMetrics{5: serverMetrics[5]}},
+		{input: "http_responses_total", want: ServerMetrics{39: serverMetrics[39], 40: serverMetrics[40], 41: serverMetrics[41], 42: serverMetrics[42], 43: serverMetrics[43], 44: serverMetrics[44]}},
+	}
+
+	for _, tt := range tests {
+		have, err := FilterServerMetrics(tt.input)
+		if err != nil {
+			t.Errorf("unexpected error for input %s: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.want, have) {
+			t.Errorf("want filtered metrics %+v for input %q, have %+v",
+				tt.want,
+				tt.input,
+				have,
+			)
+		}
+	}
+
+	if _, err := FilterServerMetrics("current_sessions,unknown_metric"); err == nil {
+		t.Error("expected an error for an unknown metric name")
+	}
+}
+
+func BenchmarkExtract(b *testing.B) {
+	config, err := os.ReadFile("test/haproxy.csv")
+	if err != nil {
+		b.Fatalf("could not read config file: %v", err.Error())
+	}
+
+	h := newHaproxy(config)
+	defer h.Close()
+
+	e, _ := NewExporter(h.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}, log.NewNopLogger())
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ch := make(chan prometheus.Metric)
+		go func(ch chan prometheus.Metric) {
+			for range ch {
+			}
+		}(ch)
+
+		e.Collect(ch)
+		close(ch)
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	b.Logf("%d bytes used after %d runs", after.Alloc-before.Alloc, b.N)
+}
+
+// repeatReader reads row over and over, n bytes in all.
+type repeatReader struct {
+	row []byte
+	n   int
+	off int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	read := 0
+	for read < len(p) {
+		c := copy(p[read:], r.row[r.off:])
+		r.off = (r.off + c) % len(r.row)
+		read += c
+	}
+	r.n -= read
+	return read, nil
+}
+
+func TestStreamStatsMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("reads 256MB of stats")
+	}
+	// The long last field, which isn't exported, makes for large stats of
+	// rows exported quickly.
+	row := newRow("fe", "FRONTEND", "0", map[int]string{4: "1", statusField: "OPEN", len(csvFieldNames): strings.Repeat("9", 4096)})
+	const size = 256 << 20
+	rows := size / len(row)
+	fetcher := FetcherFunc(func(ctx context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(&repeatReader{row: []byte(row), n: rows * len(row)}), nil
+	})
+	e, err := NewExporter("ssh://haproxy.example.com", ExporterOpts{ServerMetrics: serverMetrics, Timeout: time.Minute, StatFetcher: fetcher, StreamStats: true}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	before, peak := stats.HeapInuse, stats.HeapInuse
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	sessions := 0
+	go func() {
+		defer close(done)
+		n := 0
+		for m := range ch {
+			if strings.Contains(m.Desc().String(), `"haproxy_frontend_current_sessions"`) {
+				sessions++
+			}
+			if n++; n%50000 == 0 {
+				runtime.ReadMemStats(&stats)
+				if stats.HeapInuse > peak {
+					peak = stats.HeapInuse
+				}
+			}
+		}
+	}()
+	e.Collect(ch)
+	close(ch)
+	<-done
+
+	if sessions != rows {
+		t.Errorf("want the current sessions of %d rows, have %d", rows, sessions)
+	}
+	if grown := peak - before; grown > 64<<20 {
+		t.Errorf("heap grew by %d MB reading %d MB of stats", grown>>20, size>>20)
+	}
+}
+
+func TestMetricBuffer(t *testing.T) {
+	stats := generateStats(2, 10)
+	fetched := make(chan struct{}, 1)
+	fetcher := FetcherFunc(func(ctx context.Context) (io.ReadCloser, error) {
+		fetched <- struct{}{}
+		return io.NopCloser(bytes.NewReader(stats)), nil
+	})
+	opts := ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, StatFetcher: fetcher}
+	want := gatherRowMetrics(t, "ssh://haproxy.example.com", opts)
+	<-fetched
+
+	opts.MetricBuffer = 10000
+	if have := gatherRowMetrics(t, "ssh://haproxy.example.com", opts); have != want {
+		t.Errorf("want the metrics exported without a buffer, have\n%s", have)
+	}
+	<-fetched
+
+	// With all its metrics buffered, the scrape finishes before any of them
+	// is received.
+	e, err := NewExporter("ssh://haproxy.example.com", opts, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan prometheus.Metric)
+	go func() {
+		e.Collect(ch)
+		close(ch)
+	}()
+	<-fetched
+	for start := time.Now(); !e.mutex.TryLock(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("want the scrape to finish before its metrics are received")
+		}
+	}
+	e.mutex.Unlock()
+	n := 0
+	for range ch {
+		n++
+	}
+	if n < 1000 {
+		t.Errorf("want the buffered metrics, have %d", n)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/prometheus/haproxy_exporter/parser"
+)
+
+// columns maps the fields of HAProxy stats to the field numbers metrics are
+// keyed by, which are those of csvFieldNames. Rows with their fields moved to
+// these numbers are called canonical.
+type columns struct {
+	// names holds the names of the fields of canonical rows: csvFieldNames,
+	// followed by the columns of the header unknown to the exporter.
+	names []string
+	// positions holds the position in the stats of every field of canonical
+	// rows, or -1 if the stats lack it. It is nil if the stats already are in
+	// canonical order.
+	positions []int
+}
+
+// defaultColumns are used for stats without a header, which are assumed to be
+// in canonical order. The fields of the QUIC stats module have no fixed
+// position, so that only stats with a header have them.
+var defaultColumns = columns{names: csvFieldNames[:quicRxbufFullField]}
+
+// newColumns returns the columns of stats with the given header, which may be
+// nil. The header must have the columns needed to tell the rows apart.
+func newColumns(header []string) (columns, error) {
+	if header == nil {
+		return defaultColumns, nil
+	}
+
+	byName := make(map[string]int, len(header))
+	for i, name := range header {
+		if _, ok := byName[name]; !ok {
+			byName[name] = i
+		}
+	}
+	for _, field := range []int{pxnameField, svnameField, typeField} {
+		if _, ok := byName[csvFieldNames[field]]; !ok {
+			return columns{}, fmt.Errorf("stats header lacks the %s column", csvFieldNames[field])
+		}
+	}
+
+	c := columns{names: append([]string(nil), csvFieldNames...)}
+	positions := make([]int, 0, len(header))
+	inOrder := true
+	for i, name := range csvFieldNames {
+		pos, ok := byName[name]
+		if !ok {
+			pos = -1
+		}
+		// Columns missing at the end of the header are missing from the rows
+		// as well, which is fine for canonical rows.
+		if pos != i && !(pos == -1 && i >= len(header)) {
+			inOrder = false
+		}
+		positions = append(positions, pos)
+		delete(byName, name)
+	}
+	for i, name := range header {
+		if pos, ok := byName[name]; ok && pos == i {
+			c.names = append(c.names, name)
+			positions = append(positions, i)
+		}
+	}
+
+	if !inOrder {
+		c.positions = positions
+	}
+	return c, nil
+}
+
+// canonical returns row with its fields moved to their canonical numbers.
+// Fields the stats lack are empty, and those of unknown position dropped.
+func (c columns) canonical(row []string) []string {
+	if c.positions == nil {
+		if len(row) > len(c.names) {
+			return row[:len(c.names)]
+		}
+		return row
+	}
+	return c.appendCanonical(make([]string, 0, len(c.positions)), row)
+}
+
+// appendCanonical appends the fields of row at their canonical numbers to
+// dst, like canonical, and returns the extended slice.
+func (c columns) appendCanonical(dst, row []string) []string {
+	if c.positions == nil {
+		return append(dst, c.canonical(row)...)
+	}
+	for _, pos := range c.positions {
+		field := ""
+		if pos >= 0 && pos < len(row) {
+			field = row[pos]
+		}
+		dst = append(dst, field)
+	}
+	return dst
+}
+
+// canonicalRows moves the fields of rows to their canonical numbers, like
+// canonical, allocating the fields of all rows at once.
+func (c columns) canonicalRows(rows []parser.Row) {
+	if c.positions == nil {
+		for i := range rows {
+			rows[i].Fields = c.canonical(rows[i].Fields)
+		}
+		return
+	}
+	n := len(c.positions)
+	fields := make([]string, len(rows)*n)
+	for i := range rows {
+		res := fields[i*n : (i+1)*n : (i+1)*n]
+		for j, pos := range c.positions {
+			if pos >= 0 && pos < len(rows[i].Fields) {
+				res[j] = rows[i].Fields[pos]
+			}
+		}
+		rows[i].Fields = res
+	}
+}
+
+// position returns the position in the stats of the canonical field i, or -1
+// if the stats lack it.
+func (c columns) position(i int) int {
+	if c.positions == nil {
+		return i
+	}
+	return c.positions[i]
+}
+
+// name returns the name of the canonical field i.
+func (c columns) name(i int) string {
+	if i < len(c.names) {
+		return c.names[i]
+	}
+	return fmt.Sprintf("column_%d", i)
+}
@@ -0,0 +1,129 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewColumns(t *testing.T) {
+	cols, err := newColumns(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols.positions != nil {
+		t.Errorf("want stats without header in canonical order")
+	}
+
+	cols, err = newColumns(csvFieldNames[:40])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols.positions != nil {
+		t.Errorf("want header of older HAProxy in canonical order")
+	}
+
+	cols, err = newColumns(append(append([]string(nil), csvFieldNames...), "h9_sess", "h9_req"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols.positions != nil {
+		t.Errorf("want header with new columns at the end in canonical order")
+	}
+	if want, have := "h9_req", cols.name(len(csvFieldNames)+1); want != have {
+		t.Errorf("want new column named %q, have %q", want, have)
+	}
+
+	// A column inserted in the middle shifts all following ones.
+	header := append([]string{"pxname", "svname", "new"}, csvFieldNames[2:]...)
+	cols, err = newColumns(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := make([]string, len(header))
+	copy(row, header)
+	canonical := cols.canonical(row)
+	if want, have := csvFieldNames, canonical[:len(csvFieldNames)]; !reflect.DeepEqual(want, have) {
+		t.Errorf("want canonical fields %q, have %q", want, have)
+	}
+	if want, have := "new", canonical[len(csvFieldNames)]; want != have {
+		t.Errorf("want unknown column last, have %q", have)
+	}
+	if want, have := 3, cols.position(2); want != have {
+		t.Errorf("want qcur at position %d, have %d", want, have)
+	}
+
+	if _, err := newColumns([]string{"pxname", "svname", "qcur"}); err == nil || !strings.Contains(err.Error(), "lacks the type column") {
+		t.Errorf("want error for header without type column, have %v", err)
+	}
+}
+
+func TestReorderedColumns(t *testing.T) {
+	// scur and smax swapped, and an unknown column before status.
+	header := "pxname,svname,qcur,qmax,smax,scur,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,new,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type"
+	h := newHaproxy([]byte("# " + header + ",\nfoo,FRONTEND,,,2,1,,,,,,,,,,,,x,OPEN,,,,,,,,,,,,,,,0,\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_frontend_current_sessions Current number of active sessions.
+# TYPE haproxy_frontend_current_sessions gauge
+haproxy_frontend_current_sessions{frontend="foo"} 1
+# HELP haproxy_frontend_max_sessions Maximum observed number of active sessions.
+# TYPE haproxy_frontend_max_sessions gauge
+haproxy_frontend_max_sessions{frontend="foo"} 2
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_frontend_current_sessions", "haproxy_frontend_max_sessions"); err != nil {
+		t.Error(err)
+	}
+}
+
+// haproxy22Header is the header of the stats of HAProxy 2.2.
+const haproxy22Header = "pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,last_chk,last_agt,qtime,ctime,rtime,ttime,agent_status,agent_code,agent_duration,check_desc,agent_desc,check_rise,check_fall,check_health,agent_rise,agent_fall,agent_health,addr,cookie,mode,algo,conn_rate,conn_rate_max,conn_tot,intercepted,dcon,dses,wrew,connect,reuse,cache_lookups,cache_hits,srv_icur,srv_ilim,qtime_max,ctime_max,rtime_max,ttime_max,eint,idle_conn_cur,safe_conn_cur,used_conn_cur,need_conn_est"
+
+func TestHeaderIdleConnections(t *testing.T) {
+	header := strings.Split(haproxy22Header, ",")
+	row := make([]string, len(header))
+	row[pxnameField], row[svnameField], row[statusField], row[typeField] = "foo", "bar", "UP", "2"
+	row[88], row[89] = "3", "10"
+	h := newHaproxy([]byte("# " + haproxy22Header + ",\n" + strings.Join(row, ",") + ",\n"))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_server_idle_connections Current number of idle connections available for reuse.
+# TYPE haproxy_server_idle_connections gauge
+haproxy_server_idle_connections{backend="foo",server="bar"} 3
+# HELP haproxy_server_idle_connections_limit Configured limit on the number of idle connections available for reuse.
+# TYPE haproxy_server_idle_connections_limit gauge
+haproxy_server_idle_connections_limit{backend="foo",server="bar"} 10
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_server_idle_connections", "haproxy_server_idle_connections_limit"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Counters keeps the counters about the scrapes of Exporters, e.g.
+// haproxy_exporter_scrapes_total, so that they continue when the Exporter of
+// a target is replaced by a new one, e.g. on a reload of the configuration.
+// The counters are those of the same target URI and constant labels.
+type Counters struct {
+	mutex    sync.Mutex
+	counters map[string]*scrapeCounters
+}
+
+// NewCounters returns empty Counters.
+func NewCounters() *Counters {
+	return &Counters{counters: map[string]*scrapeCounters{}}
+}
+
+// scrapeCounters are the cumulative metrics of the scrapes of an Exporter.
+type scrapeCounters struct {
+	totalScrapes     prometheus.Counter
+	csvParseFailures *prometheus.CounterVec
+	scrapeErrors     *prometheus.CounterVec
+	seriesLimited    *prometheus.CounterVec
+	scrapeDuration   prometheus.Histogram
+	scrapeBytes      prometheus.Counter
+	cacheHits        prometheus.Counter
+}
+
+// get returns the counters of the target uri with the labels of the metrics
+// of its scrapes, or new ones if c is nil.
+func (c *Counters) get(uri string, labels prometheus.Labels) *scrapeCounters {
+	if c == nil {
+		return newScrapeCounters(labels)
+	}
+	key := countersKey(uri, labels)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	sc, ok := c.counters[key]
+	if !ok {
+		sc = newScrapeCounters(labels)
+		c.counters[key] = sc
+	}
+	return sc
+}
+
+// countersKey returns a key of the target uri and the labels. Names and
+// values are quoted, to not run into each other.
+func countersKey(uri string, labels prometheus.Labels) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b := strconv.AppendQuote(nil, uri)
+	for _, name := range names {
+		b = strconv.AppendQuote(b, name)
+		b = strconv.AppendQuote(b, labels[name])
+	}
+	return string(b)
+}
+
+func newScrapeCounters(labels prometheus.Labels) *scrapeCounters {
+	csvParseFailures := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   namespace,
+		Name:        "exporter_csv_parse_failures_total",
+		Help:        "Number of errors while parsing CSV by reason: short_row, bad_number, bad_status or csv_error.",
+		ConstLabels: labels,
+	}, []string{"reason"})
+	// All reasons are exported, so that an increase from zero is noticed.
+	for _, reason := range parseFailureReasons {
+		csvParseFailures.WithLabelValues(reason)
+	}
+
+	return &scrapeCounters{
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "exporter_scrapes_total",
+			Help:        "Current total HAProxy scrapes.",
+			ConstLabels: labels,
+		}),
+		csvParseFailures: csvParseFailures,
+		scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "exporter_scrape_errors_total",
+			Help:        "Number of failed HAProxy scrapes by type of error: dial, auth, timeout, parse or other.",
+			ConstLabels: labels,
+		}, []string{"type"}),
+		seriesLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "exporter_series_limited_total",
+			Help:        "Number of times the server metrics of a backend were dropped, by the limit exceeded: max_servers_per_backend or max_servers.",
+			ConstLabels: labels,
+		}, []string{"limit"}),
+		scrapeDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "exporter_scrape_duration_seconds",
+			Help:        "Duration of the scrapes of HAProxy, fetching and parsing the stats.",
+			ConstLabels: labels,
+			Buckets:     []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}),
+		scrapeBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "exporter_scrape_bytes_total",
+			Help:        "Total number of bytes of the stats fetched from HAProxy.",
+			ConstLabels: labels,
+		}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "exporter_cache_hits_total",
+			Help:        "Number of scrapes whose stats were served from the scrape cache instead of HAProxy.",
+			ConstLabels: labels,
+		}),
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCounters(t *testing.T) {
+	h := newHaproxy([]byte("foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"))
+	defer h.Close()
+
+	counters := NewCounters()
+	newExporter := func(labels prometheus.Labels) *Exporter {
+		e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, ConstLabels: labels, Counters: counters}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.CollectAndCount(e)
+		return e
+	}
+
+	newExporter(nil)
+	if want, have := 2.0, testutil.ToFloat64(newExporter(nil).totalScrapes); want != have {
+		t.Errorf("want %v scrapes of the target, have %v", want, have)
+	}
+	// The scrapes of the target with other labels are counted apart.
+	if want, have := 1.0, testutil.ToFloat64(newExporter(prometheus.Labels{"env": "test"}).totalScrapes); want != have {
+		t.Errorf("want %v scrapes of the target with other labels, have %v", want, have)
+	}
+}
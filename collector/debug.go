@@ -0,0 +1,223 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+// selectExporter returns the Exporter of the target given by the target query
+// parameter of r, which may be left out if there is only one.
+func selectExporter(exporters []*Exporter, r *http.Request) (*Exporter, error) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		if len(exporters) != 1 {
+			return nil, fmt.Errorf("target parameter is required with %d targets", len(exporters))
+		}
+		return exporters[0], nil
+	}
+	for _, e := range exporters {
+		if e.URI == target {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown target %q", target)
+}
+
+// RawStatsHandler returns a handler which fetches the stats of the exporter's
+// HAProxy and streams them back unmodified. Like every other endpoint it is
+// protected by the authentication configured with --web.config.file.
+func RawStatsHandler(exporters func() []*Exporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e, err := selectExporter(exporters(), r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body, err := e.fetchStat.Fetch(r.Context())
+		if err != nil {
+			level.Error(e.logger).Log("msg", "Can't scrape HAProxy", "err", err)
+			http.Error(w, "Can't scrape HAProxy: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer body.Close()
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if _, err := io.Copy(w, body); err != nil {
+			level.Error(e.logger).Log("msg", "Error streaming HAProxy stats", "err", err)
+		}
+	})
+}
+
+// parsedRow describes how a single row of the HAProxy stats was turned into
+// metrics.
+type parsedRow struct {
+	Proxy         string        `json:"proxy"`
+	Server        string        `json:"server"`
+	Type          string        `json:"type,omitempty"`
+	SkippedReason string        `json:"skipped_reason,omitempty"`
+	Fields        []parsedField `json:"fields,omitempty"`
+}
+
+// parsedField describes how a single CSV field was turned into a metric value.
+// Value is only set if the field was exported.
+type parsedField struct {
+	Name          string   `json:"name,omitempty"`
+	Column        int      `json:"column"`
+	Raw           string   `json:"raw"`
+	Value         *float64 `json:"value,omitempty"`
+	SkippedReason string   `json:"skipped_reason,omitempty"`
+}
+
+// explainLastScrape reports what happened to every row and field of the
+// stats of the last successful scrape, and when it was. The time is zero if
+// there was none. Rows dropped while reading the stats, e.g. short ones, are
+// only counted by haproxy_exporter_csv_parse_failures_total.
+func (e *Exporter) explainLastScrape() ([]parsedRow, time.Time) {
+	// The slice of the rows is reused by a later scrape once it replaces
+	// them, so they are only read while holding lastMutex.
+	e.lastMutex.Lock()
+	defer e.lastMutex.Unlock()
+	// The limits are those the rows were exported with, as they only depend
+	// on the rows.
+	limits := e.serverLimits(e.lastRows)
+	rows := make([]parsedRow, 0, len(e.lastRows))
+	for _, row := range e.lastRows {
+		rows = append(rows, e.explainRow(row.Fields, e.lastColumns, limits))
+	}
+	return rows, e.lastScrape
+}
+
+// proxySkipReason is why the rows of a proxy which isn't in --haproxy.proxies
+// aren't exported.
+const proxySkipReason = "proxy is not in --haproxy.proxies"
+
+// explainRow reports what happened to the fields of the canonical row csvRow
+// of stats with the columns cols, with the server metrics of the backends of
+// limits dropped. It checks the rows with the functions of parseRow.
+func (e *Exporter) explainRow(csvRow []string, cols columns, limits map[string]serverLimit) parsedRow {
+	if len(csvRow) < minimumCsvFieldCount {
+		r := parsedRow{SkippedReason: fmt.Sprintf("row has %d fields, at least %d are required", len(csvRow), minimumCsvFieldCount)}
+		if len(csvRow) > svnameField {
+			r.Proxy, r.Server = csvRow[pxnameField], csvRow[svnameField]
+		}
+		return r
+	}
+
+	r := parsedRow{Proxy: csvRow[pxnameField], Server: csvRow[svnameField]}
+	if !e.proxyExported(r.Proxy) {
+		r.SkippedReason = proxySkipReason
+		return r
+	}
+
+	var metrics map[int]metricInfo
+	switch csvRow[typeField] {
+	case "0":
+		r.Type, metrics = "frontend", e.frontendMetrics
+	case "1":
+		r.Type, metrics = "backend", e.backendMetrics
+	case "2":
+		r.Type, metrics = "server", e.serverMetrics
+		if !checkEnabled(csvRow) {
+			metrics = e.uncheckedServerMetrics
+		}
+		if r.SkippedReason = e.serverSkipReason(csvRow); r.SkippedReason != "" {
+			return r
+		}
+	default:
+		r.SkippedReason = fmt.Sprintf("unknown type %q", csvRow[typeField])
+		return r
+	}
+	if e.scrapeLevel != nil && !e.scrapeLevel[r.Type] {
+		r.SkippedReason = fmt.Sprintf("%s rows are not in --haproxy.scrape-level", r.Type)
+		return r
+	}
+	if e.aggregateServers && r.Type == "server" {
+		r.SkippedReason = "servers are aggregated per backend by --haproxy.aggregate-servers"
+		return r
+	}
+	if l, ok := limits[r.Proxy]; ok && r.Type == "server" {
+		r.SkippedReason = fmt.Sprintf("the server metrics of the backend, of %d servers, are dropped by %s", l.servers, limitFlags[l.limit])
+		return r
+	}
+
+	for i, valueStr := range csvRow {
+		column := cols.position(i)
+		if column == -1 {
+			continue
+		}
+		f := parsedField{Column: column, Raw: valueStr}
+		if i < len(cols.names) {
+			f.Name = cols.names[i]
+		}
+
+		metric, ok := metrics[i]
+		switch {
+		case !ok && r.Type == "server" && e.serverMetrics[i].Desc != nil:
+			f.SkippedReason = "server has no health check, see --haproxy.exclude-unchecked-server-up"
+		case !ok && r.Type == "server" && serverMetrics[i].Desc != nil:
+			f.SkippedReason = "not selected by --haproxy.server-metric-fields"
+		case !ok:
+			f.SkippedReason = "no metric for this field"
+		case valueStr == "":
+			f.SkippedReason = "empty value"
+		default:
+			value, err := parseFieldValue(i, valueStr)
+			if err != nil {
+				f.SkippedReason = "can't parse value: " + err.Error()
+			} else {
+				value = metric.value(value)
+				f.Value = &value
+			}
+		}
+		r.Fields = append(r.Fields, f)
+	}
+	return r
+}
+
+// ParsedStatsHandler returns a handler responding with a JSON description of
+// how each row and field of the stats of the last successful scrape of the
+// exporter's HAProxy was parsed, including why fields did not become metrics.
+func ParsedStatsHandler(exporters func() []*Exporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e, err := selectExporter(exporters(), r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if e.streamStats {
+			http.Error(w, "The rows of the stats aren't kept with --haproxy.stream-stats", http.StatusNotFound)
+			return
+		}
+		rows, t := e.explainLastScrape()
+		if t.IsZero() {
+			http.Error(w, "No successful scrape of HAProxy yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rows); err != nil {
+			level.Error(e.logger).Log("msg", "Error encoding parsed stats", "err", err)
+		}
+	})
+}
@@ -0,0 +1,249 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRawStatsHandler(t *testing.T) {
+	const data = "foo,FRONTEND,0,0,0,0,,0,0,0,,0,,0,0,0,0,OPEN,,,,,,,,,1,2,0,,,,0,0,0,0,\n"
+	h := newHaproxy([]byte(data))
+	defer h.Close()
+
+	e, _ := NewExporter(h.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}, log.NewNopLogger())
+
+	rec := httptest.NewRecorder()
+	RawStatsHandler(func() []*Exporter { return []*Exporter{e} }).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/haproxy-stats", nil))
+
+	if want, have := http.StatusOK, rec.Code; want != have {
+		t.Fatalf("want status %d, have %d", want, have)
+	}
+	if want, have := data, rec.Body.String(); want != have {
+		t.Errorf("want body %q, have %q", want, have)
+	}
+}
+
+func TestRawStatsHandlerNotFound(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	defer s.Close()
+
+	e, _ := NewExporter(s.URL, ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}, log.NewNopLogger())
+
+	rec := httptest.NewRecorder()
+	RawStatsHandler(func() []*Exporter { return []*Exporter{e} }).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/haproxy-stats", nil))
+
+	if want, have := http.StatusBadGateway, rec.Code; want != have {
+		t.Errorf("want status %d, have %d", want, have)
+	}
+}
+
+func TestSelectExporter(t *testing.T) {
+	opts := ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}
+	a, _ := NewExporter("http://a/;csv", opts, log.NewNopLogger())
+	b, _ := NewExporter("http://b/;csv", opts, log.NewNopLogger())
+
+	tests := []struct {
+		exporters []*Exporter
+		query     string
+		want      *Exporter
+	}{
+		{exporters: []*Exporter{a}, query: "", want: a},
+		{exporters: []*Exporter{a, b}, query: "?target=" + url.QueryEscape("http://b/;csv"), want: b},
+		{exporters: []*Exporter{a, b}, query: "", want: nil},
+		{exporters: []*Exporter{a, b}, query: "?target=" + url.QueryEscape("http://c/;csv"), want: nil},
+	}
+
+	for _, tt := range tests {
+		have, err := selectExporter(tt.exporters, httptest.NewRequest("GET", "/debug/parsed"+tt.query, nil))
+		if tt.want != have {
+			t.Errorf("%q: want exporter %p, have %p", tt.query, tt.want, have)
+		}
+		if tt.want == nil && err == nil {
+			t.Errorf("%q: expected error", tt.query)
+		}
+	}
+}
+
+func TestExplainLastScrape(t *testing.T) {
+	const data = `foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,
+foo,foo-instance-0,0,0,x,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+foo,foo-instance-1,0,0,0,0,,0,0,0,,0,,0,0,0,0,MAINT,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+not,enough,fields
+`
+	h := newHaproxy([]byte(data))
+	defer h.Close()
+	e, _ := NewExporter(h.URL, ExporterOpts{SSLVerify: true, ServerMetrics: ServerMetrics{4: serverMetrics[4]}, ExcludedServerStates: "MAINT", Timeout: 5 * time.Second}, log.NewNopLogger())
+
+	if rows, last := e.explainLastScrape(); len(rows) != 0 || !last.IsZero() {
+		t.Fatalf("want no rows before the first scrape, have %d of %s", len(rows), last)
+	}
+	testutil.CollectAndCount(e)
+	// Once served, the stats change without affecting the explanation.
+	h.response = []byte("bar,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n")
+
+	rows, last := e.explainLastScrape()
+	if last.IsZero() {
+		t.Error("want the time of the last scrape")
+	}
+	// The short row is dropped while reading the stats.
+	if want, have := 3, len(rows); want != have {
+		t.Fatalf("want %d rows, have %d", want, have)
+	}
+
+	fe := rows[0]
+	if fe.Proxy != "foo" || fe.Type != "frontend" || fe.SkippedReason != "" {
+		t.Errorf("unexpected frontend row %+v", fe)
+	}
+	if f := fe.Fields[4]; f.Name != "scur" || f.Value == nil || *f.Value != 1 {
+		t.Errorf("unexpected scur field %+v", f)
+	}
+	if f := fe.Fields[2]; f.SkippedReason != "no metric for this field" {
+		t.Errorf("unexpected qcur field %+v", f)
+	}
+	if f := fe.Fields[6]; f.SkippedReason != "empty value" {
+		t.Errorf("unexpected slim field %+v", f)
+	}
+
+	srv := rows[1]
+	if f := srv.Fields[4]; f.Value != nil || !strings.HasPrefix(f.SkippedReason, "can't parse value") {
+		t.Errorf("unexpected scur field %+v", f)
+	}
+	if f := srv.Fields[5]; f.SkippedReason != "not selected by --haproxy.server-metric-fields" {
+		t.Errorf("unexpected smax field %+v", f)
+	}
+
+	if rows[2].SkippedReason == "" || rows[2].Fields != nil {
+		t.Errorf("expected excluded server row to be skipped, have %+v", rows[2])
+	}
+}
+
+func TestExplainLastScrapeFilters(t *testing.T) {
+	frontend := func(proxy string) string {
+		return proxy + ",FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"
+	}
+	server := func(proxy, name, status string) string {
+		return proxy + "," + name + ",0,0,0,0,,0,0,0,,0,,0,0,0,0," + status + ",1,1,0,0,0,5007,0,,1,8,1,,0,,2,\n"
+	}
+	h := newHaproxy([]byte(frontend("foo") +
+		server("foo", "srv-a", "UP") +
+		server("foo", "srv-b", "UP") +
+		server("foo", "canary-c", "UP") +
+		server("foo", "srv-d", "no check") +
+		frontend("bar") +
+		server("bar", "srv-e", "UP")))
+	defer h.Close()
+
+	tests := []struct {
+		name   string
+		opts   ExporterOpts
+		row    int
+		reason string
+		// field, if not 0, is a field of the row skipped for fieldReason.
+		field       int
+		fieldReason string
+	}{
+		{name: "proxies", opts: ExporterOpts{Proxies: "foo"}, row: 5, reason: "proxy is not in --haproxy.proxies"},
+		{name: "proxies of servers", opts: ExporterOpts{Proxies: "foo"}, row: 6, reason: "proxy is not in --haproxy.proxies"},
+		{name: "max servers per backend", opts: ExporterOpts{MaxServersPerBackend: 3}, row: 1, reason: "the server metrics of the backend, of 4 servers, are dropped by --haproxy.max-servers-per-backend"},
+		{name: "max servers", opts: ExporterOpts{MaxServers: 2}, row: 4, reason: "the server metrics of the backend, of 4 servers, are dropped by --haproxy.max-servers"},
+		{name: "backends within max servers", opts: ExporterOpts{MaxServers: 2}, row: 6},
+		{name: "server name include", opts: ExporterOpts{ServerNameInclude: "srv-.*"}, row: 3, reason: "server name is filtered out by --haproxy.server-name-include"},
+		{name: "server name exclude", opts: ExporterOpts{ServerNameExclude: "canary-.*"}, row: 3, reason: "server name is filtered out by --haproxy.server-name-exclude"},
+		{name: "unchecked server up", opts: ExporterOpts{ExcludeUncheckedServerUp: true}, row: 4, field: statusField, fieldReason: "server has no health check, see --haproxy.exclude-unchecked-server-up"},
+		{name: "checked server up", opts: ExporterOpts{ExcludeUncheckedServerUp: true}, row: 1, field: statusField},
+	}
+
+	for _, tt := range tests {
+		tt.opts.ServerMetrics, tt.opts.Timeout = serverMetrics, 5*time.Second
+		e, err := NewExporter(h.URL, tt.opts, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.CollectAndCount(e)
+
+		rows, _ := e.explainLastScrape()
+		if want, have := 7, len(rows); want != have {
+			t.Fatalf("%s: want %d rows, have %d", tt.name, want, have)
+		}
+		r := rows[tt.row]
+		if want, have := tt.reason, r.SkippedReason; want != have {
+			t.Errorf("%s: want row %s/%s skipped for %q, have %q", tt.name, r.Proxy, r.Server, want, have)
+		}
+		if tt.field == 0 {
+			continue
+		}
+		if len(r.Fields) <= tt.field {
+			t.Fatalf("%s: want field %d of row %s/%s, have %+v", tt.name, tt.field, r.Proxy, r.Server, r)
+		}
+		if want, have := tt.fieldReason, r.Fields[tt.field].SkippedReason; want != have {
+			t.Errorf("%s: want field %d of row %s/%s skipped for %q, have %q", tt.name, tt.field, r.Proxy, r.Server, want, have)
+		}
+	}
+}
+
+func TestParsedStatsHandler(t *testing.T) {
+	h := newHaproxy([]byte("# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,\n" +
+		"foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"))
+	defer h.Close()
+
+	for _, tt := range []struct {
+		name   string
+		opts   ExporterOpts
+		scrape bool
+		code   int
+	}{
+		{name: "before the first scrape", code: http.StatusServiceUnavailable},
+		{name: "after a scrape", scrape: true, code: http.StatusOK},
+		{name: "streamed stats", opts: ExporterOpts{StreamStats: true}, scrape: true, code: http.StatusNotFound},
+	} {
+		tt.opts.Timeout = 5 * time.Second
+		e, err := NewExporter(h.URL, tt.opts, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.scrape {
+			testutil.CollectAndCount(e)
+		}
+
+		rec := httptest.NewRecorder()
+		ParsedStatsHandler(func() []*Exporter { return []*Exporter{e} }).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/parsed", nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: want status %d, have %d: %s", tt.name, tt.code, rec.Code, rec.Body)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		if rec.Header().Get("Last-Modified") == "" {
+			t.Errorf("%s: want the time of the last scrape", tt.name)
+		}
+		var rows []parsedRow
+		if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(rows) != 1 || rows[0].Proxy != "foo" || rows[0].Fields[4].Name != "scur" {
+			t.Errorf("%s: unexpected rows %+v", tt.name, rows)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/prometheus/haproxy_exporter/parser"
+)
+
+// DialError is returned if HAProxy can't be connected to.
+type DialError struct {
+	Err error
+}
+
+func (e *DialError) Error() string { return "can't connect to HAProxy: " + e.Err.Error() }
+func (e *DialError) Unwrap() error { return e.Err }
+
+// AuthError is returned if HAProxy rejects the credentials of a scrape.
+type AuthError struct {
+	StatusCode int
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("HAProxy rejected the credentials: HTTP status %d", e.StatusCode)
+}
+
+// ParseError is returned if the output of HAProxy can't be parsed.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string { return "can't parse HAProxy stats: " + e.Err.Error() }
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// TimeoutError is returned if HAProxy doesn't respond within the timeout.
+type TimeoutError struct {
+	Err error
+}
+
+func (e *TimeoutError) Error() string { return "timeout scraping HAProxy: " + e.Err.Error() }
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// classifyError wraps network errors of a fetch in a TimeoutError or
+// DialError, if they are one. Other errors are returned unchanged.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &TimeoutError{Err: err}
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return &DialError{Err: err}
+	}
+	return err
+}
+
+// The reasons of CSV parse failures, as used for the reason label of
+// haproxy_exporter_csv_parse_failures_total.
+const (
+	parseFailureShortRow  = "short_row"
+	parseFailureBadNumber = "bad_number"
+	parseFailureBadStatus = "bad_status"
+	parseFailureCSVError  = "csv_error"
+)
+
+var parseFailureReasons = []string{parseFailureShortRow, parseFailureBadNumber, parseFailureBadStatus, parseFailureCSVError}
+
+// rowErrorReason returns the reason of a row skipped by the parser: either it
+// has too few fields or it isn't valid CSV.
+func rowErrorReason(err error) string {
+	if errors.Is(err, parser.ErrShortRow) {
+		return parseFailureShortRow
+	}
+	return parseFailureCSVError
+}
+
+// errorType returns the type of a scrape error, as used for the type label of
+// haproxy_exporter_scrape_errors_total.
+func errorType(err error) string {
+	var (
+		dialErr    *DialError
+		authErr    *AuthError
+		parseErr   *ParseError
+		timeoutErr *TimeoutError
+	)
+	switch {
+	case errors.As(err, &timeoutErr):
+		return "timeout"
+	case errors.As(err, &dialErr):
+		return "dial"
+	case errors.As(err, &authErr):
+		return "auth"
+	case errors.As(err, &parseErr):
+		return "parse"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestErrorType(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: classifyError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}), want: "dial"},
+		{err: classifyError(fmt.Errorf("fetching: %w", context.DeadlineExceeded)), want: "timeout"},
+		{err: &AuthError{StatusCode: 401}, want: "auth"},
+		{err: fmt.Errorf("error reading CSV: %w", &ParseError{Err: errors.New("bad quote")}), want: "parse"},
+		{err: classifyError(errors.New("HTTP status 500")), want: "other"},
+	}
+
+	for _, tt := range tests {
+		if have := errorType(tt.err); tt.want != have {
+			t.Errorf("%v: want error type %q, have %q", tt.err, tt.want, have)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/haproxy_exporter/collector"
+)
+
+const exampleStats = "foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"
+
+// statsFetcher returns a Fetcher of the given stats, standing in for a custom
+// transport.
+func statsFetcher(stats string) collector.Fetcher {
+	return collector.FetcherFunc(func(ctx context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(stats)), nil
+	})
+}
+
+func ExampleNewExporter() {
+	serverMetrics, err := collector.FilterServerMetrics(collector.DefaultServerMetricFields)
+	if err != nil {
+		panic(err)
+	}
+	// The stats are fetched by a custom Fetcher, e.g. over SSH, and the URI
+	// only identifies the target.
+	e, err := collector.NewExporter("ssh://haproxy.example.com", collector.ExporterOpts{
+		ServerMetrics: serverMetrics,
+		StatFetcher:   statsFetcher(exampleStats),
+		Timeout:       5 * time.Second,
+	}, collector.FromSlog(slog.New(slog.NewTextHandler(os.Stderr, nil))))
+	if err != nil {
+		panic(err)
+	}
+
+	// The Exporter is registered with a registry of its own, instead of the
+	// global one.
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e)
+	mfs, err := registry.Gather()
+	if err != nil {
+		panic(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == "haproxy_up" {
+			fmt.Println(mf.GetName(), mf.GetMetric()[0].GetGauge().GetValue())
+		}
+	}
+	// Output: haproxy_up 1
+}
+
+func TestExportersSharingRegistry(t *testing.T) {
+	newExporter := func(labels prometheus.Labels) *collector.Exporter {
+		e, err := collector.NewExporter("ssh://haproxy.example.com", collector.ExporterOpts{StatFetcher: statsFetcher(exampleStats), Timeout: 5 * time.Second, ConstLabels: labels}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(newExporter(prometheus.Labels{"instance": "a"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(newExporter(prometheus.Labels{"instance": "b"})); err != nil {
+		t.Fatalf("want Exporters with different labels to share a registry: %v", err)
+	}
+	if err := prometheus.WrapRegistererWith(prometheus.Labels{"instance": "c"}, registry).Register(newExporter(nil)); err != nil {
+		t.Fatalf("want wrapped Exporter to share a registry: %v", err)
+	}
+	if err := registry.Register(newExporter(prometheus.Labels{"instance": "a"})); err == nil {
+		t.Errorf("want error registering an Exporter with the same labels twice")
+	}
+
+	if want, have := 3, testutil.CollectAndCount(registry, "haproxy_up"); want != have {
+		t.Errorf("want %d haproxy_up series, have %d", want, have)
+	}
+}
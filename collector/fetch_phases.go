@@ -0,0 +1,119 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// The phases of fetching the stats, the values of the label phase of
+// haproxy_exporter_fetch_phase_seconds.
+const (
+	phaseDial         = "dial"
+	phaseTLSHandshake = "tls_handshake"
+	phaseFirstByte    = "first_byte"
+	phaseBodyRead     = "body_read"
+)
+
+var fetchPhaseNames = []string{phaseDial, phaseTLSHandshake, phaseFirstByte, phaseBodyRead}
+
+// fetchPhases are the durations of the phases of a fetch, recorded by the
+// fetchers of this package if the context of the fetch carries them. Phases
+// which didn't happen, e.g. the dial of a reused connection, are zero.
+type fetchPhases struct {
+	// The trace of an HTTP request may still record the TLS handshake of its
+	// connection after the fetch timed out.
+	mutex        sync.Mutex
+	dial         time.Duration // Connecting, without the TLS handshake.
+	tlsHandshake time.Duration
+	firstByte    time.Duration // From sending the request to the first byte of the response.
+	bodyRead     time.Duration // Spent reading the rest of the response.
+}
+
+// byName returns the durations by the names of the phases.
+func (p *fetchPhases) byName() map[string]time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return map[string]time.Duration{
+		phaseDial:         p.dial,
+		phaseTLSHandshake: p.tlsHandshake,
+		phaseFirstByte:    p.firstByte,
+		phaseBodyRead:     p.bodyRead,
+	}
+}
+
+type fetchPhasesKey struct{}
+
+// withFetchPhases returns a context recording the phases of a fetch in p.
+func withFetchPhases(ctx context.Context, p *fetchPhases) context.Context {
+	return context.WithValue(ctx, fetchPhasesKey{}, p)
+}
+
+// fetchPhasesFrom returns the fetchPhases of ctx, or nil if it carries none.
+func fetchPhasesFrom(ctx context.Context) *fetchPhases {
+	p, _ := ctx.Value(fetchPhasesKey{}).(*fetchPhases)
+	return p
+}
+
+// traceHTTP returns ctx with a trace of an HTTP request recording its
+// phases in p, up to the first byte of the response.
+func traceHTTP(ctx context.Context, p *fetchPhases) context.Context {
+	var getConn, tlsStart, wroteRequest time.Time
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) { getConn = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				p.mutex.Lock()
+				p.dial = time.Since(getConn) - p.tlsHandshake
+				p.mutex.Unlock()
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.mutex.Lock()
+			p.tlsHandshake = time.Since(tlsStart)
+			p.mutex.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() },
+		GotFirstResponseByte: func() { p.firstByte = time.Since(wroteRequest) },
+	})
+}
+
+// phaseReader records the time spent reading the body of a response in p.
+// If sent is not zero, the time from it to the first byte read is recorded as
+// the first byte phase instead.
+type phaseReader struct {
+	io.ReadCloser
+	p    *fetchPhases
+	sent time.Time
+}
+
+func (r *phaseReader) Read(b []byte) (int, error) {
+	start := time.Now()
+	n, err := r.ReadCloser.Read(b)
+	if !r.sent.IsZero() {
+		if n > 0 {
+			r.p.firstByte = time.Since(r.sent)
+			r.sent = time.Time{}
+		}
+		return n, err
+	}
+	r.p.bodyRead += time.Since(start)
+	return n, err
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFetchPhases(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	h := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Write([]byte(newRow("fe", "FRONTEND", "0", map[int]string{statusField: "OPEN"})))
+	}))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.CollectAndCount(e)
+
+	phase := func(name string) time.Duration {
+		return time.Duration(testutil.ToFloat64(e.fetchPhases.WithLabelValues(name)) * float64(time.Second))
+	}
+	if phase(phaseDial) <= 0 {
+		t.Errorf("want a dial duration, have %s", phase(phaseDial))
+	}
+	if phase(phaseTLSHandshake) <= 0 {
+		t.Errorf("want a TLS handshake duration, have %s", phase(phaseTLSHandshake))
+	}
+	if have := phase(phaseFirstByte); have < delay {
+		t.Errorf("want a first byte duration of at least %s, have %s", delay, have)
+	}
+
+	// The connection is reused by the next scrape.
+	testutil.CollectAndCount(e)
+	if phase(phaseDial) != 0 || phase(phaseTLSHandshake) != 0 {
+		t.Errorf("want no dial and TLS handshake for a reused connection, have %s and %s", phase(phaseDial), phase(phaseTLSHandshake))
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/haproxy_exporter/parser"
+)
+
+// Exit codes of the health command.
+const (
+	healthOK          = 0
+	healthUnreachable = 1
+	healthParseError  = 2
+)
+
+// checkHealth fetches the stats of e's HAProxy once and returns the exit code
+// of the health command: healthUnreachable if they can't be fetched or read
+// completely, e.g. if the body is cut off, healthParseError if they were read
+// but can't be parsed and healthOK otherwise.
+func checkHealth(e *Exporter) int {
+	ctx, cancel := e.context()
+	defer cancel()
+
+	if e.fetchInfo != nil {
+		info, err := e.fetchInfo.Fetch(ctx)
+		if err != nil {
+			level.Error(e.logger).Log("msg", "Can't scrape HAProxy", "err", err, "error_type", errorType(err))
+			return healthUnreachable
+		}
+		info.Close()
+	}
+
+	body, err := e.fetchStat.Fetch(ctx)
+	if err != nil {
+		level.Error(e.logger).Log("msg", "Can't scrape HAProxy", "err", err, "error_type", errorType(err))
+		return healthUnreachable
+	}
+	defer body.Close()
+
+	if err := checkStats(body); err != nil {
+		level.Error(e.logger).Log("msg", "Can't parse HAProxy stats", "err", err, "error_type", errorType(err))
+		if errorType(err) == "parse" {
+			return healthParseError
+		}
+		return healthUnreachable
+	}
+	return healthOK
+}
+
+// checkStats returns a ParseError if the stats read from r contain no rows, or
+// a row which would be rejected by a scrape.
+func checkStats(r io.Reader) error {
+	stats, err := parser.Parse(r)
+	var skipped parser.SkippedRowsError
+	if errors.As(err, &skipped) {
+		return &ParseError{Err: err}
+	}
+	if err != nil {
+		return classifyError(err)
+	}
+	if _, err := newColumns(stats.Header); err != nil {
+		return &ParseError{Err: err}
+	}
+	if len(stats.Rows) == 0 {
+		return &ParseError{Err: fmt.Errorf("no stats received")}
+	}
+	return nil
+}
+
+// CheckHealth checks every exporter and returns the highest exit code.
+func CheckHealth(exporters []*Exporter) int {
+	code := healthOK
+	for _, e := range exporters {
+		if c := checkHealth(e); c > code {
+			code = c
+		}
+	}
+	return code
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestCheckHealth(t *testing.T) {
+	ok := newHaproxy([]byte("foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"))
+	defer ok.Close()
+	short := newHaproxy([]byte("foo,FRONTEND,1\n"))
+	defer short.Close()
+	empty := newHaproxy([]byte("# pxname,svname\n"))
+	defer empty.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	defer down.Close()
+	// The body ends before the announced length, as if the connection broke.
+	truncated := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.Write([]byte("foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\nfoo,BACK"))
+	}))
+	defer truncated.Close()
+
+	tests := []struct {
+		uri  string
+		want int
+	}{
+		{uri: ok.URL, want: healthOK},
+		{uri: down.URL, want: healthUnreachable},
+		{uri: truncated.URL, want: healthUnreachable},
+		{uri: short.URL, want: healthParseError},
+		{uri: empty.URL, want: healthParseError},
+	}
+
+	for _, tt := range tests {
+		e, _ := NewExporter(tt.uri, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+		if have := checkHealth(e); tt.want != have {
+			t.Errorf("%s: want exit code %d, have %d", tt.uri, tt.want, have)
+		}
+	}
+}
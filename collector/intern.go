@@ -0,0 +1,67 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxInternedPairs bounds the label pairs an Exporter interns, enough for the
+// names of about 100000 servers.
+const maxInternedPairs = 1 << 17
+
+// internedPairs interns the label pairs of the label values of the rows of
+// the stats, e.g. the names of proxies and servers, across scrapes. Scrapes
+// of the same stats reuse the same pairs and strings instead of building them
+// anew, and the pairs don't keep the lines of the stats they were read from.
+// Beyond max pairs, the pairs used least recently are dropped.
+type internedPairs struct {
+	mutex sync.Mutex
+	max   int
+	pairs map[[2]string]*list.Element // By name and value.
+	lru   *list.List                  // Of *dto.LabelPair, used last first.
+}
+
+func newInternedPairs(max int) *internedPairs {
+	return &internedPairs{max: max, pairs: map[[2]string]*list.Element{}, lru: list.New()}
+}
+
+// pair returns the interned label pair of name and value. Without interned
+// pairs, i being nil, it returns a new pair.
+func (i *internedPairs) pair(name, value string) *dto.LabelPair {
+	if i == nil {
+		return newLabelPair(name, value)
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	key := [2]string{name, value}
+	if e, ok := i.pairs[key]; ok {
+		i.lru.MoveToFront(e)
+		return e.Value.(*dto.LabelPair)
+	}
+	p := newLabelPair(name, strings.Clone(value))
+	key[1] = p.GetValue()
+	i.pairs[key] = i.lru.PushFront(p)
+	if i.lru.Len() > i.max {
+		oldest := i.lru.Back()
+		i.lru.Remove(oldest)
+		p := oldest.Value.(*dto.LabelPair)
+		delete(i.pairs, [2]string{p.GetName(), p.GetValue()})
+	}
+	return p
+}
@@ -0,0 +1,237 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxExtraPairs bounds the label pairs of the labels following those of the
+// rows, e.g. state or mode, kept across rows.
+const maxExtraPairs = 1024
+
+// labelLayout is the order of the label pairs of the metrics of a descriptor,
+// sorted by name as the registry expects them.
+type labelLayout struct {
+	names      []string         // The variable labels.
+	constPairs []*dto.LabelPair // Sorted by name.
+	// order gives the pair at every position: variable label i if i >= 0,
+	// constant pair -i-1 otherwise.
+	order []int
+}
+
+func newLabelLayout(variableLabels []string, constLabels prometheus.Labels) *labelLayout {
+	type entry struct {
+		name string
+		i    int
+	}
+	l := &labelLayout{names: variableLabels}
+	entries := make([]entry, 0, len(variableLabels)+len(constLabels))
+	for i, name := range variableLabels {
+		entries = append(entries, entry{name, i})
+	}
+	constNames := make([]string, 0, len(constLabels))
+	for name := range constLabels {
+		constNames = append(constNames, name)
+	}
+	sort.Strings(constNames)
+	for i, name := range constNames {
+		l.constPairs = append(l.constPairs, newLabelPair(name, constLabels[name]))
+		entries = append(entries, entry{name, -i - 1})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	for _, e := range entries {
+		l.order = append(l.order, e.i)
+	}
+	return l
+}
+
+func newLabelPair(name, value string) *dto.LabelPair {
+	return &dto.LabelPair{Name: &name, Value: &value}
+}
+
+// equal returns whether the metrics of l and o have the same labels.
+func (l *labelLayout) equal(o *labelLayout) bool {
+	if l == o {
+		return true
+	}
+	if len(l.names) != len(o.names) || len(l.constPairs) != len(o.constPairs) {
+		return false
+	}
+	for i, name := range l.names {
+		if o.names[i] != name {
+			return false
+		}
+	}
+	for i, p := range l.constPairs {
+		if o.constPairs[i].GetName() != p.GetName() || o.constPairs[i].GetValue() != p.GetValue() {
+			return false
+		}
+	}
+	return true
+}
+
+// rowLabelPairs builds the label pairs of the metrics of a row of the stats.
+// The pairs of the label values of the row are built once for all its
+// metrics, and so are the label pairs of the metrics with the same labels,
+// instead of by prometheus.MustNewConstMetric for every metric. The registry
+// doesn't modify the label pairs of metrics.
+type rowLabelPairs struct {
+	buf      []string // Holds the values of canonical rows, see resetRow.
+	values   []string
+	valid    bool             // Whether the values are valid UTF-8.
+	variable []*dto.LabelPair // Of values, built as needed.
+	interned *internedPairs   // The variable pairs are taken from, if not nil.
+	// pairs are the label pairs built last without further values, for
+	// layout.
+	layout *labelLayout
+	pairs  []*dto.LabelPair
+	// extra are the pairs of the further values of the metrics, by name and
+	// value, kept across rows.
+	extra map[[2]string]*dto.LabelPair
+}
+
+// reset starts the label pairs of a row with the given label values, which
+// are used until the next reset. The pairs of the values are not interned.
+func (r *rowLabelPairs) reset(values []string) {
+	r.interned = nil
+	r.resetValues(values)
+}
+
+func (r *rowLabelPairs) resetValues(values []string) {
+	r.values, r.valid = values, true
+	for _, v := range values {
+		if !utf8.ValidString(v) {
+			r.valid = false
+		}
+	}
+	// The pairs built so far belong to metrics, only the slice is reused.
+	r.variable = append(r.variable[:0], make([]*dto.LabelPair, len(values))...)
+	r.layout, r.pairs = nil, nil
+}
+
+// resetRow starts the label pairs of a canonical row of a frontend or
+// backend, or of a server, with the label values given by l, whose pairs are
+// interned by l.
+func (r *rowLabelPairs) resetRow(l rowLabels, csvRow []string, server bool) *rowLabelPairs {
+	r.buf = l.appendValues(r.buf[:0], csvRow, server)
+	r.interned = l.interned
+	r.resetValues(r.buf)
+	return r
+}
+
+// metric returns the metric m of the row with the given value, and with the
+// further label values extra following those of the row.
+func (r *rowLabelPairs) metric(m metricInfo, value float64, extra ...string) prometheus.Metric {
+	pairs, ok := r.labelPairs(m.layout, extra)
+	if !ok {
+		// MustNewConstMetric reports the invalid label values.
+		values := append(r.values[:len(r.values):len(r.values)], extra...)
+		return prometheus.MustNewConstMetric(m.Desc, m.Type, value, values...)
+	}
+	return &rowMetric{desc: m.Desc, typ: m.Type, value: value, labels: pairs}
+}
+
+// labelPairs returns the label pairs of the metrics of layout, or false if
+// the label values are invalid for it.
+func (r *rowLabelPairs) labelPairs(layout *labelLayout, extra []string) ([]*dto.LabelPair, bool) {
+	if len(extra) == 0 && r.layout != nil && r.layout.equal(layout) {
+		return r.pairs, true
+	}
+	if !r.valid || len(layout.names) != len(r.values)+len(extra) {
+		return nil, false
+	}
+	// The capacity is the length, so that appending to the pairs of a
+	// metric, e.g. by a wrapping Registerer, copies them.
+	pairs := make([]*dto.LabelPair, len(layout.order))
+	for i, j := range layout.order {
+		switch {
+		case j < 0:
+			pairs[i] = layout.constPairs[-j-1]
+		case j < len(r.values):
+			p := r.variable[j]
+			if p == nil || p.GetName() != layout.names[j] {
+				p = r.interned.pair(layout.names[j], r.values[j])
+				r.variable[j] = p
+			}
+			pairs[i] = p
+		default:
+			p, ok := r.extraPair(layout.names[j], extra[j-len(r.values)])
+			if !ok {
+				return nil, false
+			}
+			pairs[i] = p
+		}
+	}
+	if len(extra) == 0 {
+		r.layout, r.pairs = layout, pairs
+	}
+	return pairs, true
+}
+
+// extraPair returns the pair of a further label value, or false if it is
+// invalid.
+func (r *rowLabelPairs) extraPair(name, value string) (*dto.LabelPair, bool) {
+	key := [2]string{name, value}
+	if p, ok := r.extra[key]; ok {
+		return p, true
+	}
+	if !utf8.ValidString(value) {
+		return nil, false
+	}
+	p := newLabelPair(name, value)
+	if r.extra == nil {
+		r.extra = map[[2]string]*dto.LabelPair{}
+	}
+	if len(r.extra) < maxExtraPairs {
+		r.extra[key] = p
+	}
+	return p, true
+}
+
+// rowMetric is a constant metric of a row of the stats, with label pairs
+// shared with other metrics.
+type rowMetric struct {
+	desc   *prometheus.Desc
+	typ    prometheus.ValueType
+	value  float64
+	labels []*dto.LabelPair
+}
+
+// Desc implements prometheus.Metric.
+func (m *rowMetric) Desc() *prometheus.Desc {
+	return m.desc
+}
+
+// Write implements prometheus.Metric.
+func (m *rowMetric) Write(out *dto.Metric) error {
+	out.Label = m.labels
+	value := m.value
+	switch m.typ {
+	case prometheus.CounterValue:
+		out.Counter = &dto.Counter{Value: &value}
+	case prometheus.GaugeValue:
+		out.Gauge = &dto.Gauge{Value: &value}
+	case prometheus.UntypedValue:
+		out.Untyped = &dto.Untyped{Value: &value}
+	default:
+		return fmt.Errorf("encountered unknown type %v", m.typ)
+	}
+	return nil
+}
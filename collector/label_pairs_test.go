@@ -0,0 +1,102 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestRowLabelPairs(t *testing.T) {
+	constLabels := prometheus.Labels{"cluster": "eu", "zone": "b"}
+	labels := []string{"backend", "server", "az"}
+	gauge := newMetricInfo("haproxy_server_current_sessions", "Current number of active sessions.", prometheus.GaugeValue, labels, constLabels)
+	counter := newMetricInfo("haproxy_server_bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue, labels, constLabels)
+	status := newMetricInfo("haproxy_server_status", "Current status of the server.", prometheus.GaugeValue, append(labels, "state"), constLabels)
+
+	var r rowLabelPairs
+	for _, values := range [][]string{{"be", "web1", "a"}, {"be", "web2", "c"}} {
+		r.reset(values)
+		metrics := []struct {
+			metric prometheus.Metric
+			want   prometheus.Metric
+		}{
+			{r.metric(gauge, 3), prometheus.MustNewConstMetric(gauge.Desc, gauge.Type, 3, values...)},
+			{r.metric(counter, 7), prometheus.MustNewConstMetric(counter.Desc, counter.Type, 7, values...)},
+			{r.metric(status, 1, "UP"), prometheus.MustNewConstMetric(status.Desc, status.Type, 1, append(values, "UP")...)},
+			{r.metric(status, 0, "DOWN"), prometheus.MustNewConstMetric(status.Desc, status.Type, 0, append(values, "DOWN")...)},
+		}
+		var written []*dto.Metric
+		for _, m := range metrics {
+			var have, want dto.Metric
+			if err := m.metric.Write(&have); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.want.Write(&want); err != nil {
+				t.Fatal(err)
+			}
+			if have.String() != want.String() {
+				t.Errorf("want metric %s, have %s", want.String(), have.String())
+			}
+			written = append(written, &have)
+		}
+		if &written[0].Label[0] != &written[1].Label[0] {
+			t.Error("want the metrics with the same labels to share their label pairs")
+		}
+		if written[2].Label[0] != written[0].Label[0] {
+			t.Error("want the metrics of a row to share the pairs of its label values")
+		}
+	}
+
+	// Invalid label values are reported as by MustNewConstMetric.
+	r.reset([]string{"be", "web\xff", "a"})
+	defer func() {
+		if recover() == nil {
+			t.Error("want a panic for an invalid label value")
+		}
+	}()
+	r.metric(gauge, 1)
+}
+
+func TestInternedPairs(t *testing.T) {
+	i := newInternedPairs(2)
+	line := "be,web1"
+	web1 := i.pair("server", line[3:])
+	if i.pair("server", "web1") != web1 {
+		t.Error("want the same pair for the same name and value")
+	}
+	if i.pair("backend", "web1") == web1 {
+		t.Error("want another pair for another name")
+	}
+	// web1 was used least recently, and is dropped.
+	i.pair("server", "web2")
+	if i.pair("server", "web1") == web1 {
+		t.Error("want the pair used least recently to be dropped")
+	}
+	if len(i.pairs) != 2 || i.lru.Len() != 2 {
+		t.Errorf("want 2 interned pairs, have %d", len(i.pairs))
+	}
+
+	var r rowLabelPairs
+	gauge := newMetricInfo("haproxy_server_current_sessions", "Current number of active sessions.", prometheus.GaugeValue, []string{"backend", "server"}, nil)
+	var m dto.Metric
+	if err := r.resetRow(rowLabels{interned: i}, []string{"be", "web2"}, true).metric(gauge, 1).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Label[1] != i.pair("server", "web2") {
+		t.Error("want the label pairs of the rows to be interned")
+	}
+}
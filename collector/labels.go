@@ -0,0 +1,278 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// LabelRuleConfig extracts labels from the names of proxies or servers, e.g.
+// service, env and region from payments-prod-eu_be. Every named capture group
+// of the anchored regular expression Regex is a label.
+type LabelRuleConfig struct {
+	// Source is the name the labels are extracted from: proxy, the default,
+	// or server.
+	Source string `yaml:"source"`
+	Regex  string `yaml:"regex"`
+}
+
+// labelRule is a compiled LabelRuleConfig.
+type labelRule struct {
+	server bool
+	re     *regexp.Regexp
+}
+
+// LabelRules extract labels from the names of proxies and servers. Labels
+// extracted from proxy names are added to the metrics of frontends, backends
+// and servers, those extracted from server names only to the server metrics.
+// Where no rule matches, the labels are empty.
+type LabelRules struct {
+	rules        []labelRule
+	proxyLabels  []string
+	serverLabels []string
+}
+
+// rowLabelNames are the label names the metrics of the rows of the stats have
+// already.
+var rowLabelNames = map[string]bool{
+	"frontend": true,
+	"backend":  true,
+	"server":   true,
+	"state":    true,
+	"result":   true,
+	"mode":     true,
+	"algo":     true,
+	"code":     true,
+	"process":  true,
+	// The IDs of rowLabels.
+	"proxy_id":   true,
+	"server_id":  true,
+	"process_id": true,
+}
+
+// ReservedLabelNames returns the label names used by the metrics of an
+// Exporter, which labels added to them can't have. The map must not be
+// modified.
+func ReservedLabelNames() map[string]bool {
+	return reservedLabelNames()
+}
+
+// reservedLabelNames returns the names of ReservedLabelNames. They are taken
+// from the descriptors of an Exporter with all options adding labels enabled,
+// along with the labels of the rows, e.g. process, which only some rows have,
+// and le of the buckets of histograms.
+var reservedLabelNames = sync.OnceValue(func() map[string]bool {
+	res := map[string]bool{model.BucketLabel: true}
+	for name := range rowLabelNames {
+		res[name] = true
+	}
+	opts := ExporterOpts{
+		ServerMetrics:     serverMetrics,
+		RuntimeCollectors: strings.ReplaceAll(RuntimeCollectorNames(), " ", ""),
+		IDLabels:          true,
+		MultiProcess:      MultiProcessLabel,
+		NamingScheme:      NamingBoth,
+		Timeout:           time.Second,
+	}
+	for _, aggregate := range []bool{false, true} {
+		opts.AggregateServers = aggregate
+		e, err := NewExporter("unix:/run/haproxy.sock", opts, log.NewNopLogger())
+		if err != nil {
+			panic(fmt.Sprintf("can't create the Exporter describing the metrics: %v", err))
+		}
+		ch := make(chan *prometheus.Desc)
+		go func() {
+			e.Describe(ch)
+			close(ch)
+		}()
+		for d := range ch {
+			for _, name := range descLabelNames(d) {
+				res[name] = true
+			}
+		}
+	}
+	return res
+})
+
+// descLabelNames returns the names of the variable labels of d, which it
+// only tells by its string.
+func descLabelNames(d *prometheus.Desc) []string {
+	s := d.String()
+	i := strings.LastIndex(s, "variableLabels: [")
+	if i < 0 {
+		return nil
+	}
+	return strings.Fields(strings.TrimSuffix(s[i+len("variableLabels: ["):], "]}"))
+}
+
+// CompileLabelRules checks and compiles the given rules.
+func CompileLabelRules(cfgs []LabelRuleConfig) (LabelRules, error) {
+	var (
+		res     LabelRules
+		sources = map[string]string{} // By label name.
+	)
+	for i, c := range cfgs {
+		var r labelRule
+		switch c.Source {
+		case "", "proxy":
+		case "server":
+			r.server = true
+		default:
+			return LabelRules{}, fmt.Errorf("rule %d: invalid source %q, must be proxy or server", i, c.Source)
+		}
+		re, err := regexp.Compile("^(?:" + c.Regex + ")$")
+		if err != nil {
+			return LabelRules{}, fmt.Errorf("rule %d: %w", i, err)
+		}
+		r.re = re
+
+		named := false
+		for _, name := range re.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			named = true
+			if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+				return LabelRules{}, fmt.Errorf("rule %d: invalid label name %q", i, name)
+			}
+			if rowLabelNames[name] {
+				return LabelRules{}, fmt.Errorf("rule %d: label name %q is used by the exported metrics", i, name)
+			}
+			source := "proxy"
+			if r.server {
+				source = "server"
+			}
+			if other, ok := sources[name]; ok {
+				if other != source {
+					return LabelRules{}, fmt.Errorf("rule %d: label %q is extracted from both proxy and server names", i, name)
+				}
+				continue
+			}
+			sources[name] = source
+			if r.server {
+				res.serverLabels = append(res.serverLabels, name)
+			} else {
+				res.proxyLabels = append(res.proxyLabels, name)
+			}
+		}
+		if !named {
+			return LabelRules{}, fmt.Errorf("rule %d: regex has no named capture group", i)
+		}
+		res.rules = append(res.rules, r)
+	}
+	return res, nil
+}
+
+// check returns an error if a label of l is a const label, e.g. of a target.
+func (l LabelRules) check(constLabels map[string]string) error {
+	for _, name := range append(append([]string(nil), l.proxyLabels...), l.serverLabels...) {
+		if _, ok := constLabels[name]; ok {
+			return fmt.Errorf("label %q of the label rules is a constant label as well", name)
+		}
+	}
+	return nil
+}
+
+// extract returns the values of the labels extracted from the name of a
+// server, or of a proxy if not server. The first matching rule giving a
+// label sets it.
+func (l LabelRules) extract(server bool, name string) []string {
+	labels := l.proxyLabels
+	if server {
+		labels = l.serverLabels
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	values := make([]string, len(labels))
+	set := make([]bool, len(labels))
+	for _, r := range l.rules {
+		if r.server != server {
+			continue
+		}
+		m := r.re.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		for i, group := range r.re.SubexpNames() {
+			if group == "" {
+				continue
+			}
+			for j, label := range labels {
+				if label == group && !set[j] {
+					values[j], set[j] = m[i], true
+				}
+			}
+		}
+	}
+	return values
+}
+
+// rowLabels are the variable labels of the metrics of the rows of the stats
+// following the proxy and server names: those extracted by the label rules
+// and, if ids, the numeric IDs HAProxy gives the proxy, server and process.
+// The label pairs of the values of all labels of the rows are interned in
+// interned, if not nil.
+type rowLabels struct {
+	rules    LabelRules
+	ids      bool
+	interned *internedPairs
+}
+
+// names returns the names of the labels following the proxy name, and the
+// server name for servers.
+func (l rowLabels) names(server bool) []string {
+	res := append([]string(nil), l.rules.proxyLabels...)
+	if server {
+		res = append(res, l.rules.serverLabels...)
+	}
+	if l.ids {
+		res = append(res, "proxy_id")
+		if server {
+			res = append(res, "server_id")
+		}
+		res = append(res, "process_id")
+	}
+	return res
+}
+
+// appendValues appends the label values of the metrics of a canonical row of
+// a frontend or backend, or of a server, to dst.
+func (l rowLabels) appendValues(dst []string, csvRow []string, server bool) []string {
+	pxname, svname := csvRow[pxnameField], csvRow[svnameField]
+	res := append(dst, pxname)
+	if server {
+		res = append(res, svname)
+	}
+	res = append(res, l.rules.extract(false, pxname)...)
+	if server {
+		res = append(res, l.rules.extract(true, svname)...)
+	}
+	if l.ids {
+		res = append(res, csvRow[iidField])
+		if server {
+			res = append(res, csvRow[sidField])
+		}
+		res = append(res, csvRow[pidField])
+	}
+	return res
+}
@@ -0,0 +1,141 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLabelRules(t *testing.T) {
+	rules, err := CompileLabelRules([]LabelRuleConfig{
+		{Regex: `(?P<service>[a-z]+)-(?P<env>[a-z]+)-(?P<region>[a-z]+)_(?:be|fe)`},
+		{Regex: `(?P<service>[a-z]+)_(?:be|fe)`},
+		{Source: "server", Regex: `(?P<zone>[a-z]+[0-9])-.*`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := newRow("payments-prod-eu_fe", "FRONTEND", "0", map[int]string{4: "1"}) +
+		newRow("payments-prod-eu_be", "BACKEND", "1", map[int]string{4: "2"}) +
+		newRow("payments-prod-eu_be", "az1-web", "2", map[int]string{4: "3", statusField: "UP"}) +
+		newRow("stats_fe", "FRONTEND", "0", map[int]string{4: "4"})
+	h := newHaproxy([]byte(rows))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, LabelRules: rules}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_backend_current_sessions Current number of active sessions.
+# TYPE haproxy_backend_current_sessions gauge
+haproxy_backend_current_sessions{backend="payments-prod-eu_be",env="prod",region="eu",service="payments"} 2
+# HELP haproxy_frontend_current_sessions Current number of active sessions.
+# TYPE haproxy_frontend_current_sessions gauge
+haproxy_frontend_current_sessions{env="",frontend="stats_fe",region="",service="stats"} 4
+haproxy_frontend_current_sessions{env="prod",frontend="payments-prod-eu_fe",region="eu",service="payments"} 1
+# HELP haproxy_server_current_sessions Current number of active sessions.
+# TYPE haproxy_server_current_sessions gauge
+haproxy_server_current_sessions{backend="payments-prod-eu_be",env="prod",region="eu",server="az1-web",service="payments",zone="az1"} 3
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_frontend_current_sessions", "haproxy_backend_current_sessions", "haproxy_server_current_sessions"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewExporter(h.URL, ExporterOpts{LabelRules: rules, ConstLabels: prometheus.Labels{"env": "prod"}}, log.NewNopLogger()); err == nil {
+		t.Error("expected an error for a label of the rules which is a const label")
+	}
+}
+
+func TestCompileLabelRulesInvalid(t *testing.T) {
+	tests := []struct {
+		rules []LabelRuleConfig
+		err   string
+	}{
+		{rules: []LabelRuleConfig{{Source: "frontend", Regex: "(?P<a>.*)"}}, err: `invalid source "frontend"`},
+		{rules: []LabelRuleConfig{{Regex: "("}}, err: "missing closing )"},
+		{rules: []LabelRuleConfig{{Regex: "(.*)_be"}}, err: "no named capture group"},
+		{rules: []LabelRuleConfig{{Regex: "(?P<__a>.*)"}}, err: `invalid label name "__a"`},
+		{rules: []LabelRuleConfig{{Regex: "(?P<backend>.*)"}}, err: `label name "backend" is used`},
+		{rules: []LabelRuleConfig{{Regex: "(?P<a>.*)"}, {Source: "server", Regex: "(?P<a>.*)"}}, err: "both proxy and server names"},
+	}
+
+	for _, tt := range tests {
+		_, err := CompileLabelRules(tt.rules)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: want error containing %q, have %v", tt.rules, tt.err, err)
+		}
+	}
+}
+
+func TestIDLabels(t *testing.T) {
+	rows := newRow("fe", "FRONTEND", "0", map[int]string{4: "1", pidField: "1", iidField: "2", sidField: "0"}) +
+		newRow("be", "BACKEND", "1", map[int]string{4: "2", pidField: "1", iidField: "3", sidField: "0"}) +
+		newRow("be", "srv", "2", map[int]string{4: "3", statusField: "UP", pidField: "1", iidField: "3", sidField: "1"})
+	h := newHaproxy([]byte(rows))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, IDLabels: true}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_backend_current_sessions Current number of active sessions.
+# TYPE haproxy_backend_current_sessions gauge
+haproxy_backend_current_sessions{backend="be",process_id="1",proxy_id="3"} 2
+# HELP haproxy_frontend_current_sessions Current number of active sessions.
+# TYPE haproxy_frontend_current_sessions gauge
+haproxy_frontend_current_sessions{frontend="fe",process_id="1",proxy_id="2"} 1
+# HELP haproxy_server_current_sessions Current number of active sessions.
+# TYPE haproxy_server_current_sessions gauge
+haproxy_server_current_sessions{backend="be",process_id="1",proxy_id="3",server="srv",server_id="1"} 3
+# HELP haproxy_server_status Current status of the server, 1 for the state it is in.
+# TYPE haproxy_server_status gauge
+haproxy_server_status{backend="be",process_id="1",proxy_id="3",server="srv",server_id="1",state="DOWN"} 0
+haproxy_server_status{backend="be",process_id="1",proxy_id="3",server="srv",server_id="1",state="DRAIN"} 0
+haproxy_server_status{backend="be",process_id="1",proxy_id="3",server="srv",server_id="1",state="MAINT"} 0
+haproxy_server_status{backend="be",process_id="1",proxy_id="3",server="srv",server_id="1",state="NOLB"} 0
+haproxy_server_status{backend="be",process_id="1",proxy_id="3",server="srv",server_id="1",state="UP"} 1
+haproxy_server_status{backend="be",process_id="1",proxy_id="3",server="srv",server_id="1",state="no_check"} 0
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_frontend_current_sessions", "haproxy_backend_current_sessions", "haproxy_server_current_sessions", "haproxy_server_status"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReservedLabelNames(t *testing.T) {
+	reserved := reservedLabelNames()
+	// Labels of the stats, the exporter's own metrics and runtime collectors.
+	for _, name := range []string{"frontend", "backend", "server", "state", "mode", "code", "process", "proxy_id", "release_date", "version", "reason", "phase", "le"} {
+		if !reserved[name] {
+			t.Errorf("want label name %q reserved", name)
+		}
+	}
+	if reserved["role"] {
+		t.Errorf("want label name %q not reserved", "role")
+	}
+
+	d := prometheus.NewDesc("foo", "Foo.", []string{"a", "b"}, prometheus.Labels{"c": "x"})
+	if want, have := []string{"a", "b"}, descLabelNames(d); strings.Join(want, ",") != strings.Join(have, ",") {
+		t.Errorf("want label names %v, have %v", want, have)
+	}
+}
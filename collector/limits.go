@@ -0,0 +1,133 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/haproxy_exporter/parser"
+)
+
+// The limits of the per-server series, the values of the label limit of
+// haproxy_exporter_series_limited_total.
+const (
+	limitMaxServersPerBackend = "max_servers_per_backend"
+	limitMaxServers           = "max_servers"
+)
+
+// limitFlags are the flags of the limits.
+var limitFlags = map[string]string{
+	limitMaxServersPerBackend: "--haproxy.max-servers-per-backend",
+	limitMaxServers:           "--haproxy.max-servers",
+}
+
+// limitServers returns the backends whose server metrics are dropped by
+// serverLimits, counting them in haproxy_exporter_series_limited_total. A
+// warning is logged once when a backend is first dropped.
+func (e *Exporter) limitServers(rows []parser.Row) map[string]bool {
+	limits := e.serverLimits(rows)
+	if limits == nil {
+		return nil
+	}
+	limited := make(map[string]bool, len(limits))
+	for backend, l := range limits {
+		limited[backend] = true
+		e.seriesLimited.WithLabelValues(l.limit).Inc()
+		if !e.limitedBackends[backend] {
+			level.Warn(e.logger).Log("msg", "Dropping the server metrics of a backend", "backend", backend, "servers", l.servers, "limit", l.limit)
+		}
+	}
+	for backend := range e.limitedBackends {
+		if !limited[backend] {
+			level.Info(e.logger).Log("msg", "Exporting the server metrics of a backend again", "backend", backend)
+		}
+	}
+	e.limitedBackends = limited
+	return limited
+}
+
+// serverLimit is the limit exceeded by the servers of a backend.
+type serverLimit struct {
+	limit   string
+	servers int
+}
+
+// serverLimits returns the backends whose server metrics are dropped, as they
+// have more servers than e.maxServersPerBackend, or as their servers would
+// exceed e.maxServers along with those of the backends before them in the
+// canonical rows, or nil without limits. Only the servers which are exported
+// otherwise count.
+func (e *Exporter) serverLimits(rows []parser.Row) map[string]serverLimit {
+	if e.maxServersPerBackend <= 0 && e.maxServers <= 0 {
+		return nil
+	}
+	var (
+		backends []string // In the order of the stats.
+		servers  = map[string]int{}
+	)
+	for _, row := range rows {
+		f := row.Fields
+		if f[typeField] != "2" || !e.serverExported(f) {
+			continue
+		}
+		if _, ok := servers[f[pxnameField]]; !ok {
+			backends = append(backends, f[pxnameField])
+		}
+		servers[f[pxnameField]]++
+	}
+
+	limited := map[string]serverLimit{}
+	total := 0
+	for _, backend := range backends {
+		n := servers[backend]
+		limit := ""
+		switch {
+		case e.maxServersPerBackend > 0 && n > e.maxServersPerBackend:
+			limit = limitMaxServersPerBackend
+		case e.maxServers > 0 && total+n > e.maxServers:
+			limit = limitMaxServers
+		default:
+			total += n
+			continue
+		}
+		limited[backend] = serverLimit{limit: limit, servers: n}
+	}
+	return limited
+}
+
+// serverExported returns whether the server of a canonical row is exported,
+// not being excluded by its state, its name or that of its proxy, or as an
+// unprovisioned slot.
+func (e *Exporter) serverExported(csvRow []string) bool {
+	return e.serverSkipReason(csvRow) == ""
+}
+
+// serverSkipReason returns why the server of a canonical row isn't exported,
+// or "" if it is.
+func (e *Exporter) serverSkipReason(csvRow []string) string {
+	if !e.proxyExported(csvRow[pxnameField]) {
+		return proxySkipReason
+	}
+	if _, ok := e.excludedServerStates[csvRow[statusField]]; ok {
+		return fmt.Sprintf("server status %q is excluded by --haproxy.server-exclude-states", csvRow[statusField])
+	}
+	if !e.includeUnprovisioned && unprovisioned(csvRow) {
+		return "server is an unprovisioned slot of a server template, see --haproxy.include-unprovisioned-servers"
+	}
+	if flag := e.serverNameFilter(csvRow[svnameField]); flag != "" {
+		return fmt.Sprintf("server name is filtered out by %s", flag)
+	}
+	return ""
+}
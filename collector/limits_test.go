@@ -0,0 +1,74 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestServerLimits(t *testing.T) {
+	server := func(backend, name, status string) string {
+		return newRow(backend, name, "2", map[int]string{4: "1", statusField: status})
+	}
+	rows := server("small", "a", "UP") +
+		// The server in maintenance is excluded, and doesn't count.
+		server("medium", "a", "UP") + server("medium", "b", "UP") + server("medium", "c", "MAINT") +
+		server("large", "a", "UP") + server("large", "b", "UP") + server("large", "c", "UP") +
+		server("last", "a", "UP") + server("last", "b", "UP") +
+		newRow("large", "BACKEND", "1", map[int]string{4: "3"})
+	h := newHaproxy([]byte(rows))
+	defer h.Close()
+
+	var logs bytes.Buffer
+	e, err := NewExporter(h.URL, ExporterOpts{
+		ServerMetrics:        serverMetrics,
+		ExcludedServerStates: "MAINT",
+		Timeout:              5 * time.Second,
+		MaxServersPerBackend: 2,
+		MaxServers:           4,
+	}, log.NewLogfmtLogger(&logs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_backend_current_sessions Current number of active sessions.
+# TYPE haproxy_backend_current_sessions gauge
+haproxy_backend_current_sessions{backend="large"} 3
+# HELP haproxy_exporter_series_limited_total Number of times the server metrics of a backend were dropped, by the limit exceeded: max_servers_per_backend or max_servers.
+# TYPE haproxy_exporter_series_limited_total counter
+haproxy_exporter_series_limited_total{limit="max_servers"} 1
+haproxy_exporter_series_limited_total{limit="max_servers_per_backend"} 1
+# HELP haproxy_server_current_sessions Current number of active sessions.
+# TYPE haproxy_server_current_sessions gauge
+haproxy_server_current_sessions{backend="medium",server="a"} 1
+haproxy_server_current_sessions{backend="medium",server="b"} 1
+haproxy_server_current_sessions{backend="small",server="a"} 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_backend_current_sessions", "haproxy_server_current_sessions", "haproxy_exporter_series_limited_total"); err != nil {
+		t.Error(err)
+	}
+
+	// The backends dropped again aren't warned about again.
+	testutil.CollectAndCount(e)
+	if n := strings.Count(logs.String(), "Dropping the server metrics"); n != 2 {
+		t.Errorf("expected one warning per dropped backend, got %d:\n%s", n, logs.String())
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// slogAdapter is a go-kit Logger writing to a slog.Logger.
+type slogAdapter struct {
+	logger *slog.Logger
+}
+
+// FromSlog returns a go-kit Logger, as taken by NewExporter, which writes to l.
+// This allows routing the logs of an embedded Exporter into a slog based
+// logging stack. Messages are logged at the level set with the go-kit level
+// package, and at info level if there is none.
+func FromSlog(l *slog.Logger) log.Logger {
+	return slogAdapter{logger: l}
+}
+
+// Log implements log.Logger.
+func (a slogAdapter) Log(keyvals ...interface{}) error {
+	lvl, msg := slog.LevelInfo, ""
+	attrs := make([]any, 0, len(keyvals))
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		var value interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+
+		switch {
+		case key == fmt.Sprint(level.Key()):
+			lvl = slogLevel(fmt.Sprint(value))
+		case key == "msg":
+			msg = fmt.Sprint(value)
+		default:
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			attrs = append(attrs, slog.Any(key, value))
+		}
+	}
+	a.logger.Log(context.Background(), lvl, msg, attrs...)
+	return nil
+}
+
+// slogLevel returns the slog level of a go-kit or promlog level name.
+func slogLevel(name string) slog.Level {
+	switch name {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/go-kit/log/level"
+)
+
+func TestFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := FromSlog(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	level.Info(logger).Log("msg", "Dropped")
+	level.Error(logger).Log("msg", "Can't scrape HAProxy", "err", errors.New("down"), "target", "http://localhost/")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("want a single JSON record, have %q: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{
+		"level":  "ERROR",
+		"msg":    "Can't scrape HAProxy",
+		"err":    "down",
+		"target": "http://localhost/",
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("want %s %q, have %q", k, v, record[k])
+		}
+	}
+}
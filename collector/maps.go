@@ -0,0 +1,58 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The metrics of the maps of show map and the ACLs of show acl, by field.
+var (
+	mapMetrics = map[string]metricInfo{
+		"entry_cnt": newMetricInfo(prometheus.BuildFQName(namespace, "map", "entries"), "Current number of entries of the map.", prometheus.GaugeValue, []string{"id", "file"}, nil),
+	}
+	aclMetrics = map[string]metricInfo{
+		"entry_cnt": newMetricInfo(prometheus.BuildFQName(namespace, "acl", "entries"), "Current number of entries of the ACL.", prometheus.GaugeValue, []string{"id", "file"}, nil),
+	}
+)
+
+var patternListRE = regexp.MustCompile(`^(\d+) \(([^)]*)\) .*\bentry_cnt=(\d+)`)
+
+// parsePatternLists sends the metrics of the list of maps of show map, or of
+// ACLs of show acl, which has a line like
+//
+//	1 (/etc/haproxy/hosts.map) pattern loaded from file ... curr_ver=0 next_ver=0 entry_cnt=3
+//
+// for each. ACLs of the configuration have no file. Older HAProxy releases
+// don't show entry_cnt, and no metrics are exported for them.
+func parsePatternLists(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		m := patternListRE.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return err
+		}
+		metrics["entry_cnt"].send(ch, v, m[1], m[2])
+	}
+	return s.Err()
+}
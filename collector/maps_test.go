@@ -0,0 +1,60 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParsePatternLists(t *testing.T) {
+	tests := []struct {
+		collector, output, expected string
+	}{
+		{
+			collector: "maps",
+			output: `# id (file) description
+1 (/etc/haproxy/hosts.map) pattern loaded from file '/etc/haproxy/hosts.map' used by map at file '/etc/haproxy/haproxy.cfg' line 12. curr_ver=0 next_ver=0 entry_cnt=3
+`,
+			expected: `
+# HELP haproxy_map_entries Current number of entries of the map.
+# TYPE haproxy_map_entries gauge
+haproxy_map_entries{file="/etc/haproxy/hosts.map",id="1"} 3
+`,
+		},
+		{
+			collector: "acls",
+			output: `# id (file) description
+0 (/etc/haproxy/blocklist.acl) pattern loaded from file '/etc/haproxy/blocklist.acl' used by acl at file '/etc/haproxy/haproxy.cfg' line 20. curr_ver=2 next_ver=2 entry_cnt=120
+1 () acl 'src' file '/etc/haproxy/haproxy.cfg' line 21. curr_ver=0 next_ver=0 entry_cnt=2
+2 () acl 'path_beg' file '/etc/haproxy/haproxy.cfg' line 22
+`,
+			expected: `
+# HELP haproxy_acl_entries Current number of entries of the ACL.
+# TYPE haproxy_acl_entries gauge
+haproxy_acl_entries{file="",id="1"} 2
+haproxy_acl_entries{file="/etc/haproxy/blocklist.acl",id="0"} 120
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		p := parsedRuntime{collector: runtimeCollectors[tt.collector], output: tt.output}
+		if err := testutil.CollectAndCompare(p, strings.NewReader(tt.expected)); err != nil {
+			t.Errorf("%s: %v", tt.collector, err)
+		}
+	}
+}
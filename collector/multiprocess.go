@@ -0,0 +1,113 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/haproxy_exporter/parser"
+)
+
+// The ways of exporting the rows of several HAProxy processes, e.g. of
+// nbproc setups, for the same proxy or server.
+const (
+	MultiProcessNone      = "none"
+	MultiProcessLabel     = "label"
+	MultiProcessAggregate = "aggregate"
+)
+
+// pidField is the number of the process a row is of, counting from 1.
+const pidField = 26
+
+// checkMultiProcess returns an error if mode is not a known way of exporting
+// the rows of several processes. The empty mode is none.
+func checkMultiProcess(mode string) error {
+	switch mode {
+	case "", MultiProcessNone, MultiProcessLabel, MultiProcessAggregate:
+		return nil
+	default:
+		return fmt.Errorf("unknown multi-process mode %q", mode)
+	}
+}
+
+// rowMetricsOf returns the metrics of the rows of process, which have the
+// const label process in the label mode. Otherwise, all rows have the same
+// metrics.
+func (e *Exporter) rowMetricsOf(process string) rowMetrics {
+	if e.multiProcess != MultiProcessLabel {
+		return e.rowMetrics
+	}
+	return e.shared.rowMetricsOf(process)
+}
+
+// aggregateProcesses returns the canonical rows with the rows of the same
+// proxy or server merged into the first of them. Fields of exported counters
+// are summed, those of other exported metrics take their maximum, and other
+// fields, e.g. the status, are those of the first row.
+func (e *Exporter) aggregateProcesses(rows []parser.Row) []parser.Row {
+	type key struct{ pxname, svname, typ string }
+	first := make(map[key]int, len(rows))
+	res := rows[:0]
+	for _, row := range rows {
+		f := row.Fields
+		k := key{f[pxnameField], f[svnameField], f[typeField]}
+		i, ok := first[k]
+		if !ok {
+			first[k] = len(res)
+			res = append(res, parser.Row{Line: row.Line, Fields: append([]string(nil), f...)})
+			continue
+		}
+		mergeRow(e.rowMetrics.metricsOf(f[typeField]), res[i].Fields, f)
+	}
+	return res
+}
+
+// metricsOf returns the metrics of the rows of the type typ, or nil for other
+// types, e.g. listeners.
+func (r rowMetrics) metricsOf(typ string) metrics {
+	switch typ {
+	case "0":
+		return r.frontendMetrics
+	case "1":
+		return r.backendMetrics
+	case "2":
+		return r.serverMetrics
+	}
+	return nil
+}
+
+// mergeRow merges the fields of the metrics m of row into those of into,
+// which is of the same proxy or server.
+func mergeRow(m metrics, into, row []string) {
+	for i := 0; i < len(into) && i < len(row); i++ {
+		metric, ok := m[i]
+		if !ok || i == statusField {
+			continue
+		}
+		a, errA := strconv.ParseFloat(into[i], 64)
+		b, errB := strconv.ParseFloat(row[i], 64)
+		switch {
+		case errB != nil:
+		case errA != nil:
+			into[i] = row[i]
+		case metric.Type == prometheus.CounterValue:
+			into[i] = strconv.FormatFloat(a+b, 'f', -1, 64)
+		default:
+			into[i] = strconv.FormatFloat(math.Max(a, b), 'f', -1, 64)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/haproxy_exporter/parser"
+)
+
+// processRows are the rows of a frontend of two processes.
+var processRows = newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", pidField: "1", 5: "3", 7: "10"}) +
+	newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", pidField: "2", 5: "7", 7: "5"})
+
+func TestMultiProcess(t *testing.T) {
+	tests := []struct {
+		mode, expected string
+	}{
+		{
+			mode: MultiProcessLabel,
+			expected: `
+# HELP haproxy_frontend_max_sessions Maximum observed number of active sessions.
+# TYPE haproxy_frontend_max_sessions gauge
+haproxy_frontend_max_sessions{frontend="foo",process="1"} 3
+haproxy_frontend_max_sessions{frontend="foo",process="2"} 7
+# HELP haproxy_frontend_sessions_total Total number of sessions.
+# TYPE haproxy_frontend_sessions_total counter
+haproxy_frontend_sessions_total{frontend="foo",process="1"} 10
+haproxy_frontend_sessions_total{frontend="foo",process="2"} 5
+`,
+		},
+		{
+			mode: MultiProcessAggregate,
+			expected: `
+# HELP haproxy_frontend_max_sessions Maximum observed number of active sessions.
+# TYPE haproxy_frontend_max_sessions gauge
+haproxy_frontend_max_sessions{frontend="foo"} 7
+# HELP haproxy_frontend_sessions_total Total number of sessions.
+# TYPE haproxy_frontend_sessions_total counter
+haproxy_frontend_sessions_total{frontend="foo"} 15
+`,
+		},
+	}
+
+	h := newHaproxy([]byte(processRows))
+	defer h.Close()
+	for _, tt := range tests {
+		e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, MultiProcess: tt.mode}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The metrics labeled with the process aren't described, which
+		// pedantic registries reject.
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(e)
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(tt.expected), "haproxy_frontend_max_sessions", "haproxy_frontend_sessions_total"); err != nil {
+			t.Errorf("%s: %v", tt.mode, err)
+		}
+	}
+}
+
+func TestMultiProcessInvalid(t *testing.T) {
+	if _, err := NewExporter("http://localhost/;csv", ExporterOpts{MultiProcess: "sum"}, log.NewNopLogger()); err == nil {
+		t.Error("want error for unknown multi-process mode")
+	}
+}
+
+func TestAggregateProcessesExportedFields(t *testing.T) {
+	// Only the total sessions of the servers are exported.
+	m, err := FilterServerMetrics("7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewExporter("http://localhost/;csv", ExporterOpts{ServerMetrics: m, MultiProcess: MultiProcessAggregate}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []parser.Row
+	for _, pid := range []string{"1", "2"} {
+		row := newRow("be", "srv", "2", map[int]string{statusField: "UP", pidField: pid, 5: pid + "0", 7: pid})
+		rows = append(rows, parser.Row{Fields: strings.Split(strings.TrimSuffix(row, ",\n"), ",")})
+	}
+	rows = e.aggregateProcesses(rows)
+	if len(rows) != 1 {
+		t.Fatalf("want 1 row, got %d", len(rows))
+	}
+	// The field 5, which isn't exported, is that of the first process.
+	if got := rows[0].Fields[5]; got != "10" {
+		t.Errorf("want field 5 of the first process, got %q", got)
+	}
+	if got := rows[0].Fields[7]; got != "3" {
+		t.Errorf("want summed field 7, got %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Naming schemes of the metrics, selected with --metrics.naming-scheme.
+const (
+	NamingLegacy = "legacy"
+	NamingV2     = "v2"
+	NamingBoth   = "both"
+)
+
+// v2Name is the name of a metric in the v2 naming scheme. Its values are the
+// legacy ones divided by divisor, if it is not 0.
+type v2Name struct {
+	name    string
+	divisor float64
+}
+
+// v2Names maps the legacy names of metrics to their v2 names, which follow the
+// Prometheus naming best practices: base units, the unit as suffix, and no
+// current or max prefixes. current_sessions keeps its name, as sessions would
+// be in the same OpenMetrics family as the counter sessions_total. Metrics
+// missing here have the same name in both.
+var v2Names = map[string]v2Name{
+	"haproxy_process_idle_time_percent":                  {name: "haproxy_process_idle_time_ratio", divisor: 100},
+	"haproxy_process_ssl_frontend_session_reuse_percent": {name: "haproxy_process_ssl_frontend_session_reuse_ratio", divisor: 100},
+	"haproxy_process_ssl_frontend_max_key_rate":          {name: "haproxy_process_ssl_frontend_keys_per_second_max"},
+	"haproxy_process_ssl_backend_max_key_rate":           {name: "haproxy_process_ssl_backend_keys_per_second_max"},
+	"haproxy_process_ssl_frontend_key_rate":              {name: "haproxy_process_ssl_frontend_keys_per_second"},
+	"haproxy_process_ssl_backend_key_rate":               {name: "haproxy_process_ssl_backend_keys_per_second"},
+	"haproxy_server_server_selected_total":               {name: "haproxy_server_selected_total"},
+	"haproxy_backend_current_server":                     {name: "haproxy_backend_active_servers"},
+	"haproxy_server_throttle_percent":                    {name: "haproxy_server_throttle_ratio", divisor: 100},
+	"haproxy_frontend_current_http_request_rate":         {name: "haproxy_frontend_http_requests_per_second"},
+	"haproxy_frontend_max_http_request_rate":             {name: "haproxy_frontend_http_requests_per_second_max"},
+	"haproxy_frontend_current_connection_rate":           {name: "haproxy_frontend_connections_per_second"},
+	"haproxy_frontend_max_connection_rate":               {name: "haproxy_frontend_connections_per_second_max"},
+}
+
+func init() {
+	for _, subsystem := range []string{"frontend", "backend", "server"} {
+		for legacy, v2 := range map[string]string{
+			"current_queue":                   "queued_requests",
+			"max_queue":                       "queued_requests_max",
+			"limit_queue":                     "queued_requests_limit",
+			"max_sessions":                    "sessions_max",
+			"limit_sessions":                  "sessions_limit",
+			"current_session_rate":            "sessions_per_second",
+			"max_session_rate":                "sessions_per_second_max",
+			"limit_session_rate":              "sessions_per_second_limit",
+			"bytes_in_total":                  "received_bytes_total",
+			"bytes_out_total":                 "sent_bytes_total",
+			"compressor_bytes_in_total":       "compressor_input_bytes_total",
+			"compressor_bytes_out_total":      "compressor_output_bytes_total",
+			"compressor_bytes_bypassed_total": "compressor_bypassed_bytes_total",
+		} {
+			v2Names[prometheus.BuildFQName(namespace, subsystem, legacy)] = v2Name{name: prometheus.BuildFQName(namespace, subsystem, v2)}
+		}
+	}
+}
+
+// checkNamingScheme returns an error if scheme is not a known naming scheme.
+// The empty scheme is the legacy one.
+func checkNamingScheme(scheme string) error {
+	switch scheme {
+	case "", NamingLegacy, NamingV2, NamingBoth:
+		return nil
+	default:
+		return fmt.Errorf("unknown naming scheme %q", scheme)
+	}
+}
+
+// withNamingScheme returns m named according to scheme. With the both scheme
+// metrics renamed by v2 are also exported under their v2 name.
+func (m metricInfo) withNamingScheme(scheme string) metricInfo {
+	v2, ok := v2Names[m.fqName]
+	if !ok || scheme == "" || scheme == NamingLegacy {
+		return m
+	}
+	renamed := newMetricInfo(v2.name, m.help, m.Type, m.variableLabels, m.constLabels)
+	renamed.divisor = m.divisor
+	if v2.divisor != 0 {
+		if renamed.divisor == 0 {
+			renamed.divisor = 1
+		}
+		renamed.divisor *= v2.divisor
+	}
+	if scheme == NamingV2 {
+		return renamed
+	}
+	m.also = &renamed
+	return m
+}
+
+// withNamingScheme returns m with every metric named according to scheme.
+func (m metrics) withNamingScheme(scheme string) metrics {
+	res := make(metrics, len(m))
+	for field, metric := range m {
+		res[field] = metric.withNamingScheme(scheme)
+	}
+	return res
+}
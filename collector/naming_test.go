@@ -0,0 +1,174 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestNamingScheme(t *testing.T) {
+	h := newHaproxy([]byte("foo,FRONTEND,,,1,2,,,10,20,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"))
+	defer h.Close()
+
+	tests := []struct {
+		scheme string
+		want   map[string]int
+	}{
+		{
+			scheme: NamingLegacy,
+			want:   map[string]int{"haproxy_frontend_bytes_in_total": 1, "haproxy_frontend_received_bytes_total": 0, "haproxy_frontend_max_sessions": 1, "haproxy_frontend_sessions_max": 0},
+		},
+		{
+			scheme: NamingV2,
+			want:   map[string]int{"haproxy_frontend_bytes_in_total": 0, "haproxy_frontend_received_bytes_total": 1, "haproxy_frontend_max_sessions": 0, "haproxy_frontend_sessions_max": 1},
+		},
+		{
+			scheme: NamingBoth,
+			want:   map[string]int{"haproxy_frontend_bytes_in_total": 1, "haproxy_frontend_received_bytes_total": 1, "haproxy_frontend_max_sessions": 1, "haproxy_frontend_sessions_max": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, NamingScheme: tt.scheme}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		registry := prometheus.NewPedanticRegistry()
+		registry.MustRegister(e)
+		for name, want := range tt.want {
+			if have := testutil.CollectAndCount(registry, name); want != have {
+				t.Errorf("%s: want %d series of %s, have %d", tt.scheme, want, name, have)
+			}
+		}
+	}
+
+	if _, err := NewExporter(h.URL, ExporterOpts{NamingScheme: "v3"}, log.NewNopLogger()); err == nil {
+		t.Errorf("want error for unknown naming scheme")
+	}
+}
+
+func TestNamingSchemeScale(t *testing.T) {
+	ch := make(chan prometheus.Metric, 2)
+	haproxyIdlePct.withNamingScheme(NamingBoth).send(ch, 42)
+	close(ch)
+
+	want := map[string]float64{"haproxy_process_idle_time_percent": 42, "haproxy_process_idle_time_ratio": 0.42}
+	found := 0
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		name := m.Desc().String()
+		for n, v := range want {
+			if !strings.Contains(name, `"`+n+`"`) {
+				continue
+			}
+			found++
+			if have := pb.GetGauge().GetValue(); v != have {
+				t.Errorf("want %s %v, have %v", n, v, have)
+			}
+		}
+	}
+	if found != len(want) {
+		t.Errorf("want %d metrics, have %d", len(want), found)
+	}
+}
+
+func TestNamingSchemeRates(t *testing.T) {
+	rows := newRow("foo", "FRONTEND", "0", map[int]string{statusField: "OPEN", 46: "3", 77: "5"}) +
+		newRow("bar", "baz", "2", map[int]string{statusField: "UP", 29: "50", 77: ""}) // As long as the first row.
+	h := newHaproxy([]byte(rows))
+	defer h.Close()
+
+	e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, NamingScheme: NamingV2}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+# HELP haproxy_frontend_connections_per_second Current number of connections per second over last elapsed second.
+# TYPE haproxy_frontend_connections_per_second gauge
+haproxy_frontend_connections_per_second{frontend="foo"} 5
+# HELP haproxy_frontend_http_requests_per_second Current number of HTTP requests per second over last elapsed second.
+# TYPE haproxy_frontend_http_requests_per_second gauge
+haproxy_frontend_http_requests_per_second{frontend="foo"} 3
+# HELP haproxy_server_throttle_ratio Current throttle of the server during slowstart, in percent of its weight.
+# TYPE haproxy_server_throttle_ratio gauge
+haproxy_server_throttle_ratio{backend="bar",server="baz"} 0.5
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected),
+		"haproxy_frontend_connections_per_second",
+		"haproxy_frontend_http_requests_per_second",
+		"haproxy_server_throttle_ratio",
+	); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestNamingSchemeOpenMetrics checks that no metric of the naming schemes
+// shares its family with another in OpenMetrics, which drops the _total
+// suffix of counters from the family names.
+func TestNamingSchemeOpenMetrics(t *testing.T) {
+	data, err := os.ReadFile("test/haproxy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newHaproxy(data)
+	defer h.Close()
+
+	for _, scheme := range []string{NamingLegacy, NamingV2, NamingBoth} {
+		e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, NamingScheme: scheme}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		registry := prometheus.NewPedanticRegistry()
+		registry.MustRegister(e)
+		mfs, err := registry.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		enc := expfmt.NewEncoder(&b, expfmt.FmtOpenMetrics)
+		for _, mf := range mfs {
+			if err := enc.Encode(mf); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		families := map[string]string{}
+		for _, line := range strings.Split(b.String(), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 4 || fields[0] != "#" || fields[1] != "TYPE" {
+				continue
+			}
+			if typ, ok := families[fields[2]]; ok {
+				t.Errorf("%s: family %s is both %s and %s", scheme, fields[2], typ, fields[3])
+			}
+			families[fields[2]] = fields[3]
+		}
+		if len(families) == 0 {
+			t.Errorf("%s: no metric families", scheme)
+		}
+	}
+}
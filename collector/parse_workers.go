@@ -0,0 +1,144 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/haproxy_exporter/parser"
+)
+
+// rowBatchSize is the number of rows handed to a parse worker at once.
+const rowBatchSize = 256
+
+// rowBatch is a batch of rows handed to a parse worker. Batches are reused
+// across scrapes, along with the fields of the rows read while streaming.
+type rowBatch struct {
+	rows   []parser.Row
+	fields []string // Of the rows added by addRead.
+}
+
+var rowBatchPool = sync.Pool{
+	New: func() any { return &rowBatch{rows: make([]parser.Row, 0, rowBatchSize)} },
+}
+
+// release returns b to the pool, dropping the rows to not keep their memory.
+func (b *rowBatch) release() {
+	clear(b.rows)
+	clear(b.fields)
+	b.rows, b.fields = b.rows[:0], b.fields[:0]
+	rowBatchPool.Put(b)
+}
+
+// rowExport exports the canonical rows of a scrape, on the goroutine of the
+// scrape or, with several parse workers, in batches on as many goroutines.
+// The metrics of a row don't depend on other rows, so that the order in
+// which the workers export the rows doesn't matter.
+type rowExport struct {
+	e                 *Exporter
+	cols              columns
+	ch                chan<- prometheus.Metric
+	sections, limited map[string]bool
+
+	fields  []string // The canonical fields of the row read last.
+	batch   *rowBatch
+	batches chan *rowBatch // Nil without parse workers.
+	wg      sync.WaitGroup
+}
+
+// newRowExport returns a rowExport sending the metrics of the given sections
+// to ch, without the server metrics of the limited backends. Its wait method
+// must be called when all rows were added.
+func (e *Exporter) newRowExport(cols columns, ch chan<- prometheus.Metric, sections, limited map[string]bool) *rowExport {
+	x := &rowExport{e: e, cols: cols, ch: ch, sections: sections, limited: limited}
+	if e.parseWorkers > 1 {
+		x.batches = make(chan *rowBatch, e.parseWorkers)
+		x.wg.Add(e.parseWorkers)
+		for i := 0; i < e.parseWorkers; i++ {
+			go x.work()
+		}
+	}
+	return x
+}
+
+// add exports the canonical row, or adds it to the next batch of the
+// workers.
+func (x *rowExport) add(row parser.Row) {
+	if x.batches == nil {
+		x.e.parseRow(row.Fields, x.cols, x.ch, x.sections, x.limited, &x.e.labelPairs)
+		return
+	}
+	b := x.next()
+	b.rows = append(b.rows, row)
+	x.flush(false)
+}
+
+// addRead is like add for a row as read from the stats, whose fields are only
+// valid until the next row is read. They are copied at their canonical
+// numbers.
+func (x *rowExport) addRead(row parser.Row) {
+	if x.batches == nil {
+		x.fields = x.cols.appendCanonical(x.fields[:0], row.Fields)
+		x.e.parseRow(x.fields, x.cols, x.ch, x.sections, x.limited, &x.e.labelPairs)
+		return
+	}
+	b := x.next()
+	start := len(b.fields)
+	b.fields = x.cols.appendCanonical(b.fields, row.Fields)
+	b.rows = append(b.rows, parser.Row{Line: row.Line, Fields: b.fields[start:len(b.fields):len(b.fields)]})
+	x.flush(false)
+}
+
+// next returns the batch the next row is added to.
+func (x *rowExport) next() *rowBatch {
+	if x.batch == nil {
+		x.batch = rowBatchPool.Get().(*rowBatch)
+	}
+	return x.batch
+}
+
+// flush hands the batch to the workers once it is full, or if last, once it
+// has rows.
+func (x *rowExport) flush(last bool) {
+	if x.batch == nil {
+		return
+	}
+	if n := len(x.batch.rows); n == rowBatchSize || last && n > 0 {
+		x.batches <- x.batch
+		x.batch = nil
+	}
+}
+
+// wait hands the last batch to the workers and waits for them to export all
+// rows.
+func (x *rowExport) wait() {
+	if x.batches == nil {
+		return
+	}
+	x.flush(true)
+	close(x.batches)
+	x.wg.Wait()
+}
+
+func (x *rowExport) work() {
+	defer x.wg.Done()
+	var pairs rowLabelPairs
+	for batch := range x.batches {
+		for _, row := range batch.rows {
+			x.e.parseRow(row.Fields, x.cols, x.ch, x.sections, x.limited, &pairs)
+		}
+		batch.release()
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// gatherRowMetrics returns the metrics of the rows of the stats exported by
+// an Exporter with opts, in the text format.
+func gatherRowMetrics(t *testing.T, uri string, opts ExporterOpts) string {
+	t.Helper()
+	e, err := NewExporter(uri, opts, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(e)
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for _, mf := range mfs {
+		if strings.HasPrefix(mf.GetName(), "haproxy_exporter_") {
+			continue
+		}
+		if _, err := expfmt.MetricFamilyToText(&b, mf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b.String()
+}
+
+func TestParseWorkers(t *testing.T) {
+	stats, err := os.ReadFile("test/haproxy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newHaproxy(stats)
+	defer h.Close()
+
+	opts := ExporterOpts{ServerMetrics: serverMetrics, ExcludedServerStates: excludedServerStates, Timeout: 5 * time.Second}
+	want := gatherRowMetrics(t, h.URL, opts)
+	if !strings.Contains(want, "haproxy_server_up") {
+		t.Fatal("want server metrics from test/haproxy.csv")
+	}
+	for _, stream := range []bool{false, true} {
+		opts.ParseWorkers, opts.StreamStats = 4, stream
+		if have := gatherRowMetrics(t, h.URL, opts); have != want {
+			t.Errorf("stream %t: want the metrics exported without parse workers, have\n%s", stream, have)
+		}
+	}
+}
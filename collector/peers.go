@@ -0,0 +1,186 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	peerLabelNames      = []string{"peers", "peer"}
+	peerTableLabelNames = []string{"peers", "peer", "table"}
+)
+
+// peerMetrics are the metrics of the remote peers of show peers, by field. The
+// update fields are those of the stick tables shared with a peer.
+var peerMetrics = map[string]metricInfo{
+	"established": newPeerMetric("established", "Whether the connection to the peer is established (1 = established, 0 = not).", prometheus.GaugeValue, peerLabelNames),
+	"last_hdshk":  newPeerMetric("last_handshake_age_seconds", "Time since the last handshake with the peer.", prometheus.GaugeValue, peerLabelNames),
+	"new_conn":    newPeerMetric("connections_total", "Total number of connections to the peer.", prometheus.CounterValue, peerLabelNames),
+	"proto_err":   newPeerMetric("protocol_errors_total", "Total number of protocol errors of the peer.", prometheus.CounterValue, peerLabelNames),
+	"coll":        newPeerMetric("collisions_total", "Total number of connections closed because the peer connected at the same time.", prometheus.CounterValue, peerLabelNames),
+	"update":      newPeerMetric("table_update", "Current update ID of the shared stick table.", prometheus.GaugeValue, peerTableLabelNames),
+	"last_pushed": newPeerMetric("table_last_pushed_update", "ID of the last update of the shared stick table pushed to the peer.", prometheus.GaugeValue, peerTableLabelNames),
+	"last_acked":  newPeerMetric("table_last_acked_update", "ID of the last update of the shared stick table acknowledged by the peer.", prometheus.GaugeValue, peerTableLabelNames),
+	"last_get":    newPeerMetric("table_last_received_update", "ID of the last update of the shared stick table received from the peer.", prometheus.GaugeValue, peerTableLabelNames),
+}
+
+func newPeerMetric(metricName string, docString string, t prometheus.ValueType, labels []string) metricInfo {
+	return newMetricInfo(prometheus.BuildFQName(namespace, "peer", metricName), docString, t, labels, nil)
+}
+
+// parsePeers sends the metrics of the output of show peers. It lists the
+// peers of every peers section, each followed by the stick tables shared with
+// it, as key=value pairs spread over several lines:
+//
+//	0x55deb0224320: [07/Dec/2018:12:49:51] id=sharedlb disabled=0 flags=0x3 ...
+//	  0x55deb022a440: id=lbtap(remote,active) addr=127.0.0.1:10000 last_status=ESTA last_hdshk=2s
+//	        reconnect=4s confirm=0 new_conn=1 proto_err=0 coll=0
+//	        shared tables:
+//	          0x55deb0220980 local_id=1 remote_id=1 flags=0x0 remote_data=0x65
+//	              last_acked=0 last_pushed=3 last_get=0 teaching_origin=0 update=3
+//	              table:0x55deb022d6a0 id=tap update=3 localupdate=3 commitupdate=3 syncing=0
+//
+// The local peer has no connection and is left out.
+func parsePeers(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	var (
+		section, peer string
+		peerFields    map[string]string // Of the current remote peer.
+		tableFields   map[string]string // Of the current shared stick table.
+		inTables      bool
+	)
+	sendPeer := func() error {
+		if peer == "" {
+			return nil
+		}
+		status, ok := peerFields["last_status"]
+		if !ok {
+			status = peerFields["status"]
+		}
+		metrics["established"].send(ch, boolToFloat(status == "ESTA"), section, peer)
+		if hdshk, ok := peerFields["last_hdshk"]; ok && !strings.HasPrefix(hdshk, "<") {
+			d, err := parseHumanTime(hdshk)
+			if err != nil {
+				return fmt.Errorf("invalid last_hdshk of peer %q: %w", peer, err)
+			}
+			metrics["last_hdshk"].send(ch, d.Seconds(), section, peer)
+		}
+		for _, field := range []string{"new_conn", "proto_err", "coll"} {
+			if err := sendPeerField(ch, metrics[field], peerFields, field, section, peer); err != nil {
+				return err
+			}
+		}
+		peer = ""
+		return nil
+	}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		fields := keyValues(line)
+		first, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch {
+		case !strings.HasPrefix(line, " ") && fields["id"] != "":
+			if err := sendPeer(); err != nil {
+				return err
+			}
+			section, inTables = fields["id"], false
+		case strings.HasSuffix(first, ":") && fields["id"] != "":
+			if err := sendPeer(); err != nil {
+				return err
+			}
+			name, kind, _ := strings.Cut(fields["id"], "(")
+			if !strings.HasPrefix(kind, "local") {
+				peer, peerFields = name, fields
+			}
+			inTables = false
+		case strings.TrimSpace(line) == "shared tables:":
+			inTables, tableFields = true, map[string]string{}
+		case peer == "":
+		case !inTables:
+			for k, v := range fields {
+				peerFields[k] = v
+			}
+		default:
+			for k, v := range fields {
+				tableFields[k] = v
+			}
+			// The line of the stick table ends a shared table, its update
+			// field overriding that of the peer.
+			if !strings.HasPrefix(first, "table:") {
+				continue
+			}
+			for _, field := range []string{"update", "last_pushed", "last_acked", "last_get"} {
+				if err := sendPeerField(ch, metrics[field], tableFields, field, section, peer, fields["id"]); err != nil {
+					return err
+				}
+			}
+			tableFields = map[string]string{}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return sendPeer()
+}
+
+// sendPeerField sends m for the numeric field of fields, if it is present.
+func sendPeerField(ch chan<- prometheus.Metric, m metricInfo, fields map[string]string, field string, labels ...string) error {
+	value, ok := fields[field]
+	if !ok {
+		return nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s of peer %q: %w", field, labels[1], err)
+	}
+	m.send(ch, v, labels...)
+	return nil
+}
+
+// keyValues returns the key=value pairs of a line.
+func keyValues(line string) map[string]string {
+	res := map[string]string{}
+	for _, f := range strings.Fields(line) {
+		if k, v, ok := strings.Cut(f, "="); ok {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// parseHumanTime parses a duration as shown by HAProxy, e.g. "1d2h" or
+// "1m19s".
+func parseHumanTime(s string) (time.Duration, error) {
+	var days time.Duration
+	if d, rest, ok := strings.Cut(s, "d"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		days, s = time.Duration(n)*24*time.Hour, rest
+		if s == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	return days + d, err
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testPeers = `0x55deb0224320: [07/Dec/2018:12:49:51] id=sharedlb disabled=0 flags=0x3 resync_timeout=<PAST> task_calls=28
+  0x55deb022b540: id=tap(local,inactive) addr=127.0.0.1:10001 last_status=NONE last_hdshk=<NEVER>
+        reconnect=<NEVER> heartbeat=<NEVER> confirm=0 tx.hb=0 rx.hb=0 no_hbt=0 new_conn=0 proto_err=0 coll=0
+        flags=0x0
+        shared tables:
+          0x55deb0220980 local_id=1 remote_id=0 flags=0x0 remote_data=0x0
+              last_acked=0 last_pushed=0 last_get=0 teaching_origin=0 update=0
+              table:0x55deb022d6a0 id=tap update=3 localupdate=3 commitupdate=3 syncing=0
+  0x55deb022a440: id=lbtap(remote,active) addr=127.0.0.1:10000 last_status=ESTA last_hdshk=1m19s
+        reconnect=4s heartbeat=3s confirm=0 tx.hb=21 rx.hb=21 no_hbt=0 new_conn=2 proto_err=1 coll=0
+        flags=0x0 appstate=ESTA(0)
+        appctx:0x55deb028fba0 st0=7 st1=0 task_calls=14456 state=EST
+        shared tables:
+          0x55deb0220980 local_id=1 remote_id=1 flags=0x0 remote_data=0x65
+              last_acked=2 last_pushed=3 last_get=1 teaching_origin=0 update=2
+              table:0x55deb022d6a0 id=tap update=3 localupdate=3 commitupdate=3 syncing=0
+  0x55deb022a540: id=lbtap2(remote,inactive) addr=127.0.0.1:10002 last_status=CONN last_hdshk=<NEVER>
+        reconnect=1s heartbeat=<NEVER> confirm=0 tx.hb=0 rx.hb=0 no_hbt=0 new_conn=0 proto_err=0 coll=0
+`
+
+func TestParsePeers(t *testing.T) {
+	p := parsedRuntime{collector: runtimeCollectors["peers"], output: testPeers}
+	expected := `
+# HELP haproxy_peer_collisions_total Total number of connections closed because the peer connected at the same time.
+# TYPE haproxy_peer_collisions_total counter
+haproxy_peer_collisions_total{peer="lbtap",peers="sharedlb"} 0
+haproxy_peer_collisions_total{peer="lbtap2",peers="sharedlb"} 0
+# HELP haproxy_peer_connections_total Total number of connections to the peer.
+# TYPE haproxy_peer_connections_total counter
+haproxy_peer_connections_total{peer="lbtap",peers="sharedlb"} 2
+haproxy_peer_connections_total{peer="lbtap2",peers="sharedlb"} 0
+# HELP haproxy_peer_established Whether the connection to the peer is established (1 = established, 0 = not).
+# TYPE haproxy_peer_established gauge
+haproxy_peer_established{peer="lbtap",peers="sharedlb"} 1
+haproxy_peer_established{peer="lbtap2",peers="sharedlb"} 0
+# HELP haproxy_peer_last_handshake_age_seconds Time since the last handshake with the peer.
+# TYPE haproxy_peer_last_handshake_age_seconds gauge
+haproxy_peer_last_handshake_age_seconds{peer="lbtap",peers="sharedlb"} 79
+# HELP haproxy_peer_protocol_errors_total Total number of protocol errors of the peer.
+# TYPE haproxy_peer_protocol_errors_total counter
+haproxy_peer_protocol_errors_total{peer="lbtap",peers="sharedlb"} 1
+haproxy_peer_protocol_errors_total{peer="lbtap2",peers="sharedlb"} 0
+# HELP haproxy_peer_table_last_acked_update ID of the last update of the shared stick table acknowledged by the peer.
+# TYPE haproxy_peer_table_last_acked_update gauge
+haproxy_peer_table_last_acked_update{peer="lbtap",peers="sharedlb",table="tap"} 2
+# HELP haproxy_peer_table_last_pushed_update ID of the last update of the shared stick table pushed to the peer.
+# TYPE haproxy_peer_table_last_pushed_update gauge
+haproxy_peer_table_last_pushed_update{peer="lbtap",peers="sharedlb",table="tap"} 3
+# HELP haproxy_peer_table_last_received_update ID of the last update of the shared stick table received from the peer.
+# TYPE haproxy_peer_table_last_received_update gauge
+haproxy_peer_table_last_received_update{peer="lbtap",peers="sharedlb",table="tap"} 1
+# HELP haproxy_peer_table_update Current update ID of the shared stick table.
+# TYPE haproxy_peer_table_update gauge
+haproxy_peer_table_update{peer="lbtap",peers="sharedlb",table="tap"} 3
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseHumanTime(t *testing.T) {
+	tests := map[string]time.Duration{
+		"2s":    2 * time.Second,
+		"1m19s": 79 * time.Second,
+		"3h4m":  3*time.Hour + 4*time.Minute,
+		"1d":    24 * time.Hour,
+		"1d2h":  26 * time.Hour,
+	}
+	for s, want := range tests {
+		if have, err := parseHumanTime(s); err != nil || have != want {
+			t.Errorf("%s: want %v, have %v (%v)", s, want, have, err)
+		}
+	}
+	if _, err := parseHumanTime("xd"); err == nil {
+		t.Error("xd: want error, have none")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// profilingMetrics are the per-function metrics of the tasks activity of show
+// profiling, by column. The averages of the columns cpu_avg and lat_avg
+// follow from dividing the rates of the totals by the rate of the calls.
+var profilingMetrics = map[string]metricInfo{
+	"calls":   newTaskMetric("calls_total", "Total number of calls of the tasks running the function.", prometheus.CounterValue),
+	"cpu_tot": newTaskMetric("cpu_seconds_total", "Total CPU time spent in the tasks running the function.", prometheus.CounterValue),
+	"lat_tot": newTaskMetric("latency_seconds_total", "Total time the tasks running the function waited to be run after being woken up.", prometheus.CounterValue),
+}
+
+func newTaskMetric(metricName string, docString string, t prometheus.ValueType) metricInfo {
+	return newMetricInfo(prometheus.BuildFQName(namespace, "task", metricName), docString, t, []string{"function"}, nil)
+}
+
+// parseProfiling sends the metrics of the tasks activity of show profiling, a
+// table headed by "function calls cpu_tot ...". HAProxy only fills it while
+// profiling.tasks is enabled.
+func parseProfiling(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	var columns []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if columns == nil {
+			if len(fields) > 0 && fields[0] == "function" {
+				columns = fields
+			}
+			continue
+		}
+		// The table ends with an empty line.
+		if len(fields) == 0 {
+			break
+		}
+		if len(fields) != len(columns) {
+			return fmt.Errorf("invalid task activity %q", s.Text())
+		}
+		for i, column := range columns[1:] {
+			m, ok := metrics[column]
+			if !ok {
+				continue
+			}
+			v, err := parseProfilingValue(column, fields[i+1])
+			if err != nil {
+				return err
+			}
+			m.send(ch, v, fields[0])
+		}
+	}
+	return s.Err()
+}
+
+// parseProfilingValue returns the value of a column of the tasks activity.
+// Times are shown with a unit, e.g. "3.784us", and returned in seconds.
+func parseProfilingValue(column, value string) (float64, error) {
+	if column == "calls" {
+		return strconv.ParseFloat(value, 64)
+	}
+	if value == "-" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return d.Seconds(), nil
+}
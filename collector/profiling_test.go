@@ -0,0 +1,64 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testProfiling = `Per-task CPU profiling              : on            # set profiling tasks {on|auto|off}
+Memory usage profiling              : off           # set profiling memory {on|off}
+Tasks activity:
+  function                      calls   cpu_tot   cpu_avg   lat_tot   lat_avg
+  h1_io_cb                      3667    3.295ms   898.0ns   13.88ms   3.784us
+  process_stream                3570    4.521ms   1.266us   -         -
+
+`
+
+func TestParseProfiling(t *testing.T) {
+	p := parsedRuntime{collector: runtimeCollectors["profiling"], output: testProfiling}
+	expected := `
+# HELP haproxy_task_calls_total Total number of calls of the tasks running the function.
+# TYPE haproxy_task_calls_total counter
+haproxy_task_calls_total{function="h1_io_cb"} 3667
+haproxy_task_calls_total{function="process_stream"} 3570
+# HELP haproxy_task_cpu_seconds_total Total CPU time spent in the tasks running the function.
+# TYPE haproxy_task_cpu_seconds_total counter
+haproxy_task_cpu_seconds_total{function="h1_io_cb"} 0.003295
+haproxy_task_cpu_seconds_total{function="process_stream"} 0.004521
+# HELP haproxy_task_latency_seconds_total Total time the tasks running the function waited to be run after being woken up.
+# TYPE haproxy_task_latency_seconds_total counter
+haproxy_task_latency_seconds_total{function="h1_io_cb"} 0.01388
+haproxy_task_latency_seconds_total{function="process_stream"} 0
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseProfilingInvalid(t *testing.T) {
+	for _, output := range []string{
+		"  function calls cpu_tot cpu_avg lat_tot lat_avg\n  h1_io_cb 1 2ms\n",
+		"  function calls cpu_tot cpu_avg lat_tot lat_avg\n  h1_io_cb 1 2mz 1us 0ns 0ns\n",
+	} {
+		c := runtimeCollectors["profiling"]
+		if err := c.parse(strings.NewReader(output), c.metrics, make(chan prometheus.Metric, 10)); err == nil {
+			t.Errorf("%q: want error, have none", output)
+		}
+	}
+}
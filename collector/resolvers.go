@@ -0,0 +1,84 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// resolverMetrics are the per-nameserver metrics of show resolvers, by field.
+var resolverMetrics = map[string]metricInfo{
+	"sent":        newResolverMetric("queries_total", "Total number of DNS queries sent to the nameserver.", prometheus.CounterValue),
+	"snd_error":   newResolverMetric("query_errors_total", "Total number of DNS queries which couldn't be sent to the nameserver.", prometheus.CounterValue),
+	"valid":       newResolverMetric("valid_responses_total", "Total number of valid responses.", prometheus.CounterValue),
+	"update":      newResolverMetric("update_responses_total", "Total number of valid responses which updated the address of a server.", prometheus.CounterValue),
+	"cname":       newResolverMetric("cname_responses_total", "Total number of CNAME responses.", prometheus.CounterValue),
+	"cname_error": newResolverMetric("cname_error_responses_total", "Total number of CNAME responses with an error.", prometheus.CounterValue),
+	"any_err":     newResolverMetric("empty_responses_total", "Total number of responses without a usable record.", prometheus.CounterValue),
+	"nx":          newResolverMetric("nxdomain_responses_total", "Total number of NXDOMAIN responses.", prometheus.CounterValue),
+	"timeout":     newResolverMetric("timeouts_total", "Total number of DNS queries without a response in time.", prometheus.CounterValue),
+	"refused":     newResolverMetric("refused_responses_total", "Total number of responses refusing the query.", prometheus.CounterValue),
+	"other":       newResolverMetric("other_error_responses_total", "Total number of responses with another error.", prometheus.CounterValue),
+	"invalid":     newResolverMetric("invalid_responses_total", "Total number of invalid responses.", prometheus.CounterValue),
+	"too_big":     newResolverMetric("too_big_responses_total", "Total number of responses too big to be processed.", prometheus.CounterValue),
+	"truncated":   newResolverMetric("truncated_responses_total", "Total number of truncated responses.", prometheus.CounterValue),
+	"outdated":    newResolverMetric("outdated_responses_total", "Total number of responses arriving after another nameserver answered.", prometheus.CounterValue),
+}
+
+func newResolverMetric(metricName string, docString string, t prometheus.ValueType) metricInfo {
+	return newMetricInfo(prometheus.BuildFQName(namespace, "resolver", metricName), docString, t, []string{"resolvers", "nameserver"}, nil)
+}
+
+// parseResolvers sends the metrics of the output of show resolvers, which
+// lists the counters of every nameserver of every resolvers section:
+//
+//	Resolvers section mydns
+//	 nameserver dns1:
+//	  sent:        8
+//	  ...
+func parseResolvers(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	var resolvers, nameserver string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if section, ok := strings.CutPrefix(line, "Resolvers section "); ok {
+			resolvers, nameserver = section, ""
+			continue
+		}
+		if ns, ok := strings.CutPrefix(line, "nameserver "); ok {
+			nameserver = strings.TrimSuffix(ns, ":")
+			continue
+		}
+		field, value, ok := strings.Cut(line, ":")
+		if !ok || nameserver == "" {
+			continue
+		}
+		m, ok := metrics[field]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s of nameserver %q: %w", field, nameserver, err)
+		}
+		m.send(ch, v, resolvers, nameserver)
+	}
+	return s.Err()
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testResolvers = `Resolvers section mydns
+ nameserver dns1:
+  sent:        8
+  snd_error:   0
+  valid:       4
+  timeout:     4
+ nameserver dns2:
+  sent:        8
+  snd_error:   1
+  valid:       7
+  timeout:     0
+`
+
+func TestParseResolvers(t *testing.T) {
+	p := parsedRuntime{collector: runtimeCollectors["resolvers"], output: testResolvers}
+	expected := `
+# HELP haproxy_resolver_queries_total Total number of DNS queries sent to the nameserver.
+# TYPE haproxy_resolver_queries_total counter
+haproxy_resolver_queries_total{nameserver="dns1",resolvers="mydns"} 8
+haproxy_resolver_queries_total{nameserver="dns2",resolvers="mydns"} 8
+# HELP haproxy_resolver_query_errors_total Total number of DNS queries which couldn't be sent to the nameserver.
+# TYPE haproxy_resolver_query_errors_total counter
+haproxy_resolver_query_errors_total{nameserver="dns1",resolvers="mydns"} 0
+haproxy_resolver_query_errors_total{nameserver="dns2",resolvers="mydns"} 1
+# HELP haproxy_resolver_timeouts_total Total number of DNS queries without a response in time.
+# TYPE haproxy_resolver_timeouts_total counter
+haproxy_resolver_timeouts_total{nameserver="dns1",resolvers="mydns"} 4
+haproxy_resolver_timeouts_total{nameserver="dns2",resolvers="mydns"} 0
+# HELP haproxy_resolver_valid_responses_total Total number of valid responses.
+# TYPE haproxy_resolver_valid_responses_total counter
+haproxy_resolver_valid_responses_total{nameserver="dns1",resolvers="mydns"} 4
+haproxy_resolver_valid_responses_total{nameserver="dns2",resolvers="mydns"} 7
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
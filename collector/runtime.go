@@ -0,0 +1,124 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// runtimeCollector exports the output of a command of the HAProxy runtime API,
+// e.g. show activity. Runtime collectors are enabled by name with
+// --haproxy.runtime-collectors, and need a stats socket.
+type runtimeCollector struct {
+	name    string // Only set for the collectors of an Exporter.
+	command string
+	// metrics are the exported metrics, keyed as parse looks them up.
+	metrics map[string]metricInfo
+	// parse sends the metrics for the output of the command.
+	parse func(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error
+	fetch Fetcher // Only set for the collectors of an Exporter.
+}
+
+// runtimeCollectors are the available runtime collectors, by name.
+var runtimeCollectors = map[string]runtimeCollector{
+	"activity":      {command: "show activity", metrics: activityMetrics, parse: parseActivity},
+	"profiling":     {command: "show profiling", metrics: profilingMetrics, parse: parseProfiling},
+	"tables":        {command: "show table", metrics: stickTableMetrics, parse: parseStickTables},
+	"resolvers":     {command: "show resolvers", metrics: resolverMetrics, parse: parseResolvers},
+	"peers":         {command: "show peers", metrics: peerMetrics, parse: parsePeers},
+	"errors":        {command: "show errors", metrics: capturedErrorMetrics, parse: parseCapturedErrors},
+	"servers-state": {command: "show servers state", metrics: serversStateMetrics, parse: parseServersState},
+	"servers-conn":  {command: "show servers conn", metrics: serversConnMetrics, parse: parseServersConn},
+	"maps":          {command: "show map", metrics: mapMetrics, parse: parsePatternLists},
+	"acls":          {command: "show acl", metrics: aclMetrics, parse: parsePatternLists},
+	"startup-logs":  {command: "show startup-logs", metrics: startupLogMetrics, parse: parseStartupLogs},
+}
+
+// RuntimeCollectorNames returns the names of the available runtime collectors.
+func RuntimeCollectorNames() string {
+	names := make([]string, 0, len(runtimeCollectors))
+	for name := range runtimeCollectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// newRuntimeCollectors returns the runtime collectors of the comma-separated
+// list names, fetching their commands from the stats socket of the scrape URI
+// u.
+func newRuntimeCollectors(names string, u *url.URL, opts ExporterOpts) ([]runtimeCollector, error) {
+	var scheme, address string
+	switch {
+	case opts.StatFetcher != nil:
+	case u.Scheme == "unix":
+		scheme, address = "unix", u.Path
+	case u.Scheme == "tcp":
+		scheme, address = "tcp", u.Host
+	}
+
+	var res []runtimeCollector
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		c, ok := runtimeCollectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown runtime collector %q, available are %s", name, RuntimeCollectorNames())
+		}
+		if address == "" {
+			return nil, fmt.Errorf("runtime collector %q needs a stats socket to scrape", name)
+		}
+		metrics := make(map[string]metricInfo, len(c.metrics))
+		for key, m := range c.metrics {
+			metrics[key] = m.withConstLabels(opts.ConstLabels).withNamingScheme(opts.NamingScheme)
+		}
+		c.metrics = metrics
+		c.name = name
+		c.fetch = SocketFetcher(scheme, address, c.command+"\n", opts.Timeout)
+		if opts.Cache != nil {
+			c.fetch = opts.Cache.wrap(u.String()+" "+c.command+"\n", c.fetch, nil)
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+func (c runtimeCollector) describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		m.describe(ch)
+	}
+}
+
+// collect runs the command and sends its metrics. Errors fetching the output
+// are returned, errors parsing it are wrapped in a ParseError.
+func (c runtimeCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	r, err := c.fetch.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := c.parse(r, c.metrics, ch); err != nil {
+		return &ParseError{Err: fmt.Errorf("%s: %w", c.command, err)}
+	}
+	return nil
+}
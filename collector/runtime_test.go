@@ -0,0 +1,168 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newRuntimeSocket serves the responses to commands of the runtime API on
+// the unix socket file.
+func newRuntimeSocket(file string, responses map[string]string) (io.Closer, error) {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	l, err := net.Listen("unix", file)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				cmd, err := bufio.NewReader(c).ReadString('\n')
+				if err != nil {
+					return
+				}
+				if resp, ok := responses[strings.TrimSuffix(cmd, "\n")]; ok {
+					c.Write([]byte(resp))
+				} else {
+					c.Write([]byte("Unknown command.\n"))
+				}
+			}(c)
+		}
+	}()
+	return l, nil
+}
+
+// parsedRuntime is a collector of the metrics parsed by a runtime collector
+// from output.
+type parsedRuntime struct {
+	collector runtimeCollector
+	output    string
+}
+
+func (p parsedRuntime) Describe(ch chan<- *prometheus.Desc) {
+	p.collector.describe(ch)
+}
+
+func (p parsedRuntime) Collect(ch chan<- prometheus.Metric) {
+	if err := p.collector.parse(strings.NewReader(p.output), p.collector.metrics, ch); err != nil {
+		panic(err)
+	}
+}
+
+func TestRuntimeCollectors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+	srv, err := newRuntimeSocket(testSocket, map[string]string{
+		"show stat":     "test,BACKEND,0,0,0,0,0,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,,,0,,1,1,1,,0,,1,0,,0,,,,0,0,0,0,0,0,0,,,,0,0,,,,,,,,,,,\n",
+		"show info":     testInfo,
+		"show activity": "thread_id: 1 (1..2)\nloops: 30 [ 10 20 ]\n",
+	})
+	if err != nil {
+		t.Fatalf("can't start test server: %v", err)
+	}
+	defer srv.Close()
+
+	e, err := NewExporter("unix:"+testSocket, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, RuntimeCollectors: "activity", ConstLabels: prometheus.Labels{"haproxy": "a"}}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_thread_loops_total Total number of polling loops.
+# TYPE haproxy_thread_loops_total counter
+haproxy_thread_loops_total{haproxy="a",thread="1"} 10
+haproxy_thread_loops_total{haproxy="a",thread="2"} 20
+# HELP haproxy_up Was the last scrape of HAProxy successful.
+# TYPE haproxy_up gauge
+haproxy_up{haproxy="a"} 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_thread_loops_total", "haproxy_up"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRuntimeCollectorFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+	srv, err := newRuntimeSocket(testSocket, map[string]string{
+		"show stat":     "test,BACKEND,0,0,0,0,0,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,,,0,,1,1,1,,0,,1,0,,0,,,,0,0,0,0,0,0,0,,,,0,0,,,,,,,,,,,\n",
+		"show info":     testInfo,
+		"show activity": "thread_id: 1 (1..2)\nloops: 30 [ 10 20 ]\n",
+		"show table":    "# table: foo, type: ip, size:bogus, used:0\n",
+	})
+	if err != nil {
+		t.Fatalf("can't start test server: %v", err)
+	}
+	defer srv.Close()
+
+	e, err := NewExporter("unix:"+testSocket, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, RuntimeCollectors: "activity,tables"}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_backend_up Current health status of the backend (1 = UP, 0 = DOWN).
+# TYPE haproxy_backend_up gauge
+haproxy_backend_up{backend="test"} 1
+# HELP haproxy_exporter_runtime_collector_success Whether the last run of a runtime collector succeeded.
+# TYPE haproxy_exporter_runtime_collector_success gauge
+haproxy_exporter_runtime_collector_success{collector="activity"} 1
+haproxy_exporter_runtime_collector_success{collector="tables"} 0
+# HELP haproxy_up Was the last scrape of HAProxy successful.
+# TYPE haproxy_up gauge
+haproxy_up 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_backend_up", "haproxy_exporter_runtime_collector_success", "haproxy_up"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewRuntimeCollectorsInvalid(t *testing.T) {
+	tests := []struct {
+		uri, collectors string
+		err             string
+	}{
+		{uri: "unix:/run/haproxy.sock", collectors: "activity,unknown", err: `unknown runtime collector "unknown"`},
+		{uri: "http://localhost/;csv", collectors: "activity", err: `runtime collector "activity" needs a stats socket`},
+	}
+
+	for _, tt := range tests {
+		_, err := NewExporter(tt.uri, ExporterOpts{RuntimeCollectors: tt.collectors}, log.NewNopLogger())
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s with %q: want error containing %q, have %v", tt.uri, tt.collectors, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package collector
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// budgetCPURatio bounds the CPU time of collecting the generated stats, as a
+// multiple of the CPU time of merely reading them with encoding/csv. Both
+// scale alike with the speed of the machine, and with the race detector.
+const budgetCPURatio = 20
+
+// cpuTime returns the user and system CPU time used by the process.
+func cpuTime(t testing.TB) time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		t.Fatal(err)
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+// bestCPUTime returns the least CPU time taken by f in a few runs, to leave
+// out e.g. a garbage collection of the garbage of an earlier test.
+func bestCPUTime(t testing.TB, f func()) time.Duration {
+	var best time.Duration
+	for i := 0; i < 3; i++ {
+		start := cpuTime(t)
+		f()
+		if d := cpuTime(t) - start; i == 0 || d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+func TestScaleCPUBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("collects the stats of 50000 servers")
+	}
+	stats := generateStats(500, 100)
+	rows := 500 * 102
+	e := newScaleExporter(t, stats, ExporterOpts{})
+	collect(e)
+
+	used := bestCPUTime(t, func() { collect(e) })
+	reference := bestCPUTime(t, func() {
+		r := csv.NewReader(bytes.NewReader(stats))
+		r.Comment, r.FieldsPerRecord = '#', -1
+		for {
+			if _, err := r.Read(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	ratio := float64(used) / float64(reference)
+
+	t.Logf("%d rows: %v CPU time/row, %.1f times that of reading the CSV",
+		rows, used/time.Duration(rows), ratio)
+	if ratio > budgetCPURatio {
+		t.Errorf("CPU time of %v per row, %.1f times that of reading the CSV, exceeds the budget of %d times",
+			used/time.Duration(rows), ratio, budgetCPURatio)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scaleFields is the number of columns of the generated stats, those of
+// HAProxy 2.8 up to uweight.
+const scaleFields = 100
+
+// generateStats returns stats as HAProxy 2.8 reports them for the given
+// number of proxies, each with a frontend, a backend and serversPerProxy
+// servers with health checks. Counters and gauges vary between rows.
+func generateStats(proxies, serversPerProxy int) []byte {
+	var b bytes.Buffer
+	b.WriteString("# " + strings.Join(csvFieldNames[:scaleFields], ",") + ",\n")
+	row := make([]string, scaleFields)
+	n := 0
+	write := func(pxname, svname, typ string, set map[int]string) {
+		n++
+		for i := range row {
+			row[i] = strconv.Itoa((n*31 + i*17) % 10007)
+		}
+		// Limits and fields of other row types are empty.
+		for _, i := range []int{slimField, qlimitField, 29, 31, rateLimField, 37, 62, 63, 64, 65, 66, 70, 71, 72, 74, 80, 81, 82, 83, 84, 85, 86, 87} {
+			row[i] = ""
+		}
+		row[pxnameField], row[svnameField], row[typeField] = pxname, svname, typ
+		row[pidField] = "1"
+		for i, v := range set {
+			row[i] = v
+		}
+		b.WriteString(strings.Join(row, ",") + ",\n")
+	}
+	for p := 0; p < proxies; p++ {
+		name := fmt.Sprintf("service-%d", p)
+		write(name, "FRONTEND", "0", map[int]string{statusField: "OPEN", checkStatusField: "", modeField: "http", algoField: "", addrField: "", 38: ""})
+		for s := 0; s < serversPerProxy; s++ {
+			write(name, fmt.Sprintf("%s-%d", name, s), "2", map[int]string{
+				statusField: "UP", checkStatusField: "L7OK", 37: "200", modeField: "http", algoField: "",
+				addrField: fmt.Sprintf("10.%d.%d.%d:8080", p/256%256, p%256, s%256), sidField: strconv.Itoa(s + 1),
+			})
+		}
+		write(name, "BACKEND", "1", map[int]string{statusField: "UP", checkStatusField: "", modeField: "http", algoField: "roundrobin", addrField: "", 38: ""})
+	}
+	return b.Bytes()
+}
+
+// newScaleExporter returns an Exporter of the given stats, fetched without
+// HTTP to measure the exporter alone, with the given further options.
+func newScaleExporter(t testing.TB, stats []byte, opts ExporterOpts) *Exporter {
+	opts.ServerMetrics, opts.Timeout = serverMetrics, time.Minute
+	opts.StatFetcher = FetcherFunc(func(ctx context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(stats)), nil
+	})
+	e, err := NewExporter("ssh://haproxy.example.com", opts, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// collect collects the metrics of e, returning their number.
+func collect(e *Exporter) int {
+	ch := make(chan prometheus.Metric, 1024)
+	n := make(chan int)
+	go func() {
+		metrics := 0
+		for range ch {
+			metrics++
+		}
+		n <- metrics
+	}()
+	e.Collect(ch)
+	close(ch)
+	return <-n
+}
+
+// The budget of collecting the generated stats of 50000 servers, per row.
+// See "Performance budget" in README.md. The CPU time is checked relative to
+// reading the stats by TestScaleCPUBudget.
+const (
+	budgetAllocsPerRow = 130
+	budgetBytesPerRow  = 8 << 10
+	// budgetRetainedPerRow bounds the heap retained by the exporter after a
+	// scrape, e.g. the rows kept for the JSON API.
+	budgetRetainedPerRow = 3 << 10
+)
+
+func TestScaleBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("collects the stats of 50000 servers")
+	}
+	stats := generateStats(500, 100)
+	rows := 500 * 102
+	var base, before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&base)
+	e := newScaleExporter(t, stats, ExporterOpts{})
+	// AllocsPerRun warms up, e.g. the descriptors of the processes, with a
+	// collect of its own first.
+	var metrics int
+	allocs := uint64(testing.AllocsPerRun(1, func() { metrics = collect(e) }))
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	collect(e)
+	runtime.ReadMemStats(&after)
+	allocated := after.TotalAlloc - before.TotalAlloc
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	retained := int64(after.HeapAlloc) - int64(base.HeapAlloc)
+	runtime.KeepAlive(e)
+	runtime.KeepAlive(stats)
+
+	t.Logf("%d rows, %d metrics: %d allocs/row, %d B/row, %d B/row retained",
+		rows, metrics, allocs/uint64(rows), allocated/uint64(rows), retained/int64(rows))
+	if metrics < rows*50 {
+		t.Fatalf("want at least 50 metrics per row, have %d for %d rows", metrics, rows)
+	}
+	if perRow := allocs / uint64(rows); perRow > budgetAllocsPerRow {
+		t.Errorf("%d allocations per row exceed the budget of %d", perRow, budgetAllocsPerRow)
+	}
+	if perRow := allocated / uint64(rows); perRow > budgetBytesPerRow {
+		t.Errorf("%d bytes allocated per row exceed the budget of %d", perRow, budgetBytesPerRow)
+	}
+	if perRow := retained / int64(rows); perRow > budgetRetainedPerRow {
+		t.Errorf("%d bytes retained per row exceed the budget of %d", perRow, budgetRetainedPerRow)
+	}
+}
+
+func BenchmarkScale(b *testing.B) {
+	for _, servers := range []int{1000, 10000, 50000} {
+		stats := generateStats(servers/100, 100)
+		for _, stream := range []bool{false, true} {
+			b.Run(fmt.Sprintf("servers=%d/stream=%t", servers, stream), func(b *testing.B) {
+				e := newScaleExporter(b, stats, ExporterOpts{StreamStats: stream})
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					collect(e)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const showStatTypedCmd = "show stat typed\n"
+
+// schemaMetrics exports the columns of the stats unknown to the exporter, by
+// the nature HAProxy gives them in show stat typed. The JSON schema of show
+// schema json only describes the natures, not which fields have them.
+type schemaMetrics struct {
+	fetch       Fetcher
+	constLabels prometheus.Labels
+	labels      map[string][]string // The variable labels, by section.
+	// header is that of the stats natures were fetched for. A new header
+	// means that HAProxy was upgraded, and natures are fetched anew.
+	header  string
+	natures map[string]byte // Of the numeric fields, by name.
+	// metrics are the metrics generated so far, protected by mutex for the
+	// parse workers.
+	mutex   sync.Mutex
+	metrics map[schemaKey]metricInfo
+}
+
+// schemaKey identifies a metric of schemaMetrics.
+type schemaKey struct {
+	section, process, name string
+}
+
+func newSchemaMetrics(fetch Fetcher, constLabels prometheus.Labels, labels rowLabels) *schemaMetrics {
+	return &schemaMetrics{
+		fetch:       fetch,
+		constLabels: constLabels,
+		labels: map[string][]string{
+			"frontend": append(append([]string(nil), frontendLabelNames...), labels.names(false)...),
+			"backend":  append(append([]string(nil), backendLabelNames...), labels.names(false)...),
+			"server":   append(append([]string(nil), serverLabelNames...), labels.names(true)...),
+		},
+		metrics: map[schemaKey]metricInfo{},
+	}
+}
+
+// update fetches the natures of the fields if the header of the stats changed.
+func (s *schemaMetrics) update(ctx context.Context, header []string) error {
+	h := strings.Join(header, ",")
+	if s.natures != nil && h == s.header {
+		return nil
+	}
+	r, err := s.fetch.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	natures, err := parseStatTyped(r)
+	if err != nil {
+		return &ParseError{Err: fmt.Errorf("show stat typed: %w", err)}
+	}
+	s.header, s.natures = h, natures
+	return nil
+}
+
+// export sends the metrics of the unknown columns of a canonical row of the
+// given section, and of process if not empty.
+func (s *schemaMetrics) export(ch chan<- prometheus.Metric, csvRow []string, cols columns, section, process string, row *rowLabelPairs) {
+	for i := len(csvFieldNames); i < len(csvRow) && i < len(cols.names); i++ {
+		if csvRow[i] == "" {
+			continue
+		}
+		m, ok := s.metric(section, process, cols.names[i])
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(csvRow[i], 64)
+		if err != nil {
+			continue
+		}
+		m.sendRow(ch, row, v)
+	}
+}
+
+// metric returns the metric of the column name of section and process, or
+// false if the column isn't numeric.
+func (s *schemaMetrics) metric(section, process, name string) (metricInfo, bool) {
+	key := schemaKey{section, process, name}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if m, ok := s.metrics[key]; ok {
+		return m, true
+	}
+	nature, ok := s.natures[name]
+	if !ok {
+		return metricInfo{}, false
+	}
+	metricName, t := schemaMetricName(name, nature)
+	m := newMetricInfo(prometheus.BuildFQName(namespace, section, metricName), fmt.Sprintf("Value of the %s field of the HAProxy stats.", name), t, s.labels[section], s.constLabels)
+	if process != "" {
+		m = m.withConstLabels(prometheus.Labels{"process": process})
+	}
+	s.metrics[key] = m
+	return m, true
+}
+
+// schemaMetricName returns the name and type of the metric of a field of the
+// given nature. Counters get the suffix _total, other numeric natures, e.g.
+// maxima, rates or durations, are gauges.
+func schemaMetricName(field string, nature byte) (string, prometheus.ValueType) {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, field)
+	if nature == 'C' {
+		return name + "_total", prometheus.CounterValue
+	}
+	return name, prometheus.GaugeValue
+}
+
+// parseStatTyped returns the natures of the numeric fields of show stat
+// typed, by name. Its lines look like
+//
+//	F.2.0.4.scur.1:MGP:u32:0
+//
+// with the object type, proxy and server ID, position, name and process of
+// the field, followed by its origin, nature and scope, its type and value.
+// Names, keys and outputs are left out, as are strings.
+func parseStatTyped(r io.Reader) (map[string]byte, error) {
+	natures := map[string]byte{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 4)
+		if len(parts) != 4 || len(parts[1]) < 2 {
+			return nil, fmt.Errorf("invalid typed field %q", line)
+		}
+		key := strings.Split(parts[0], ".")
+		if len(key) != 6 {
+			return nil, fmt.Errorf("invalid typed field %q", line)
+		}
+		nature := parts[1][1]
+		if parts[2] == "str" || strings.IndexByte("NKO", nature) >= 0 {
+			continue
+		}
+		natures[key[4]] = nature
+	}
+	return natures, s.Err()
+}
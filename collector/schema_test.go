@@ -0,0 +1,105 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testStatTyped = `F.2.0.0.pxname.1:KNSN:str:fe
+F.2.0.4.scur.1:MGP:u32:0
+F.2.0.100.h9_req_tot.1:MCP:u64:12
+F.2.0.101.h9_conn_max.1:MMP:u32:3
+F.2.0.102.h9_proto.1:COP:str:h9
+
+S.3.1.100.h9_req_tot.1:MCP:u64:5
+`
+
+func TestParseStatTyped(t *testing.T) {
+	natures, err := parseStatTyped(strings.NewReader(testStatTyped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]byte{"scur": 'G', "h9_req_tot": 'C', "h9_conn_max": 'M'}
+	if !reflect.DeepEqual(want, natures) {
+		t.Errorf("want natures %q, have %q", want, natures)
+	}
+
+	if _, err := parseStatTyped(strings.NewReader("F.2.0.4.scur:MGP:u32:0\n")); err == nil {
+		t.Error("want error for field without process")
+	}
+}
+
+func TestSchemaMetrics(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+	header := append(append([]string(nil), csvFieldNames...), "h9_req_tot", "h9_conn_max", "h9_proto")
+	row := func(pxname, svname, typ, reqTot, connMax string) string {
+		fields := make([]string, len(header))
+		fields[pxnameField], fields[svnameField], fields[typeField], fields[statusField] = pxname, svname, typ, "UP"
+		fields[len(header)-3], fields[len(header)-2], fields[len(header)-1] = reqTot, connMax, "h9"
+		return strings.Join(fields, ",") + "\n"
+	}
+	srv, err := newRuntimeSocket(testSocket, map[string]string{
+		"show stat":       "# " + strings.Join(header, ",") + "\n" + row("fe", "FRONTEND", "0", "12", "3") + row("be", "srv", "2", "5", ""),
+		"show info":       testInfo,
+		"show stat typed": testStatTyped,
+	})
+	if err != nil {
+		t.Fatalf("can't start test server: %v", err)
+	}
+	defer srv.Close()
+
+	e, err := NewExporter("unix:"+testSocket, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, SchemaMetrics: true}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The generated metrics aren't described, which pedantic registries
+	// reject.
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(e)
+
+	expected := `
+# HELP haproxy_frontend_h9_conn_max Value of the h9_conn_max field of the HAProxy stats.
+# TYPE haproxy_frontend_h9_conn_max gauge
+haproxy_frontend_h9_conn_max{frontend="fe"} 3
+# HELP haproxy_frontend_h9_req_tot_total Value of the h9_req_tot field of the HAProxy stats.
+# TYPE haproxy_frontend_h9_req_tot_total counter
+haproxy_frontend_h9_req_tot_total{frontend="fe"} 12
+# HELP haproxy_server_h9_req_tot_total Value of the h9_req_tot field of the HAProxy stats.
+# TYPE haproxy_server_h9_req_tot_total counter
+haproxy_server_h9_req_tot_total{backend="be",server="srv"} 5
+`
+	names := []string{"haproxy_frontend_h9_conn_max", "haproxy_frontend_h9_proto", "haproxy_frontend_h9_req_tot_total", "haproxy_server_h9_conn_max", "haproxy_server_h9_req_tot_total"}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSchemaMetricsNeedSocket(t *testing.T) {
+	_, err := NewExporter("http://localhost/;csv", ExporterOpts{SchemaMetrics: true}, log.NewNopLogger())
+	if err == nil || !strings.Contains(err.Error(), "need a stats socket") {
+		t.Errorf("want error about the stats socket, have %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serversConnMetrics are the connection pool metrics of show servers conn, by
+// column. They are named apart from the metrics of the stats of HAProxy 2.4
+// and later, which have some of them as well.
+var serversConnMetrics = map[string]metricInfo{
+	"purge_delay":  fromMilliseconds(newServerMetric("pool_purge_delay_seconds", "Delay between purges of idle connections.", prometheus.GaugeValue, nil)),
+	"used_cur":     newServerMetric("pool_used_connections", "Current number of connections in use.", prometheus.GaugeValue, nil),
+	"used_max":     newServerMetric("pool_used_connections_max", "Maximum number of connections in use since the last purge.", prometheus.GaugeValue, nil),
+	"need_est":     newServerMetric("pool_needed_connections_estimate", "Estimated number of connections needed.", prometheus.GaugeValue, nil),
+	"unsafe_nb":    newServerMetric("pool_unsafe_idle_connections", "Current number of unsafe idle connections.", prometheus.GaugeValue, nil),
+	"safe_nb":      newServerMetric("pool_safe_idle_connections", "Current number of safe idle connections.", prometheus.GaugeValue, nil),
+	"idle_lim":     newServerMetric("pool_idle_connections_limit", "Configured limit on idle connections.", prometheus.GaugeValue, nil),
+	"idle_cur":     newServerMetric("pool_idle_connections", "Current number of idle connections.", prometheus.GaugeValue, nil),
+	"idle_per_thr": newMetricInfo(prometheus.BuildFQName(namespace, "server", "pool_thread_idle_connections"), "Current number of idle connections of a thread.", prometheus.GaugeValue, append(serverLabelNames, "thread"), nil),
+}
+
+// parseServersConn sends the metrics of the output of show servers conn, a
+// table headed by "# bkname/svname bkid/svid addr port - purge_delay ...".
+// Its last column, e.g. idle_per_thr[4], has a value for every thread. Limits
+// of -1 are left out, as there is none.
+func parseServersConn(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	var columns []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) > 1 && fields[0] == "#" {
+			columns = fields[1:]
+			continue
+		}
+		if columns == nil || len(fields) < len(columns) {
+			continue
+		}
+		backend, server, _ := strings.Cut(fields[0], "/")
+		for i, column := range columns {
+			column, _, perThread := strings.Cut(column, "[")
+			m, ok := metrics[column]
+			if !ok {
+				continue
+			}
+			values := fields[i : i+1]
+			if perThread {
+				values = fields[i:]
+			}
+			for thread, value := range values {
+				v, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return fmt.Errorf("invalid %s of server %q: %w", column, fields[0], err)
+				}
+				switch {
+				case perThread:
+					m.send(ch, v, backend, server, strconv.Itoa(thread+1))
+				case v >= 0:
+					m.send(ch, v, backend, server)
+				}
+			}
+		}
+	}
+	return s.Err()
+}
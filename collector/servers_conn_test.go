@@ -0,0 +1,59 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testServersConn = `# bkname/svname bkid/svid addr port - purge_delay used_cur used_max need_est unsafe_nb safe_nb idle_lim idle_cur idle_per_thr[2]
+be/srv1 3/1 127.0.0.1 8080 - 5000 2 4 3 1 2 -1 3 1 2
+`
+
+func TestParseServersConn(t *testing.T) {
+	p := parsedRuntime{collector: runtimeCollectors["servers-conn"], output: testServersConn}
+	expected := `
+# HELP haproxy_server_pool_idle_connections Current number of idle connections.
+# TYPE haproxy_server_pool_idle_connections gauge
+haproxy_server_pool_idle_connections{backend="be",server="srv1"} 3
+# HELP haproxy_server_pool_needed_connections_estimate Estimated number of connections needed.
+# TYPE haproxy_server_pool_needed_connections_estimate gauge
+haproxy_server_pool_needed_connections_estimate{backend="be",server="srv1"} 3
+# HELP haproxy_server_pool_purge_delay_seconds Delay between purges of idle connections.
+# TYPE haproxy_server_pool_purge_delay_seconds gauge
+haproxy_server_pool_purge_delay_seconds{backend="be",server="srv1"} 5
+# HELP haproxy_server_pool_safe_idle_connections Current number of safe idle connections.
+# TYPE haproxy_server_pool_safe_idle_connections gauge
+haproxy_server_pool_safe_idle_connections{backend="be",server="srv1"} 2
+# HELP haproxy_server_pool_thread_idle_connections Current number of idle connections of a thread.
+# TYPE haproxy_server_pool_thread_idle_connections gauge
+haproxy_server_pool_thread_idle_connections{backend="be",server="srv1",thread="1"} 1
+haproxy_server_pool_thread_idle_connections{backend="be",server="srv1",thread="2"} 2
+# HELP haproxy_server_pool_unsafe_idle_connections Current number of unsafe idle connections.
+# TYPE haproxy_server_pool_unsafe_idle_connections gauge
+haproxy_server_pool_unsafe_idle_connections{backend="be",server="srv1"} 1
+# HELP haproxy_server_pool_used_connections Current number of connections in use.
+# TYPE haproxy_server_pool_used_connections gauge
+haproxy_server_pool_used_connections{backend="be",server="srv1"} 2
+# HELP haproxy_server_pool_used_connections_max Maximum number of connections in use since the last purge.
+# TYPE haproxy_server_pool_used_connections_max gauge
+haproxy_server_pool_used_connections_max{backend="be",server="srv1"} 4
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
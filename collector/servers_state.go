@@ -0,0 +1,90 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serversStateMetrics are the metrics of show servers state, by column. Both
+// states are state sets, 1 for the states a server is in.
+var serversStateMetrics = map[string]metricInfo{
+	"srv_op_state":    newMetricInfo(prometheus.BuildFQName(namespace, "server", "operational_state"), "Operational state of the server, 1 for the state it is in.", prometheus.GaugeValue, append(serverLabelNames, "state"), nil),
+	"srv_admin_state": newMetricInfo(prometheus.BuildFQName(namespace, "server", "admin_state"), "Administrative states of the server, 1 for those it is in, e.g. maint_cli for maintenance set on the CLI and maint_config for servers disabled in the configuration.", prometheus.GaugeValue, append(serverLabelNames, "state"), nil),
+	"srv_check_addr":  newMetricInfo(prometheus.BuildFQName(namespace, "server", "check_info"), "Address and port of the health checks of the server.", prometheus.GaugeValue, append(serverLabelNames, "address", "port"), nil),
+}
+
+// serverOpStates are the values of srv_op_state.
+var serverOpStates = []string{"stopped", "starting", "running", "stopping"}
+
+// serverAdminStates are the flags of srv_admin_state, the state of bit i
+// being at index i.
+var serverAdminStates = []string{"maint_cli", "maint_inherited", "maint_config", "drain_cli", "drain_inherited", "maint_resolution", "maint_hostname"}
+
+// parseServersState sends the metrics of the output of show servers state, a
+// version line followed by a table headed by "# be_id be_name srv_id ...".
+func parseServersState(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	var columns map[string]int
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if header, ok := strings.CutPrefix(line, "# "); ok {
+			columns = map[string]int{}
+			for i, name := range strings.Fields(header) {
+				columns[name] = i
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if columns == nil || len(fields) < len(columns) {
+			continue
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return fields[i]
+			}
+			return ""
+		}
+		backend, server := field("be_name"), field("srv_name")
+
+		if v := field("srv_op_state"); v != "" {
+			state, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid srv_op_state of server %q: %w", server, err)
+			}
+			for i, s := range serverOpStates {
+				metrics["srv_op_state"].send(ch, boolToFloat(i == state), backend, server, s)
+			}
+		}
+		if v := field("srv_admin_state"); v != "" {
+			flags, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid srv_admin_state of server %q: %w", server, err)
+			}
+			for i, s := range serverAdminStates {
+				metrics["srv_admin_state"].send(ch, boolToFloat(flags&(1<<i) != 0), backend, server, s)
+			}
+		}
+		if addr := field("srv_check_addr"); addr != "" && addr != "-" {
+			metrics["srv_check_addr"].send(ch, 1, backend, server, addr, field("srv_check_port"))
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,65 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const testServersState = `1
+# be_id be_name srv_id srv_name srv_addr srv_op_state srv_admin_state srv_uweight srv_iweight srv_time_since_last_change srv_check_status srv_check_result srv_check_health srv_check_state srv_agent_state bk_f_forced_id srv_f_forced_id srv_fqdn srv_port srvrecord srv_use_ssl srv_check_port srv_check_addr srv_agent_addr srv_agent_port
+3 be 1 srv1 127.0.0.1 2 0 1 1 21 6 3 4 6 0 0 0 - 8080 - 0 8081 10.0.0.1 - 0
+3 be 2 srv2 127.0.0.2 0 9 1 1 21 6 3 4 6 0 0 0 - 8080 - 0 0 - - 0
+`
+
+func TestParseServersState(t *testing.T) {
+	p := parsedRuntime{collector: runtimeCollectors["servers-state"], output: testServersState}
+	expected := `
+# HELP haproxy_server_admin_state Administrative states of the server, 1 for those it is in, e.g. maint_cli for maintenance set on the CLI and maint_config for servers disabled in the configuration.
+# TYPE haproxy_server_admin_state gauge
+haproxy_server_admin_state{backend="be",server="srv1",state="drain_cli"} 0
+haproxy_server_admin_state{backend="be",server="srv1",state="drain_inherited"} 0
+haproxy_server_admin_state{backend="be",server="srv1",state="maint_cli"} 0
+haproxy_server_admin_state{backend="be",server="srv1",state="maint_config"} 0
+haproxy_server_admin_state{backend="be",server="srv1",state="maint_hostname"} 0
+haproxy_server_admin_state{backend="be",server="srv1",state="maint_inherited"} 0
+haproxy_server_admin_state{backend="be",server="srv1",state="maint_resolution"} 0
+haproxy_server_admin_state{backend="be",server="srv2",state="drain_cli"} 1
+haproxy_server_admin_state{backend="be",server="srv2",state="drain_inherited"} 0
+haproxy_server_admin_state{backend="be",server="srv2",state="maint_cli"} 1
+haproxy_server_admin_state{backend="be",server="srv2",state="maint_config"} 0
+haproxy_server_admin_state{backend="be",server="srv2",state="maint_hostname"} 0
+haproxy_server_admin_state{backend="be",server="srv2",state="maint_inherited"} 0
+haproxy_server_admin_state{backend="be",server="srv2",state="maint_resolution"} 0
+# HELP haproxy_server_check_info Address and port of the health checks of the server.
+# TYPE haproxy_server_check_info gauge
+haproxy_server_check_info{address="10.0.0.1",backend="be",port="8081",server="srv1"} 1
+# HELP haproxy_server_operational_state Operational state of the server, 1 for the state it is in.
+# TYPE haproxy_server_operational_state gauge
+haproxy_server_operational_state{backend="be",server="srv1",state="running"} 1
+haproxy_server_operational_state{backend="be",server="srv1",state="starting"} 0
+haproxy_server_operational_state{backend="be",server="srv1",state="stopped"} 0
+haproxy_server_operational_state{backend="be",server="srv1",state="stopping"} 0
+haproxy_server_operational_state{backend="be",server="srv2",state="running"} 0
+haproxy_server_operational_state{backend="be",server="srv2",state="starting"} 0
+haproxy_server_operational_state{backend="be",server="srv2",state="stopped"} 1
+haproxy_server_operational_state{backend="be",server="srv2",state="stopping"} 0
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
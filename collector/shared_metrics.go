@@ -0,0 +1,177 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"container/list"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxSharedMetrics bounds the sets of metrics kept for the Exporters created
+// later.
+const maxSharedMetrics = 64
+
+// sharedMetrics are the metrics of the rows of the stats and of show info,
+// which only depend on the options of an Exporter, not on its target.
+// Exporters with the same options share them, along with their interned
+// label pairs, so that creating an Exporter for every probe or for many
+// targets doesn't build their descriptors and field maps anew.
+type sharedMetrics struct {
+	rowMetrics    rowMetrics
+	info, idlePct metricInfo
+	infoMetrics   map[string]metricInfo
+	interned      *internedPairs
+
+	// The metrics of the rows of the processes, in the label mode of
+	// MultiProcess, built as the processes are seen.
+	processMutex      sync.Mutex
+	processRowMetrics map[string]rowMetrics // By process.
+}
+
+// sharedMetricsCache holds the sharedMetrics of the options of the Exporters
+// created last.
+var sharedMetricsCache = struct {
+	mutex   sync.Mutex
+	entries map[string]*list.Element // By key of the options.
+	lru     *list.List               // Of *sharedMetricsEntry, used last first.
+}{entries: map[string]*list.Element{}, lru: list.New()}
+
+type sharedMetricsEntry struct {
+	key     string
+	metrics *sharedMetrics
+}
+
+// sharedMetricsFor returns the sharedMetrics of opts and the labels of the
+// rows, built if no Exporter created before had the same.
+func sharedMetricsFor(opts ExporterOpts, labels rowLabels) *sharedMetrics {
+	c := &sharedMetricsCache
+	key := sharedMetricsKey(opts, labels)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*sharedMetricsEntry).metrics
+	}
+	m := newSharedMetrics(opts, labels)
+	c.entries[key] = c.lru.PushFront(&sharedMetricsEntry{key: key, metrics: m})
+	if c.lru.Len() > maxSharedMetrics {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sharedMetricsEntry).key)
+	}
+	return m
+}
+
+// sharedMetricsKey returns a key of the options the sharedMetrics depend on.
+// Names and values are quoted, to not run into each other. The server metrics
+// are told apart by their names and types, as those of a field are the same
+// otherwise.
+func sharedMetricsKey(opts ExporterOpts, labels rowLabels) string {
+	b := make([]byte, 0, 1024)
+	names := make([]string, 0, len(opts.ConstLabels))
+	for name := range opts.ConstLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b = strconv.AppendQuote(b, name)
+		b = strconv.AppendQuote(b, opts.ConstLabels[name])
+	}
+	b = append(b, '\n')
+	b = strconv.AppendQuote(b, opts.NamingScheme)
+	for _, o := range []bool{opts.TimingMilliseconds, opts.AggregateServers, opts.ExcludeUncheckedServerUp} {
+		b = strconv.AppendBool(append(b, ' '), o)
+	}
+	for _, server := range []bool{false, true} {
+		b = append(b, '\n')
+		for _, name := range labels.names(server) {
+			b = strconv.AppendQuote(b, name)
+		}
+	}
+	for _, fm := range metrics(opts.ServerMetrics).sorted() {
+		b = strconv.AppendInt(append(b, '\n'), int64(fm.field), 10)
+		b = strconv.AppendQuote(append(b, ' '), fm.metric.fqName)
+		b = strconv.AppendInt(append(b, ' '), int64(fm.metric.Type), 10)
+	}
+	return string(b)
+}
+
+func newSharedMetrics(opts ExporterOpts, labels rowLabels) *sharedMetrics {
+	exported := func(m metrics) metrics {
+		m = m.withConstLabels(opts.ConstLabels).withNamingScheme(opts.NamingScheme)
+		if opts.TimingMilliseconds {
+			m = m.withMilliseconds()
+		}
+		return m
+	}
+	serverMetrics := exported(metrics(opts.ServerMetrics))
+	exportedInfo := make(map[string]metricInfo, len(infoMetrics))
+	for field, m := range infoMetrics {
+		exportedInfo[field] = m.withConstLabels(opts.ConstLabels).withNamingScheme(opts.NamingScheme)
+	}
+	var aggregated metrics
+	if opts.AggregateServers {
+		aggregated = aggregatedServerMetrics(serverMetrics)
+	}
+	uncheckedServerMetrics := serverMetrics
+	if _, ok := serverMetrics[statusField]; ok && opts.ExcludeUncheckedServerUp {
+		uncheckedServerMetrics = make(metrics, len(serverMetrics))
+		for field, metric := range serverMetrics {
+			if field != statusField {
+				uncheckedServerMetrics[field] = metric
+			}
+		}
+	}
+
+	return &sharedMetrics{
+		rowMetrics: rowMetrics{
+			frontendMetrics:         exported(frontendMetrics),
+			backendMetrics:          exported(backendMetrics),
+			serverMetrics:           serverMetrics,
+			uncheckedServerMetrics:  uncheckedServerMetrics,
+			aggregatedServerMetrics: aggregated,
+			frontendStatus:          frontendStatus.withConstLabels(opts.ConstLabels),
+			backendStatus:           backendStatus.withConstLabels(opts.ConstLabels),
+			frontendInfo:            frontendInfo.withConstLabels(opts.ConstLabels),
+			backendInfo:             backendInfo.withConstLabels(opts.ConstLabels),
+			serverStatus:            serverStatus.withConstLabels(opts.ConstLabels),
+			serverCheckStatus:       serverCheckStatus.withConstLabels(opts.ConstLabels),
+			serverCheckEnabled:      serverCheckEnabled.withConstLabels(opts.ConstLabels),
+			serverCheckTransition:   serverCheckTransition.withConstLabels(opts.ConstLabels),
+		}.withRowLabels(labels),
+		info:              haproxyInfo.withConstLabels(opts.ConstLabels),
+		idlePct:           haproxyIdlePct.withConstLabels(opts.ConstLabels).withNamingScheme(opts.NamingScheme),
+		infoMetrics:       exportedInfo,
+		interned:          newInternedPairs(maxInternedPairs),
+		processRowMetrics: map[string]rowMetrics{},
+	}
+}
+
+// rowMetricsOf returns the metrics of the rows of process, which have the
+// const label process.
+func (s *sharedMetrics) rowMetricsOf(process string) rowMetrics {
+	s.processMutex.Lock()
+	defer s.processMutex.Unlock()
+	r, ok := s.processRowMetrics[process]
+	if !ok {
+		r = s.rowMetrics.withConstLabels(prometheus.Labels{"process": process})
+		r.process = process
+		s.processRowMetrics[process] = r
+	}
+	return r
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSharedMetrics(t *testing.T) {
+	newExporter := func(uri string, opts ExporterOpts) *Exporter {
+		t.Helper()
+		opts.ServerMetrics = serverMetrics
+		e, err := NewExporter(uri, opts, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	a := newExporter("http://a.example.com/;csv", ExporterOpts{})
+	b := newExporter("unix:/run/haproxy/b.sock", ExporterOpts{})
+	if a.shared != b.shared || a.frontendMetrics[4].Desc != b.frontendMetrics[4].Desc {
+		t.Error("want Exporters of different targets with the same options to share their metrics")
+	}
+	if a.labels.interned != b.labels.interned {
+		t.Error("want Exporters sharing their metrics to share their interned label pairs")
+	}
+	for _, opts := range []ExporterOpts{
+		{ConstLabels: prometheus.Labels{"env": "prod"}},
+		{NamingScheme: NamingV2},
+		{TimingMilliseconds: true},
+		{AggregateServers: true},
+		{ExcludeUncheckedServerUp: true},
+		{IDLabels: true},
+	} {
+		if c := newExporter("http://a.example.com/;csv", opts); c.shared == a.shared {
+			t.Errorf("want Exporters with options %+v to have metrics of their own", opts)
+		}
+	}
+
+	// The metrics of the options used least recently are dropped.
+	for i := 0; i < maxSharedMetrics; i++ {
+		newExporter("http://a.example.com/;csv", ExporterOpts{ConstLabels: prometheus.Labels{"n": fmt.Sprint(i)}})
+	}
+	if c := newExporter("http://a.example.com/;csv", ExporterOpts{}); c.shared == a.shared {
+		t.Error("want the metrics used least recently to be dropped")
+	}
+}
+
+func BenchmarkNewExporter(b *testing.B) {
+	opts := ExporterOpts{ServerMetrics: serverMetrics}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewExporter("http://haproxy.example.com/;csv", opts, log.NewNopLogger()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
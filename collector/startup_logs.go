@@ -0,0 +1,59 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// startupLogLevels are the levels of the messages of show startup-logs.
+var startupLogLevels = []string{"alert", "warning", "notice", "diag"}
+
+var startupLogMetrics = map[string]metricInfo{
+	"messages": newMetricInfo(prometheus.BuildFQName(namespace, "startup_log", "messages"), "Number of messages logged while loading the configuration at the last start or reload, by level.", prometheus.GaugeValue, []string{"level"}, nil),
+}
+
+// parseStartupLogs sends the number of messages of every level of show
+// startup-logs, whose lines look like
+//
+//	[WARNING]  (1234) : config : missing timeouts for frontend 'fe'.
+//
+// Messages are counted for all levels, so that a configuration without
+// warnings exports 0. An alert from a master process means that its last
+// reload failed.
+func parseStartupLogs(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	counts := make(map[string]float64, len(startupLogLevels))
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "[") {
+			continue
+		}
+		level, _, ok := strings.Cut(line[1:], "]")
+		if ok {
+			counts[strings.ToLower(level)]++
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	for _, level := range startupLogLevels {
+		metrics["messages"].send(ch, counts[level], level)
+	}
+	return nil
+}
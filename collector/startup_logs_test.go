@@ -0,0 +1,40 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseStartupLogs(t *testing.T) {
+	p := parsedRuntime{collector: runtimeCollectors["startup-logs"], output: `[NOTICE]   (1) : haproxy version is 2.8.3
+[WARNING]  (1) : config : missing timeouts for frontend 'fe'.
+   | While not properly invalid, you will certainly encounter various problems
+[WARNING]  (1) : config : log format ignored for frontend 'fe' since it has no log address.
+`}
+	expected := `
+# HELP haproxy_startup_log_messages Number of messages logged while loading the configuration at the last start or reload, by level.
+# TYPE haproxy_startup_log_messages gauge
+haproxy_startup_log_messages{level="alert"} 0
+haproxy_startup_log_messages{level="diag"} 0
+haproxy_startup_log_messages{level="notice"} 1
+haproxy_startup_log_messages{level="warning"} 2
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// stickTableMetrics are the metrics of the stick tables of show table, by
+// field. The utilization is the ratio of the used and size fields.
+var stickTableMetrics = map[string]metricInfo{
+	"size":        newStickTableMetric("size", "Maximum number of entries of the stick table.", prometheus.GaugeValue),
+	"used":        newStickTableMetric("entries", "Current number of entries of the stick table.", prometheus.GaugeValue),
+	"utilization": newStickTableMetric("utilization_ratio", "Ratio of the used entries of the stick table to its size.", prometheus.GaugeValue),
+}
+
+func newStickTableMetric(metricName string, docString string, t prometheus.ValueType) metricInfo {
+	return newMetricInfo(prometheus.BuildFQName(namespace, "stick_table", metricName), docString, t, []string{"table"}, nil)
+}
+
+// parseStickTables sends the metrics of the output of show table, which has
+// a line like "# table: foo, type: ip, size:204800, used:171" per table.
+func parseStickTables(r io.Reader, metrics map[string]metricInfo, ch chan<- prometheus.Metric) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line, ok := strings.CutPrefix(s.Text(), "# table:")
+		if !ok {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		table := strings.TrimSpace(fields[0])
+		values := map[string]float64{}
+		for _, f := range fields[1:] {
+			k, v, ok := strings.Cut(f, ":")
+			if !ok {
+				continue
+			}
+			k = strings.TrimSpace(k)
+			if _, ok := metrics[k]; !ok {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s of table %q: %w", k, table, err)
+			}
+			values[k] = value
+			metrics[k].send(ch, value, table)
+		}
+		if size, ok := values["size"]; ok && size > 0 {
+			metrics["utilization"].send(ch, values["used"]/size, table)
+		}
+	}
+	return s.Err()
+}
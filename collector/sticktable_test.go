@@ -0,0 +1,42 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseStickTables(t *testing.T) {
+	p := parsedRuntime{collector: runtimeCollectors["tables"], output: "# table: front_pub, type: ip, size:200, used:50\n# table: back_rdp, type: string, size:1024, used:0\n"}
+	expected := `
+# HELP haproxy_stick_table_entries Current number of entries of the stick table.
+# TYPE haproxy_stick_table_entries gauge
+haproxy_stick_table_entries{table="back_rdp"} 0
+haproxy_stick_table_entries{table="front_pub"} 50
+# HELP haproxy_stick_table_size Maximum number of entries of the stick table.
+# TYPE haproxy_stick_table_size gauge
+haproxy_stick_table_size{table="back_rdp"} 1024
+haproxy_stick_table_size{table="front_pub"} 200
+# HELP haproxy_stick_table_utilization_ratio Ratio of the used entries of the stick table to its size.
+# TYPE haproxy_stick_table_utilization_ratio gauge
+haproxy_stick_table_utilization_ratio{table="back_rdp"} 0
+haproxy_stick_table_utilization_ratio{table="front_pub"} 0.25
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+// statsVersions maps the CSV columns first added by a HAProxy release to the
+// release, newest first. HAProxy only ever appends columns, so the last column
+// of the header tells the oldest release the stats can be from.
+var statsVersions = []struct {
+	column, version string
+}{
+	{"uweight", "2.4"},
+	{"idle_conn_cur", "2.2"},
+	{"qtime_max", "2.0"},
+	{"srv_icur", "1.9"},
+	{"cache_hits", "1.8"},
+	{"dses", "1.7"},
+	{"ttime", "1.5.19"},
+	{"lastsess", "1.5"},
+	{"srv_abrt", "1.4"},
+}
+
+// versionFromHeader returns the oldest HAProxy release which emits stats with
+// the given header, as e.g. "1.7+". It returns "" if the release is unknown.
+func versionFromHeader(header []string) string {
+	columns := make(map[string]struct{}, len(header))
+	for _, c := range header {
+		columns[c] = struct{}{}
+	}
+	for _, v := range statsVersions {
+		if _, ok := columns[v.column]; ok {
+			return v.version + "+"
+		}
+	}
+	return ""
+}
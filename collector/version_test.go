@@ -0,0 +1,72 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestVersionFromHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "pxname,svname,qcur", want: ""},
+		{header: "pxname,svname,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt", want: "1.4+"},
+		{header: "pxname,svname,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess", want: "1.5+"},
+		{header: "pxname,svname,lastsess,last_chk,last_agt,qtime,ctime,rtime,ttime", want: "1.5.19+"},
+		{header: strings.Join(csvFieldNames[:83], ","), want: "1.7+"},
+		{header: strings.Join(csvFieldNames[:88], ","), want: "1.8+"},
+		{header: strings.Join(csvFieldNames[:90], ","), want: "1.9+"},
+		{header: strings.Join(csvFieldNames[:95], ","), want: "2.0+"},
+		{header: haproxy22Header, want: "2.2+"},
+		{header: strings.Join(csvFieldNames[:quicRxbufFullField], ","), want: "2.4+"},
+		// HAProxy 2.6 and later add the columns of the QUIC module at the end.
+		{header: strings.Join(csvFieldNames, ","), want: "2.4+"},
+	}
+
+	for _, tt := range tests {
+		if have := versionFromHeader(strings.Split(tt.header, ",")); tt.want != have {
+			t.Errorf("%s: want %q, have %q", tt.header, tt.want, have)
+		}
+	}
+}
+
+func TestVersionFromStatsHeader(t *testing.T) {
+	for header, version := range map[string]string{
+		strings.Join(csvFieldNames[:83], ","): "1.7+",
+		haproxy22Header:                       "2.2+",
+	} {
+		h := newHaproxy([]byte("# " + header + ",\nfoo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"))
+		e, err := NewExporter(h.URL, ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `
+# HELP haproxy_version_info HAProxy version info.
+# TYPE haproxy_version_info gauge
+haproxy_version_info{release_date="",version="` + version + `"} 1
+`
+		if err := testutil.CollectAndCompare(e, strings.NewReader(expected), "haproxy_version_info"); err != nil {
+			t.Errorf("%s: %s", version, err)
+		}
+		h.Close()
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/haproxy_exporter/collector"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the content of the file given with --config.file. Every option
+// corresponds to a command-line flag, which takes precedence over the file.
+// Options which are not set in the file keep the default of their flag.
+type Config struct {
+	HAProxy HAProxyConfig `yaml:"haproxy"`
+	Web     WebConfig     `yaml:"web"`
+	// Modules are named sets of scrape options, selected with the module
+	// parameter of /probe.
+	Modules map[string]ModuleConfig `yaml:"modules"`
+	// Targets are the HAProxy instances scraped on the metrics endpoint. If
+	// none are given, the scrape URI of the haproxy section is scraped.
+	Targets []TargetConfig `yaml:"targets"`
+	// MetricRules rename or drop metrics before they are exposed.
+	MetricRules []MetricRuleConfig `yaml:"metric_rules"`
+	// LabelRules extract labels from the names of proxies and servers.
+	LabelRules []collector.LabelRuleConfig `yaml:"label_rules"`
+}
+
+// TargetConfig describes an HAProxy instance scraped on the metrics endpoint.
+type TargetConfig struct {
+	ScrapeURI string `yaml:"scrape_uri"`
+	// Module names the module whose options are used for scraping the target.
+	// Without it the options of the haproxy section are used, except for the
+	// credentials.
+	Module string `yaml:"module"`
+	// Labels are added to every metric of the target.
+	Labels map[string]string `yaml:"labels"`
+}
+
+// HAProxyConfig holds the options of the --haproxy.* flags, along with those
+// of --metrics.naming-scheme and --log.scrape-errors-every.
+type HAProxyConfig struct {
+	ScrapeURI           *string `yaml:"scrape_uri"`
+	ModuleConfig        `yaml:",inline"`
+	NamingScheme        *string        `yaml:"naming_scheme"`
+	ScrapeErrorLogEvery *int           `yaml:"scrape_error_log_every"`
+	ScrapeCacheTTL      *time.Duration `yaml:"scrape_cache_ttl"`
+	PidFile             *string        `yaml:"pid_file"`
+}
+
+// ModuleConfig holds the options for scraping a single HAProxy. Options which
+// are not set keep the value of the corresponding flag.
+type ModuleConfig struct {
+	SSLVerify                   *bool          `yaml:"ssl_verify"`
+	ProxyFromEnv                *bool          `yaml:"proxy_from_env"`
+	Username                    *string        `yaml:"username"`
+	Password                    *string        `yaml:"password"`
+	ServerMetricFields          *string        `yaml:"server_metric_fields"`
+	ServerExcludeStates         *string        `yaml:"server_exclude_states"`
+	IncludeUnprovisionedServers *bool          `yaml:"include_unprovisioned_servers"`
+	ServerNameInclude           *string        `yaml:"server_name_include"`
+	ServerNameExclude           *string        `yaml:"server_name_exclude"`
+	ExcludeUncheckedServerUp    *bool          `yaml:"exclude_unchecked_server_up"`
+	UnlimitedValue              *string        `yaml:"unlimited_value"`
+	TimingMilliseconds          *bool          `yaml:"timing_milliseconds"`
+	RuntimeCollectors           *string        `yaml:"runtime_collectors"`
+	SchemaMetrics               *bool          `yaml:"schema_metrics"`
+	ScrapeLevel                 *string        `yaml:"scrape_level"`
+	Proxies                     *string        `yaml:"proxies"`
+	MaxServersPerBackend        *int           `yaml:"max_servers_per_backend"`
+	MaxServers                  *int           `yaml:"max_servers"`
+	AggregateServers            *bool          `yaml:"aggregate_servers"`
+	IDLabels                    *bool          `yaml:"id_labels"`
+	MultiProcess                *string        `yaml:"multi_process"`
+	StreamStats                 *bool          `yaml:"stream_stats"`
+	ParseWorkers                *int           `yaml:"parse_workers"`
+	MetricBuffer                *int           `yaml:"metric_buffer"`
+	Timeout                     *time.Duration `yaml:"timeout"`
+}
+
+// apply returns opts with the options set in m.
+func (m ModuleConfig) apply(opts collector.ExporterOpts) (collector.ExporterOpts, error) {
+	setIfConfigured(&opts.SSLVerify, m.SSLVerify)
+	setIfConfigured(&opts.ProxyFromEnv, m.ProxyFromEnv)
+	setIfConfigured(&opts.Username, m.Username)
+	setIfConfigured(&opts.Password, m.Password)
+	setIfConfigured(&opts.ExcludedServerStates, m.ServerExcludeStates)
+	setIfConfigured(&opts.IncludeUnprovisionedServers, m.IncludeUnprovisionedServers)
+	setIfConfigured(&opts.ServerNameInclude, m.ServerNameInclude)
+	setIfConfigured(&opts.ServerNameExclude, m.ServerNameExclude)
+	setIfConfigured(&opts.ExcludeUncheckedServerUp, m.ExcludeUncheckedServerUp)
+	setIfConfigured(&opts.UnlimitedValue, m.UnlimitedValue)
+	setIfConfigured(&opts.TimingMilliseconds, m.TimingMilliseconds)
+	setIfConfigured(&opts.RuntimeCollectors, m.RuntimeCollectors)
+	setIfConfigured(&opts.SchemaMetrics, m.SchemaMetrics)
+	setIfConfigured(&opts.ScrapeLevel, m.ScrapeLevel)
+	setIfConfigured(&opts.Proxies, m.Proxies)
+	setIfConfigured(&opts.MaxServersPerBackend, m.MaxServersPerBackend)
+	setIfConfigured(&opts.MaxServers, m.MaxServers)
+	setIfConfigured(&opts.AggregateServers, m.AggregateServers)
+	setIfConfigured(&opts.IDLabels, m.IDLabels)
+	setIfConfigured(&opts.MultiProcess, m.MultiProcess)
+	setIfConfigured(&opts.StreamStats, m.StreamStats)
+	setIfConfigured(&opts.ParseWorkers, m.ParseWorkers)
+	setIfConfigured(&opts.MetricBuffer, m.MetricBuffer)
+	setIfConfigured(&opts.Timeout, m.Timeout)
+	if m.ServerMetricFields != nil {
+		metrics, err := collector.FilterServerMetrics(*m.ServerMetricFields)
+		if err != nil {
+			return opts, err
+		}
+		opts.ServerMetrics = metrics
+	}
+	return opts, nil
+}
+
+// WebConfig holds the options of the --web.* flags of the exporter. Listen
+// addresses and TLS are configured with the flags of the exporter toolkit.
+type WebConfig struct {
+	TelemetryPath          *string        `yaml:"telemetry_path"`
+	LogRequests            *bool          `yaml:"log_requests"`
+	MaxRequests            *int           `yaml:"max_requests"`
+	RequestTimeout         *time.Duration `yaml:"request_timeout"`
+	EnableOpenMetrics      *bool          `yaml:"enable_openmetrics"`
+	ErrorHandling          *string        `yaml:"error_handling"`
+	Compression            *string        `yaml:"compression"`
+	DisableExporterMetrics *bool          `yaml:"disable_exporter_metrics"`
+	EnableDebugEndpoints   *bool          `yaml:"enable_debug_endpoints"`
+}
+
+var envVarRE = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandVars replaces every ${VAR} in s with the value of the environment
+// variable VAR. Other uses of $ are left alone, so that e.g. passwords
+// containing it don't need escaping.
+func expandVars(s string) (string, error) {
+	var err error
+	expanded := envVarRE.ReplaceAllStringFunc(s, func(m string) string {
+		name := envVarRE.FindStringSubmatch(m)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %q is not set", name)
+		}
+		return value
+	})
+	return expanded, err
+}
+
+// expandEnv expands the references to environment variables in the scrape
+// URIs, the credentials and the target labels of cfg. They are expanded in
+// the parsed values only, so that the values of variables can't change the
+// structure of the file, and other options, e.g. the replacements of metric
+// rules, which may refer to capture groups as ${name}, are left alone.
+func (cfg *Config) expandEnv() error {
+	values := []*string{cfg.HAProxy.ScrapeURI, cfg.HAProxy.Username, cfg.HAProxy.Password}
+	for _, m := range cfg.Modules {
+		values = append(values, m.Username, m.Password)
+	}
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		values = append(values, &t.ScrapeURI)
+		for name, value := range t.Labels {
+			expanded, err := expandVars(value)
+			if err != nil {
+				return fmt.Errorf("label %q of target %q: %w", name, t.ScrapeURI, err)
+			}
+			t.Labels[name] = expanded
+		}
+	}
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		expanded, err := expandVars(*v)
+		if err != nil {
+			return err
+		}
+		*v = expanded
+	}
+	return nil
+}
+
+// loadConfig reads and validates the config file at path, expanding
+// references to environment variables.
+func loadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config file %q: %w", path, err)
+	}
+	if err := cfg.expandEnv(); err != nil {
+		return nil, fmt.Errorf("error expanding config file %q: %w", path, err)
+	}
+
+	if v := cfg.Web.ErrorHandling; v != nil {
+		if _, ok := errorHandlingModes[*v]; !ok {
+			return nil, fmt.Errorf("invalid error_handling %q in config file %q", *v, path)
+		}
+	}
+	if v := cfg.HAProxy.NamingScheme; v != nil && *v != collector.NamingLegacy && *v != collector.NamingV2 && *v != collector.NamingBoth {
+		return nil, fmt.Errorf("invalid naming_scheme %q in config file %q", *v, path)
+	}
+	if v := cfg.Web.Compression; v != nil && *v != "auto" && *v != "force" && *v != "off" {
+		return nil, fmt.Errorf("invalid compression %q in config file %q", *v, path)
+	}
+	for name, m := range cfg.Modules {
+		if _, err := m.apply(collector.ExporterOpts{}); err != nil {
+			return nil, fmt.Errorf("invalid module %q in config file %q: %w", name, path, err)
+		}
+	}
+	if _, err := compileMetricRules(cfg.MetricRules); err != nil {
+		return nil, fmt.Errorf("invalid metric_rules in config file %q: %w", path, err)
+	}
+	if err := validateTargets(cfg); err != nil {
+		return nil, fmt.Errorf("invalid targets in config file %q: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// validateTargets checks that the targets of cfg use existing modules and that
+// their metrics are told apart by their labels. All targets must have the
+// same label names, as metrics of the same name must have the same labels.
+func validateTargets(cfg *Config) error {
+	labelSets := map[uint64]string{}
+	var labelNames []string // Of the first target.
+	for i, t := range cfg.Targets {
+		if t.ScrapeURI == "" {
+			return fmt.Errorf("target without scrape_uri")
+		}
+		if _, ok := cfg.Modules[t.Module]; t.Module != "" && !ok {
+			return fmt.Errorf("unknown module %q for target %q", t.Module, t.ScrapeURI)
+		}
+		for name := range t.Labels {
+			if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+				return fmt.Errorf("invalid label name %q for target %q", name, t.ScrapeURI)
+			}
+			if collector.ReservedLabelNames()[name] || (name == collector.TargetLabel && len(cfg.Targets) > 1) {
+				return fmt.Errorf("label name %q of target %q is used by the exported metrics", name, t.ScrapeURI)
+			}
+		}
+		names := make([]string, 0, len(t.Labels))
+		for name := range t.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if i == 0 {
+			labelNames = names
+		} else if strings.Join(names, ",") != strings.Join(labelNames, ",") {
+			return fmt.Errorf("targets %q and %q have different label names", cfg.Targets[0].ScrapeURI, t.ScrapeURI)
+		}
+		sig := model.LabelsToSignature(t.Labels)
+		if other, ok := labelSets[sig]; ok {
+			return fmt.Errorf("targets %q and %q have the same labels", other, t.ScrapeURI)
+		}
+		labelSets[sig] = t.ScrapeURI
+	}
+	return nil
+}
+
+// flagsSetByUser returns the names of the flags of app given in args.
+func flagsSetByUser(app *kingpin.Application, args []string) (map[string]bool, error) {
+	ctx, err := app.ParseContext(args)
+	if err != nil {
+		return nil, err
+	}
+	set := map[string]bool{}
+	for _, el := range ctx.Elements {
+		if f, ok := el.Clause.(*kingpin.FlagClause); ok {
+			set[f.Model().Name] = true
+		}
+	}
+	return set, nil
+}
+
+// setIfConfigured sets *dst to the configured value src, unless the option is
+// not in the config file.
+func setIfConfigured[T any](dst, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// override is like setIfConfigured, but leaves *dst alone if the flag was
+// given on the command line.
+func override[T any](dst, src *T, flag string, setFlags map[string]bool) {
+	if !setFlags[flag] {
+		setIfConfigured(dst, src)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/haproxy_exporter/collector"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := loadConfig(writeConfig(t, `
+haproxy:
+  scrape_uri: unix:/run/haproxy/admin.sock
+  ssl_verify: false
+  timeout: 2s
+web:
+  compression: force
+  enable_debug_endpoints: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "unix:/run/haproxy/admin.sock", *cfg.HAProxy.ScrapeURI; want != have {
+		t.Errorf("want scrape_uri %q, have %q", want, have)
+	}
+	if *cfg.HAProxy.SSLVerify {
+		t.Errorf("want ssl_verify false")
+	}
+	if want, have := 2*time.Second, *cfg.HAProxy.Timeout; want != have {
+		t.Errorf("want timeout %s, have %s", want, have)
+	}
+	if cfg.HAProxy.PidFile != nil {
+		t.Errorf("want unset pid_file, have %q", *cfg.HAProxy.PidFile)
+	}
+	if !*cfg.Web.EnableDebugEndpoints {
+		t.Errorf("want enable_debug_endpoints true")
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{content: "haproxy:\n  scrape_url: http://localhost/\n", err: "field scrape_url not found"},
+		{content: "haproxy:\n  naming_scheme: v3\n", err: `invalid naming_scheme "v3"`},
+		{content: "web:\n  compression: brotli\n", err: `invalid compression "brotli"`},
+		{content: "web:\n  error_handling: ignore\n", err: `invalid error_handling "ignore"`},
+		{content: "targets:\n  - labels: {role: edge}\n", err: "target without scrape_uri"},
+		{content: "targets:\n  - scrape_uri: http://a/\n    module: bogus\n", err: `unknown module "bogus"`},
+		{content: "targets:\n  - scrape_uri: http://a/\n    labels: {backend: a}\n", err: `label name "backend"`},
+		{content: "targets:\n  - scrape_uri: http://a/\n    labels: {0role: a}\n", err: `invalid label name "0role"`},
+		{content: "targets:\n  - scrape_uri: http://a/\n    labels: {state: a}\n", err: `label name "state"`},
+		{content: "targets:\n  - scrape_uri: http://a/\n    labels: {reason: a}\n", err: `label name "reason"`},
+		{content: "targets:\n  - scrape_uri: http://a/\n    labels: {le: a}\n", err: `label name "le"`},
+		{content: "targets:\n  - scrape_uri: http://a/\n    labels: {proxy_id: a}\n", err: `label name "proxy_id"`},
+		{content: "targets:\n  - scrape_uri: http://a/\n    labels: {role: a}\n  - scrape_uri: http://b/\n    labels: {env: b}\n", err: "different label names"},
+		{content: "targets:\n  - scrape_uri: http://a/\n  - scrape_uri: http://b/\n", err: "have the same labels"},
+		{content: "targets:\n  - scrape_uri: http://a/\n    labels: {target: a}\n  - scrape_uri: http://b/\n", err: `label name "target"`},
+	}
+
+	for _, tt := range tests {
+		_, err := loadConfig(writeConfig(t, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("want error containing %q, have %v", tt.err, err)
+		}
+	}
+}
+
+func TestOverride(t *testing.T) {
+	app := kingpin.New("test", "")
+	uri := app.Flag("haproxy.scrape-uri", "").Default("http://localhost/;csv").String()
+	verify := app.Flag("haproxy.ssl-verify", "").Default("true").Bool()
+	timeout := app.Flag("haproxy.timeout", "").Default("5s").Duration()
+
+	args := []string{"--no-haproxy.ssl-verify", "--haproxy.timeout=1s"}
+	if _, err := app.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	setFlags, err := flagsSetByUser(app, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfgURI, cfgVerify, cfgTimeout := "unix:/run/haproxy/admin.sock", true, 3*time.Second
+	override(uri, &cfgURI, "haproxy.scrape-uri", setFlags)
+	override(verify, &cfgVerify, "haproxy.ssl-verify", setFlags)
+	override(timeout, &cfgTimeout, "haproxy.timeout", setFlags)
+
+	if want, have := cfgURI, *uri; want != have {
+		t.Errorf("want configured scrape URI %q, have %q", want, have)
+	}
+	if *verify {
+		t.Errorf("want ssl-verify from command line to take precedence")
+	}
+	if want, have := time.Second, *timeout; want != have {
+		t.Errorf("want timeout from command line %s, have %s", want, have)
+	}
+}
+
+func TestModuleConfigApply(t *testing.T) {
+	cfg, err := loadConfig(writeConfig(t, `
+modules:
+  socket:
+    timeout: 1s
+    server_metric_fields: "8,9"
+  http:
+    username: admin
+    password: secret
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := collector.ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, Timeout: 5 * time.Second}
+
+	socket, err := cfg.Modules["socket"].apply(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := time.Second, socket.Timeout; want != have {
+		t.Errorf("want timeout %s, have %s", want, have)
+	}
+	if want, have := 2, len(socket.ServerMetrics); want != have {
+		t.Errorf("want %d server metrics, have %d", want, have)
+	}
+
+	httpOpts, err := cfg.Modules["http"].apply(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if httpOpts.Username != "admin" || httpOpts.Password != "secret" {
+		t.Errorf("want credentials from module, have %q/%q", httpOpts.Username, httpOpts.Password)
+	}
+	if !httpOpts.SSLVerify || httpOpts.Timeout != base.Timeout {
+		t.Errorf("want unset options to keep their defaults, have %+v", httpOpts)
+	}
+
+	if _, err := loadConfig(writeConfig(t, "modules:\n  bad:\n    server_metric_fields: a\n")); err == nil {
+		t.Errorf("expected error for invalid server_metric_fields")
+	}
+}
+
+func TestLoadConfigExpandEnv(t *testing.T) {
+	t.Setenv("HAPROXY_SOCKET", "/run/haproxy/admin.sock")
+	t.Setenv("HAPROXY_PASSWORD", "s3cret\n  ssl_verify: false")
+	t.Setenv("DC", "eu-1")
+
+	cfg, err := loadConfig(writeConfig(t, `
+haproxy:
+  scrape_uri: unix:${HAPROXY_SOCKET}
+  username: $user
+  password: ${HAPROXY_PASSWORD}
+targets:
+  - scrape_uri: http://${DC}.example.com/;csv
+    labels:
+      dc: ${DC}
+metric_rules:
+  - regex: haproxy_(?P<DC>.*)
+    action: rename
+    replacement: ${DC}_total
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "unix:/run/haproxy/admin.sock", *cfg.HAProxy.ScrapeURI; want != have {
+		t.Errorf("want scrape_uri %q, have %q", want, have)
+	}
+	if want, have := "$user", *cfg.HAProxy.Username; want != have {
+		t.Errorf("want username %q, have %q", want, have)
+	}
+	// The values of variables are never parsed as YAML.
+	if want, have := "s3cret\n  ssl_verify: false", *cfg.HAProxy.Password; want != have {
+		t.Errorf("want password %q, have %q", want, have)
+	}
+	if cfg.HAProxy.SSLVerify != nil {
+		t.Errorf("want ssl_verify unset, have %v", *cfg.HAProxy.SSLVerify)
+	}
+	if want, have := (TargetConfig{ScrapeURI: "http://eu-1.example.com/;csv", Labels: map[string]string{"dc": "eu-1"}}), cfg.Targets[0]; !reflect.DeepEqual(want, have) {
+		t.Errorf("want target %+v, have %+v", want, have)
+	}
+	// Replacements refer to capture groups, not to variables.
+	if want, have := "${DC}_total", cfg.MetricRules[0].Replacement; want != have {
+		t.Errorf("want replacement %q, have %q", want, have)
+	}
+
+	_, err = loadConfig(writeConfig(t, "haproxy:\n  scrape_uri: ${HAPROXY_EXPORTER_UNSET_VARIABLE}\n"))
+	if err == nil || !strings.Contains(err.Error(), "HAPROXY_EXPORTER_UNSET_VARIABLE") {
+		t.Errorf("want error about unset variable, have %v", err)
+	}
+}
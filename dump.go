@@ -0,0 +1,58 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// dump gathers the metrics of g once and writes them to w in the text
+// exposition format, with rules applied. It returns whether every scraped
+// HAProxy was up.
+func dump(w io.Writer, g prometheus.Gatherer, rules []metricRule) (bool, error) {
+	mfs, err := g.Gather()
+	if err != nil {
+		return false, err
+	}
+
+	up := false
+	for _, mf := range mfs {
+		if mf.GetName() != prometheus.BuildFQName(namespace, "", "up") {
+			continue
+		}
+		up = len(mf.Metric) > 0
+		for _, m := range mf.Metric {
+			if m.GetGauge().GetValue() != 1 {
+				up = false
+			}
+		}
+	}
+
+	// Apply the rules to the gathered metrics instead of gathering again.
+	gathered := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return mfs, nil })
+	if mfs, err = withMetricRules(gathered, func() []metricRule { return rules }).Gather(); err != nil {
+		return false, err
+	}
+	enc := expfmt.NewEncoder(w, expfmt.FmtText)
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			return false, err
+		}
+	}
+	return up, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/haproxy_exporter/collector"
+)
+
+func TestDump(t *testing.T) {
+	const data = "foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"
+	h := newHaproxy([]byte(data))
+	defer h.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	defer down.Close()
+
+	rules, err := compileMetricRules([]MetricRuleConfig{{Name: "haproxy_frontend_current_sessions", Action: "rename", Replacement: "haproxy_frontend_sessions"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		uri  string
+		up   bool
+		want string
+	}{
+		{uri: h.URL, up: true, want: `haproxy_frontend_sessions{frontend="foo"} 1`},
+		{uri: down.URL, up: false, want: "haproxy_up 0"},
+	}
+
+	for _, tt := range tests {
+		e, _ := collector.NewExporter(tt.uri, collector.ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(e)
+
+		var buf bytes.Buffer
+		up, err := dump(&buf, registry, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.up != up {
+			t.Errorf("%s: want up %v, have %v", tt.uri, tt.up, up)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("%s: want %q in output, have %q", tt.uri, tt.want, buf.String())
+		}
+	}
+}
@@ -14,24 +14,11 @@
 package main
 
 import (
-	"bufio"
-	"crypto/tls"
-	"encoding/csv"
-	"errors"
-	"fmt"
-	"io"
-	"net"
+	"context"
 	"net/http"
 	_ "net/http/pprof"
-	"net/url"
 	"os"
-	"sort"
-	"strconv"
-	"strings"
-	"sync"
-	"time"
 
-	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
@@ -41,584 +28,253 @@ import (
 	"github.com/prometheus/common/version"
 	"github.com/prometheus/exporter-toolkit/web"
 	webflag "github.com/prometheus/exporter-toolkit/web/kingpinflag"
+	"github.com/prometheus/haproxy_exporter/collector"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
-const (
-	namespace = "haproxy" // For Prometheus metrics.
+const namespace = "haproxy" // For Prometheus metrics.
 
-	// HAProxy 1.4
-	// # pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,
-	// HAProxy 1.5
-	// pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,
-	// HAProxy 1.5.19
-	// pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,last_chk,last_agt,qtime,ctime,rtime,ttime,
-	// HAProxy 1.7
-	// pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,last_chk,last_agt,qtime,ctime,rtime,ttime,agent_status,agent_code,agent_duration,check_desc,agent_desc,check_rise,check_fall,check_health,agent_rise,agent_fall,agent_health,addr,cookie,mode,algo,conn_rate,conn_rate_max,conn_tot,intercepted,dcon,dses
-	minimumCsvFieldCount = 33
-
-	pxnameField        = 0
-	svnameField        = 1
-	statusField        = 17
-	typeField          = 32
-	checkDurationField = 38
-	qtimeMsField       = 58
-	ctimeMsField       = 59
-	rtimeMsField       = 60
-	ttimeMsField       = 61
-
-	excludedServerStates = ""
-	showStatCmd          = "show stat\n"
-	showInfoCmd          = "show info\n"
-)
-
-var (
-	frontendLabelNames = []string{"frontend"}
-	backendLabelNames  = []string{"backend"}
-	serverLabelNames   = []string{"backend", "server"}
-)
-
-type metricInfo struct {
-	Desc *prometheus.Desc
-	Type prometheus.ValueType
-}
-
-func newFrontendMetric(metricName string, docString string, t prometheus.ValueType, constLabels prometheus.Labels) metricInfo {
-	return metricInfo{
-		Desc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "frontend", metricName),
-			docString,
-			frontendLabelNames,
-			constLabels,
-		),
-		Type: t,
-	}
-}
-
-func newBackendMetric(metricName string, docString string, t prometheus.ValueType, constLabels prometheus.Labels) metricInfo {
-	return metricInfo{
-		Desc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "backend", metricName),
-			docString,
-			backendLabelNames,
-			constLabels,
-		),
-		Type: t,
-	}
-}
-
-func newServerMetric(metricName string, docString string, t prometheus.ValueType, constLabels prometheus.Labels) metricInfo {
-	return metricInfo{
-		Desc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "server", metricName),
-			docString,
-			serverLabelNames,
-			constLabels,
-		),
-		Type: t,
-	}
-}
-
-type metrics map[int]metricInfo
-
-func (m metrics) String() string {
-	keys := make([]int, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
-	}
-	sort.Ints(keys)
-	s := make([]string, len(keys))
-	for i, k := range keys {
-		s[i] = strconv.Itoa(k)
-	}
-	return strings.Join(s, ",")
-}
-
-var (
-	serverMetrics = metrics{
-		2:  newServerMetric("current_queue", "Current number of queued requests assigned to this server.", prometheus.GaugeValue, nil),
-		3:  newServerMetric("max_queue", "Maximum observed number of queued requests assigned to this server.", prometheus.GaugeValue, nil),
-		4:  newServerMetric("current_sessions", "Current number of active sessions.", prometheus.GaugeValue, nil),
-		5:  newServerMetric("max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue, nil),
-		6:  newServerMetric("limit_sessions", "Configured session limit.", prometheus.GaugeValue, nil),
-		7:  newServerMetric("sessions_total", "Total number of sessions.", prometheus.CounterValue, nil),
-		8:  newServerMetric("bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue, nil),
-		9:  newServerMetric("bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue, nil),
-		13: newServerMetric("connection_errors_total", "Total of connection errors.", prometheus.CounterValue, nil),
-		14: newServerMetric("response_errors_total", "Total of response errors.", prometheus.CounterValue, nil),
-		15: newServerMetric("retry_warnings_total", "Total of retry warnings.", prometheus.CounterValue, nil),
-		16: newServerMetric("redispatch_warnings_total", "Total of redispatch warnings.", prometheus.CounterValue, nil),
-		17: newServerMetric("up", "Current health status of the server (1 = UP, 0 = DOWN).", prometheus.GaugeValue, nil),
-		18: newServerMetric("weight", "Current weight of the server.", prometheus.GaugeValue, nil),
-		21: newServerMetric("check_failures_total", "Total number of failed health checks.", prometheus.CounterValue, nil),
-		24: newServerMetric("downtime_seconds_total", "Total downtime in seconds.", prometheus.CounterValue, nil),
-		30: newServerMetric("server_selected_total", "Total number of times a server was selected, either for new sessions, or when re-dispatching.", prometheus.CounterValue, nil),
-		33: newServerMetric("current_session_rate", "Current number of sessions per second over last elapsed second.", prometheus.GaugeValue, nil),
-		35: newServerMetric("max_session_rate", "Maximum observed number of sessions per second.", prometheus.GaugeValue, nil),
-		38: newServerMetric("check_duration_seconds", "Previously run health check duration, in seconds", prometheus.GaugeValue, nil),
-		39: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "1xx"}),
-		40: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "2xx"}),
-		41: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "3xx"}),
-		42: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "4xx"}),
-		43: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "5xx"}),
-		44: newServerMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "other"}),
-		49: newServerMetric("client_aborts_total", "Total number of data transfers aborted by the client.", prometheus.CounterValue, nil),
-		50: newServerMetric("server_aborts_total", "Total number of data transfers aborted by the server.", prometheus.CounterValue, nil),
-		58: newServerMetric("http_queue_time_average_seconds", "Avg. HTTP queue time for last 1024 successful connections.", prometheus.GaugeValue, nil),
-		59: newServerMetric("http_connect_time_average_seconds", "Avg. HTTP connect time for last 1024 successful connections.", prometheus.GaugeValue, nil),
-		60: newServerMetric("http_response_time_average_seconds", "Avg. HTTP response time for last 1024 successful connections.", prometheus.GaugeValue, nil),
-		61: newServerMetric("http_total_time_average_seconds", "Avg. HTTP total time for last 1024 successful connections.", prometheus.GaugeValue, nil),
-	}
-
-	frontendMetrics = metrics{
-		4:  newFrontendMetric("current_sessions", "Current number of active sessions.", prometheus.GaugeValue, nil),
-		5:  newFrontendMetric("max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue, nil),
-		6:  newFrontendMetric("limit_sessions", "Configured session limit.", prometheus.GaugeValue, nil),
-		7:  newFrontendMetric("sessions_total", "Total number of sessions.", prometheus.CounterValue, nil),
-		8:  newFrontendMetric("bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue, nil),
-		9:  newFrontendMetric("bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue, nil),
-		10: newFrontendMetric("requests_denied_total", "Total of requests denied for security.", prometheus.CounterValue, nil),
-		12: newFrontendMetric("request_errors_total", "Total of request errors.", prometheus.CounterValue, nil),
-		33: newFrontendMetric("current_session_rate", "Current number of sessions per second over last elapsed second.", prometheus.GaugeValue, nil),
-		34: newFrontendMetric("limit_session_rate", "Configured limit on new sessions per second.", prometheus.GaugeValue, nil),
-		35: newFrontendMetric("max_session_rate", "Maximum observed number of sessions per second.", prometheus.GaugeValue, nil),
-		39: newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "1xx"}),
-		40: newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "2xx"}),
-		41: newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "3xx"}),
-		42: newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "4xx"}),
-		43: newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "5xx"}),
-		44: newFrontendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "other"}),
-		48: newFrontendMetric("http_requests_total", "Total HTTP requests.", prometheus.CounterValue, nil),
-		51: newFrontendMetric("compressor_bytes_in_total", "Number of HTTP response bytes fed to the compressor", prometheus.CounterValue, nil),
-		52: newFrontendMetric("compressor_bytes_out_total", "Number of HTTP response bytes emitted by the compressor", prometheus.CounterValue, nil),
-		53: newFrontendMetric("compressor_bytes_bypassed_total", "Number of bytes that bypassed the HTTP compressor", prometheus.CounterValue, nil),
-		54: newFrontendMetric("http_responses_compressed_total", "Number of HTTP responses that were compressed", prometheus.CounterValue, nil),
-		79: newFrontendMetric("connections_total", "Total number of connections", prometheus.CounterValue, nil),
-	}
-	backendMetrics = metrics{
-		2:  newBackendMetric("current_queue", "Current number of queued requests not assigned to any server.", prometheus.GaugeValue, nil),
-		3:  newBackendMetric("max_queue", "Maximum observed number of queued requests not assigned to any server.", prometheus.GaugeValue, nil),
-		4:  newBackendMetric("current_sessions", "Current number of active sessions.", prometheus.GaugeValue, nil),
-		5:  newBackendMetric("max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue, nil),
-		6:  newBackendMetric("limit_sessions", "Configured session limit.", prometheus.GaugeValue, nil),
-		7:  newBackendMetric("sessions_total", "Total number of sessions.", prometheus.CounterValue, nil),
-		8:  newBackendMetric("bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue, nil),
-		9:  newBackendMetric("bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue, nil),
-		13: newBackendMetric("connection_errors_total", "Total of connection errors.", prometheus.CounterValue, nil),
-		14: newBackendMetric("response_errors_total", "Total of response errors.", prometheus.CounterValue, nil),
-		15: newBackendMetric("retry_warnings_total", "Total of retry warnings.", prometheus.CounterValue, nil),
-		16: newBackendMetric("redispatch_warnings_total", "Total of redispatch warnings.", prometheus.CounterValue, nil),
-		17: newBackendMetric("up", "Current health status of the backend (1 = UP, 0 = DOWN).", prometheus.GaugeValue, nil),
-		18: newBackendMetric("weight", "Total weight of the servers in the backend.", prometheus.GaugeValue, nil),
-		19: newBackendMetric("current_server", "Current number of active servers", prometheus.GaugeValue, nil),
-		30: newBackendMetric("server_selected_total", "Total number of times a server was selected, either for new sessions, or when re-dispatching.", prometheus.CounterValue, nil),
-		33: newBackendMetric("current_session_rate", "Current number of sessions per second over last elapsed second.", prometheus.GaugeValue, nil),
-		35: newBackendMetric("max_session_rate", "Maximum number of sessions per second.", prometheus.GaugeValue, nil),
-		39: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "1xx"}),
-		40: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "2xx"}),
-		41: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "3xx"}),
-		42: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "4xx"}),
-		43: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "5xx"}),
-		44: newBackendMetric("http_responses_total", "Total of HTTP responses.", prometheus.CounterValue, prometheus.Labels{"code": "other"}),
-		49: newBackendMetric("client_aborts_total", "Total number of data transfers aborted by the client.", prometheus.CounterValue, nil),
-		50: newBackendMetric("server_aborts_total", "Total number of data transfers aborted by the server.", prometheus.CounterValue, nil),
-		51: newBackendMetric("compressor_bytes_in_total", "Number of HTTP response bytes fed to the compressor", prometheus.CounterValue, nil),
-		52: newBackendMetric("compressor_bytes_out_total", "Number of HTTP response bytes emitted by the compressor", prometheus.CounterValue, nil),
-		53: newBackendMetric("compressor_bytes_bypassed_total", "Number of bytes that bypassed the HTTP compressor", prometheus.CounterValue, nil),
-		54: newBackendMetric("http_responses_compressed_total", "Number of HTTP responses that were compressed", prometheus.CounterValue, nil),
-		58: newBackendMetric("http_queue_time_average_seconds", "Avg. HTTP queue time for last 1024 successful connections.", prometheus.GaugeValue, nil),
-		59: newBackendMetric("http_connect_time_average_seconds", "Avg. HTTP connect time for last 1024 successful connections.", prometheus.GaugeValue, nil),
-		60: newBackendMetric("http_response_time_average_seconds", "Avg. HTTP response time for last 1024 successful connections.", prometheus.GaugeValue, nil),
-		61: newBackendMetric("http_total_time_average_seconds", "Avg. HTTP total time for last 1024 successful connections.", prometheus.GaugeValue, nil),
-	}
-
-	haproxyInfo    = prometheus.NewDesc(prometheus.BuildFQName(namespace, "version", "info"), "HAProxy version info.", []string{"release_date", "version"}, nil)
-	haproxyUp      = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "up"), "Was the last scrape of HAProxy successful.", nil, nil)
-	haproxyIdlePct = prometheus.NewDesc(prometheus.BuildFQName(namespace, "process_idle_time", "percent"), "Time spent waiting for events instead of processing them.", nil, nil)
-)
-
-// Exporter collects HAProxy stats from the given URI and exports them using
-// the prometheus metrics package.
-type Exporter struct {
-	URI       string
-	mutex     sync.RWMutex
-	fetchInfo func() (io.ReadCloser, error)
-	fetchStat func() (io.ReadCloser, error)
-
-	up                             prometheus.Gauge
-	totalScrapes, csvParseFailures prometheus.Counter
-	serverMetrics                  map[int]metricInfo
-	excludedServerStates           map[string]struct{}
-	logger                         log.Logger
+var errorHandlingModes = map[string]promhttp.HandlerErrorHandling{
+	"http":     promhttp.HTTPErrorOnError,
+	"continue": promhttp.ContinueOnError,
+	"panic":    promhttp.PanicOnError,
 }
 
-// NewExporter returns an initialized Exporter.
-func NewExporter(uri string, sslVerify, proxyFromEnv bool, selectedServerMetrics map[int]metricInfo, excludedServerStates string, timeout time.Duration, logger log.Logger) (*Exporter, error) {
-	u, err := url.Parse(uri)
-	if err != nil {
-		return nil, err
-	}
-
-	var fetchInfo func() (io.ReadCloser, error)
-	var fetchStat func() (io.ReadCloser, error)
-	switch u.Scheme {
-	case "http", "https", "file":
-		fetchStat = fetchHTTP(uri, sslVerify, proxyFromEnv, timeout)
-	case "unix":
-		fetchInfo = fetchUnix("unix", u.Path, showInfoCmd, timeout)
-		fetchStat = fetchUnix("unix", u.Path, showStatCmd, timeout)
-	case "tcp":
-		fetchInfo = fetchUnix("tcp", u.Host, showInfoCmd, timeout)
-		fetchStat = fetchUnix("tcp", u.Host, showStatCmd, timeout)
-	default:
-		return nil, fmt.Errorf("unsupported scheme: %q", u.Scheme)
-	}
-
-	excludedServerStatesMap := map[string]struct{}{}
-	for _, f := range strings.Split(excludedServerStates, ",") {
-		excludedServerStatesMap[f] = struct{}{}
-	}
-
-	return &Exporter{
-		URI:       uri,
-		fetchInfo: fetchInfo,
-		fetchStat: fetchStat,
-		up: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Name:      "up",
-			Help:      "Was the last scrape of haproxy successful.",
-		}),
-		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
-			Namespace: namespace,
-			Name:      "exporter_scrapes_total",
-			Help:      "Current total HAProxy scrapes.",
-		}),
-		csvParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
-			Namespace: namespace,
-			Name:      "exporter_csv_parse_failures_total",
-			Help:      "Number of errors while parsing CSV.",
-		}),
-		serverMetrics:        selectedServerMetrics,
-		excludedServerStates: excludedServerStatesMap,
-		logger:               logger,
-	}, nil
-}
-
-// Describe describes all the metrics ever exported by the HAProxy exporter. It
-// implements prometheus.Collector.
-func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	for _, m := range frontendMetrics {
-		ch <- m.Desc
-	}
-	for _, m := range backendMetrics {
-		ch <- m.Desc
-	}
-	for _, m := range e.serverMetrics {
-		ch <- m.Desc
-	}
-	ch <- haproxyInfo
-	ch <- haproxyUp
-	ch <- haproxyIdlePct
-	ch <- e.totalScrapes.Desc()
-	ch <- e.csvParseFailures.Desc()
-}
-
-// Collect fetches the stats from configured HAProxy location and delivers them
-// as Prometheus metrics. It implements prometheus.Collector.
-func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	e.mutex.Lock() // To protect metrics from concurrent collects.
-	defer e.mutex.Unlock()
+func main() {
+	const pidFileHelpText = `Path to HAProxy pid file.
 
-	up := e.scrape(ch)
+	The standard process metrics get exported for the HAProxy process, prefixed
+	with 'haproxy_process_...'. Without a pid file, the PID is read with show
+	info from a unix stats socket, or looked up in /proc if HAProxy is scraped
+	on localhost. The haproxy_process exporter needs to have read access to
+	files owned by the HAProxy process. Depends on the availability of /proc.
 
-	ch <- prometheus.MustNewConstMetric(haproxyUp, prometheus.GaugeValue, up)
-	ch <- e.totalScrapes
-	ch <- e.csvParseFailures
-}
+	https://prometheus.io/docs/instrumenting/writing_clientlibs/#process-metrics.`
 
-func fetchHTTP(uri string, sslVerify, proxyFromEnv bool, timeout time.Duration) func() (io.ReadCloser, error) {
-	tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: !sslVerify}}
-	if proxyFromEnv {
-		tr.Proxy = http.ProxyFromEnvironment
-	}
-	client := http.Client{
-		Timeout:   timeout,
-		Transport: tr,
-	}
+	var (
+		configFile                  = kingpin.Flag("config.file", "Path to a YAML file configuring the exporter. Command-line flags override the options in the file.").Default("").String()
+		configAutoReload            = kingpin.Flag("config.auto-reload", "Reload the config file whenever it changes. Changes of the web section and of the cache and pid file options require a restart.").Default("false").Bool()
+		webConfig                   = webflag.AddFlags(kingpin.CommandLine, ":9101")
+		metricsPath                 = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
+		logRequests                 = kingpin.Flag("web.log-requests", "Log method, path, remote address, status and duration of every HTTP request served.").Default("false").Bool()
+		maxRequests                 = kingpin.Flag("web.max-requests", "Maximum number of parallel scrape requests. Use 0 to disable.").Default("0").Int()
+		requestTimeout              = kingpin.Flag("web.request-timeout", "Maximum duration of a scrape request before it is answered with 503 Service Unavailable. Use 0 to disable.").Default("0s").Duration()
+		enableOpenMetrics           = kingpin.Flag("web.enable-openmetrics", "Serve the OpenMetrics exposition format to clients requesting it.").Default("false").Bool()
+		errorHandling               = kingpin.Flag("web.error-handling", "How to handle errors while gathering metrics: 'http' responds with an HTTP error, 'continue' serves the metrics gathered so far, 'panic' panics.").Default("http").Enum("http", "continue", "panic")
+		compression                 = kingpin.Flag("web.compression", "Compression of the /metrics response: 'auto' gzips it when the client accepts it, 'force' always gzips it, 'off' never does.").Default("auto").Enum("auto", "force", "off")
+		disableExporterMetrics      = kingpin.Flag("web.disable-exporter-metrics", "Exclude metrics about the exporter itself (promhttp_*, process_*, go_*).").Default("false").Bool()
+		enableDebugEndpoints        = kingpin.Flag("web.enable-debug-endpoints", "Serve the raw and parsed stats of HAProxy on /debug/haproxy-stats and /debug/parsed.").Default("false").Bool()
+		remoteWriteURL              = kingpin.Flag("remote-write.url", "Push the metrics to this Prometheus remote write endpoint, in addition to serving them.").Default("").String()
+		remoteWriteInterval         = kingpin.Flag("remote-write.interval", "Interval between pushes to the remote write endpoint.").Default("15s").Duration()
+		remoteWriteTimeout          = kingpin.Flag("remote-write.timeout", "Timeout for pushes to the remote write endpoint.").Default("10s").Duration()
+		remoteWriteLabels           = kingpin.Flag("remote-write.label", "Label added to every pushed series, e.g. instance=edge-1. May be repeated.").StringMap()
+		once                        = kingpin.Flag("once", "Scrape HAProxy once, print the metrics to stdout and exit with status 0 if HAProxy was up, 1 otherwise.").Default("false").Bool()
+		logScrapeErrorsEvery        = kingpin.Flag("log.scrape-errors-every", "Log only the first and every Nth of consecutive failed scrapes of a target, and those failing with another type of error. Use 1 to log every failed scrape.").Default("10").Int()
+		namingScheme                = kingpin.Flag("metrics.naming-scheme", "Naming scheme of the metrics: 'legacy' keeps the names of previous releases, 'v2' follows the Prometheus naming best practices, 'both' exports both for migrating.").Default(collector.NamingLegacy).Enum(collector.NamingLegacy, collector.NamingV2, collector.NamingBoth)
+		haProxyScrapeURI            = kingpin.Flag("haproxy.scrape-uri", "URI on which to scrape HAProxy.").Default("http://localhost/;csv").String()
+		haProxySSLVerify            = kingpin.Flag("haproxy.ssl-verify", "Flag that enables SSL certificate verification for the scrape URI").Default("true").Bool()
+		haProxyServerMetricFields   = kingpin.Flag("haproxy.server-metric-fields", "Comma-separated list of exported server metrics, by CSV field number or metric name, e.g. current_sessions. See the list-fields command and http://cbonte.github.io/haproxy-dconv/configuration-1.5.html#9.1").Default(collector.DefaultServerMetricFields).String()
+		haProxyServerExcludeStates  = kingpin.Flag("haproxy.server-exclude-states", "Comma-separated list of exported server states to exclude. See https://cbonte.github.io/haproxy-dconv/1.8/management.html#9.1, field 17 statuus").Default("").String()
+		haProxyIncludeUnprovisioned = kingpin.Flag("haproxy.include-unprovisioned-servers", "Export the slots of server templates without an address, in MAINT (resolution) or in maintenance with an unspecified address, which are left out by default.").Default("false").Bool()
+		haProxyServerNameInclude    = kingpin.Flag("haproxy.server-name-include", "Regular expression the names of exported servers must match, anchored at both ends. By default all servers are exported.").Default("").String()
+		haProxyServerNameExclude    = kingpin.Flag("haproxy.server-name-exclude", "Regular expression the names of exported servers must not match, anchored at both ends, e.g. 'canary-.*'.").Default("").String()
+		haProxyExcludeUncheckedUp   = kingpin.Flag("haproxy.exclude-unchecked-server-up", "Leave out haproxy_server_up for servers without health checks, instead of reporting them as up.").Default("false").Bool()
+		haProxyUnlimitedValue       = kingpin.Flag("haproxy.unlimited-value", "Value exported for session, queue and rate limits HAProxy leaves empty because there is none, e.g. +Inf. By default their metrics are left out.").Default("").String()
+		haProxyTimingMilliseconds   = kingpin.Flag("haproxy.timing-milliseconds", "Export the average queue, connect, response and total times in milliseconds as well, as reported by HAProxy.").Default("false").Bool()
+		haProxyRuntimeCollectors    = kingpin.Flag("haproxy.runtime-collectors", "Comma-separated list of runtime collectors exporting the output of further commands of the stats socket. Available are "+collector.RuntimeCollectorNames()+".").Default("").String()
+		haProxySchemaMetrics        = kingpin.Flag("haproxy.schema-metrics", "Export the stats columns the exporter has no metric for as well, named after them and typed as show stat typed tells. Needs a stats socket.").Default("false").Bool()
+		haProxyScrapeLevel          = kingpin.Flag("haproxy.scrape-level", "Comma-separated list of the stats sections to export, of frontend, backend and server, e.g. 'frontend,backend' to skip the per-server series. By default all are exported.").Default("").String()
+		haProxyProxies              = kingpin.Flag("haproxy.proxies", "Comma-separated list of the names of the proxies to export, e.g. 'www,api'. Stats sockets are only asked for the rows of these proxies. By default all are exported.").Default("").String()
+		haProxyMaxServersPerBackend = kingpin.Flag("haproxy.max-servers-per-backend", "Number of servers of a backend above which its server metrics are dropped, 0 for no limit.").Default("0").Int()
+		haProxyMaxServers           = kingpin.Flag("haproxy.max-servers", "Number of servers of all backends above which the server metrics of further backends are dropped, 0 for no limit.").Default("0").Int()
+		haProxyAggregateServers     = kingpin.Flag("haproxy.aggregate-servers", "Export the server metrics summed over the servers of every backend, without the label server, instead of per server.").Default("false").Bool()
+		haProxyIDLabels             = kingpin.Flag("haproxy.id-labels", "Label the stats metrics with the numeric IDs of the proxy, server and process (iid, sid and pid), as used by the runtime API.").Default("false").Bool()
+		haProxyMultiProcess         = kingpin.Flag("haproxy.multi-process", "How to export rows of several HAProxy processes for the same proxy or server: 'none' exports them as they are, which fails on duplicates, 'label' adds the label process, 'aggregate' sums their counters and takes the maximum of other metrics.").Default(collector.MultiProcessNone).Enum(collector.MultiProcessNone, collector.MultiProcessLabel, collector.MultiProcessAggregate)
+		haProxyStreamStats          = kingpin.Flag("haproxy.stream-stats", "Export the rows of the stats as they are read, without keeping them for the JSON API, so that memory use doesn't grow with the stats.").Default("false").Bool()
+		haProxyParseWorkers         = kingpin.Flag("haproxy.parse-workers", "Number of goroutines exporting the rows of the stats of a scrape in parallel, 1 to export them on the goroutine of the scrape.").Default("1").Int()
+		haProxyMetricBuffer         = kingpin.Flag("haproxy.metric-buffer", "Number of metrics of a scrape buffered on their way to the registry, so that the scrape finishes before they are encoded. Use 0 to disable.").Default("0").Int()
+		haProxyTimeout              = kingpin.Flag("haproxy.timeout", "Timeout for trying to get stats from HAProxy.").Default("5s").Duration()
+		haProxyCacheTTL             = kingpin.Flag("haproxy.scrape-cache-ttl", "Serve scrapes arriving within this duration of a previous fetch of the same target from its result, instead of querying HAProxy again. Use 0 to disable.").Default("0s").Duration()
+		haProxyPidFile              = kingpin.Flag("haproxy.pid-file", pidFileHelpText).Default("").String()
+		httpProxyFromEnv            = kingpin.Flag("http.proxy-from-env", "Flag that enables using HTTP proxy settings from environment variables ($http_proxy, $https_proxy, $no_proxy)").Default("false").Bool()
+	)
 
-	return func() (io.ReadCloser, error) {
-		resp, err := client.Get(uri)
-		if err != nil {
-			return nil, err
-		}
-		if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
-			resp.Body.Close()
-			return nil, fmt.Errorf("HTTP status %d", resp.StatusCode)
+	promlogConfig := &promlog.Config{}
+	flag.AddFlags(kingpin.CommandLine, promlogConfig)
+	kingpin.Version(version.Print("haproxy_exporter"))
+	kingpin.HelpFlag.Short('h')
+	kingpin.Command("serve", "Serve the metrics of HAProxy.").Default()
+	kingpin.Command("list-fields", "List the CSV fields of the server metrics, to pick --haproxy.server-metric-fields.")
+	kingpin.Command("health", "Scrape HAProxy once and exit with status 0 if it succeeded, 1 if the stats can't be fetched or read completely, e.g. HAProxy is unreachable or the body is cut off, and 2 if they were read but can't be parsed.")
+	command := kingpin.Parse()
+	if command == "list-fields" {
+		if err := collector.ListServerFields(os.Stdout); err != nil {
+			os.Exit(1)
 		}
-		return resp.Body, nil
+		os.Exit(0)
 	}
-}
+	logger := collector.FromSlog(newSlogLogger(promlogConfig, os.Stderr))
 
-func fetchUnix(scheme, address, cmd string, timeout time.Duration) func() (io.ReadCloser, error) {
-	return func() (io.ReadCloser, error) {
-		f, err := net.DialTimeout(scheme, address, timeout)
+	setFlags := map[string]bool{}
+	if *configFile != "" {
+		cfg, err := loadConfig(*configFile)
 		if err != nil {
-			return nil, err
+			level.Error(logger).Log("msg", "Error loading config file", "err", err)
+			os.Exit(1)
 		}
-		if err := f.SetDeadline(time.Now().Add(timeout)); err != nil {
-			f.Close()
-			return nil, err
-		}
-		n, err := io.WriteString(f, cmd)
+		setFlags, err = flagsSetByUser(kingpin.CommandLine, os.Args[1:])
 		if err != nil {
-			f.Close()
-			return nil, err
-		}
-		if n != len(cmd) {
-			f.Close()
-			return nil, errors.New("write error")
+			level.Error(logger).Log("msg", "Error parsing command-line flags", "err", err)
+			os.Exit(1)
 		}
-		return f, nil
+		// The other options of the haproxy section are applied by
+		// newScrapeSettings, so that they can be reloaded.
+		override(haProxyCacheTTL, cfg.HAProxy.ScrapeCacheTTL, "haproxy.scrape-cache-ttl", setFlags)
+		override(haProxyPidFile, cfg.HAProxy.PidFile, "haproxy.pid-file", setFlags)
+		override(metricsPath, cfg.Web.TelemetryPath, "web.telemetry-path", setFlags)
+		override(logRequests, cfg.Web.LogRequests, "web.log-requests", setFlags)
+		override(maxRequests, cfg.Web.MaxRequests, "web.max-requests", setFlags)
+		override(requestTimeout, cfg.Web.RequestTimeout, "web.request-timeout", setFlags)
+		override(enableOpenMetrics, cfg.Web.EnableOpenMetrics, "web.enable-openmetrics", setFlags)
+		override(errorHandling, cfg.Web.ErrorHandling, "web.error-handling", setFlags)
+		override(compression, cfg.Web.Compression, "web.compression", setFlags)
+		override(disableExporterMetrics, cfg.Web.DisableExporterMetrics, "web.disable-exporter-metrics", setFlags)
+		override(enableDebugEndpoints, cfg.Web.EnableDebugEndpoints, "web.enable-debug-endpoints", setFlags)
 	}
-}
 
-func (e *Exporter) scrape(ch chan<- prometheus.Metric) (up float64) {
-	e.totalScrapes.Inc()
-	var err error
+	level.Info(logger).Log("msg", "Starting haproxy_exporter", "version", version.Info())
+	level.Info(logger).Log("msg", "Build context", "context", version.BuildContext())
 
-	if e.fetchInfo != nil {
-		infoReader, err := e.fetchInfo()
-		if err != nil {
-			level.Error(e.logger).Log("msg", "Can't scrape HAProxy", "err", err)
-			return 0
+	var cache *collector.ScrapeCache
+	if *haProxyCacheTTL > 0 {
+		cache = collector.NewScrapeCache(*haProxyCacheTTL)
+	}
+	counters := collector.NewCounters()
+	flags := haproxyFlags{
+		scrapeURI:            *haProxyScrapeURI,
+		sslVerify:            *haProxySSLVerify,
+		proxyFromEnv:         *httpProxyFromEnv,
+		serverMetricFields:   *haProxyServerMetricFields,
+		serverExcludeStates:  *haProxyServerExcludeStates,
+		includeUnprovisioned: *haProxyIncludeUnprovisioned,
+		serverNameInclude:    *haProxyServerNameInclude,
+		serverNameExclude:    *haProxyServerNameExclude,
+		excludeUncheckedUp:   *haProxyExcludeUncheckedUp,
+		unlimitedValue:       *haProxyUnlimitedValue,
+		timingMilliseconds:   *haProxyTimingMilliseconds,
+		runtimeCollectors:    *haProxyRuntimeCollectors,
+		schemaMetrics:        *haProxySchemaMetrics,
+		scrapeLevel:          *haProxyScrapeLevel,
+		proxies:              *haProxyProxies,
+		maxServersPerBackend: *haProxyMaxServersPerBackend,
+		maxServers:           *haProxyMaxServers,
+		aggregateServers:     *haProxyAggregateServers,
+		idLabels:             *haProxyIDLabels,
+		multiProcess:         *haProxyMultiProcess,
+		streamStats:          *haProxyStreamStats,
+		parseWorkers:         *haProxyParseWorkers,
+		metricBuffer:         *haProxyMetricBuffer,
+		namingScheme:         *namingScheme,
+		scrapeErrorLogEvery:  *logScrapeErrorsEvery,
+		timeout:              *haProxyTimeout,
+	}
+	settings := func(cfg *Config) (scrapeSettings, error) {
+		return newScrapeSettings(flags, cfg, setFlags, cache, counters)
+	}
+
+	registry := prometheus.NewRegistry()
+	current := &reloadableExporter{}
+	if *configFile != "" {
+		reloader := newConfigReloader(*configFile, current, settings, logger)
+		if err := reloader.reload(); err != nil {
+			level.Error(logger).Log("msg", "Error creating an exporter", "err", err)
+			os.Exit(1)
 		}
-		defer infoReader.Close()
-
-		info, err := e.parseInfo(infoReader)
-		if err != nil {
-			level.Debug(e.logger).Log("msg", "Failed parsing show info", "err", err)
-		} else {
-			ch <- prometheus.MustNewConstMetric(haproxyInfo, prometheus.GaugeValue, 1, info.ReleaseDate, info.Version)
-			if info.IdlePct != -1 {
-				ch <- prometheus.MustNewConstMetric(haproxyIdlePct, prometheus.GaugeValue, info.IdlePct)
+		registry.MustRegister(reloader)
+		if *configAutoReload && !*once && command == "serve" {
+			if err := reloader.watch(); err != nil {
+				level.Error(logger).Log("msg", "Error watching config file", "err", err)
+				os.Exit(1)
 			}
 		}
-	}
-
-	body, err := e.fetchStat()
-	if err != nil {
-		level.Error(e.logger).Log("msg", "Can't scrape HAProxy", "err", err)
-		return 0
-	}
-	defer body.Close()
-
-	reader := csv.NewReader(body)
-	reader.Comment = '#'
-
-loop:
-	for {
-		row, err := reader.Read()
-		switch err {
-		case nil:
-		case io.EOF:
-			break loop
-		default:
-			if _, ok := err.(*csv.ParseError); ok {
-				level.Error(e.logger).Log("msg", "Can't read CSV", "err", err)
-				e.csvParseFailures.Inc()
-				continue loop
-			}
-			level.Error(e.logger).Log("msg", "Unexpected error while reading CSV", "err", err)
-			return 0
-		}
-		e.parseRow(row, ch)
-	}
-	return 1
-}
-
-type versionInfo struct {
-	ReleaseDate string
-	Version     string
-	IdlePct     float64
-}
-
-func (e *Exporter) parseInfo(i io.Reader) (versionInfo, error) {
-	var version, releaseDate string
-	// idlePct value of -1 is used to indicate it's unset
-	var idlePct float64 = -1
-	s := bufio.NewScanner(i)
-	for s.Scan() {
-		line := s.Text()
-		if !strings.Contains(line, ":") {
-			continue
+	} else {
+		s, err := settings(&Config{})
+		if err != nil {
+			level.Error(logger).Log("msg", "Error filtering server metrics", "err", err)
+			os.Exit(1)
 		}
-
-		field := strings.SplitN(line, ": ", 2)
-		switch field[0] {
-		case "Release_date":
-			releaseDate = field[1]
-		case "Version":
-			version = field[1]
-		case "Idle_pct":
-			i, err := strconv.ParseFloat(field[1], 64)
-			if err == nil && i >= 0 && i <= 100 {
-				idlePct = i
-			}
+		exporters, err := s.newExporters(logger)
+		if err != nil {
+			level.Error(logger).Log("msg", "Error creating an exporter", "err", err)
+			os.Exit(1)
 		}
+		current.set(exporters, s)
 	}
-	return versionInfo{ReleaseDate: releaseDate, Version: version, IdlePct: idlePct}, s.Err()
-}
-
-func (e *Exporter) parseRow(csvRow []string, ch chan<- prometheus.Metric) {
-	if len(csvRow) < minimumCsvFieldCount {
-		level.Error(e.logger).Log("msg", "Parser received unexpected number of CSV fields", "min", minimumCsvFieldCount, "received", len(csvRow))
-		e.csvParseFailures.Inc()
-		return
+	if command == "health" {
+		os.Exit(collector.CheckHealth(current.Exporters()))
 	}
 
-	pxname, svname, status, typ := csvRow[pxnameField], csvRow[svnameField], csvRow[statusField], csvRow[typeField]
-
-	const (
-		frontend = "0"
-		backend  = "1"
-		server   = "2"
-	)
-
-	switch typ {
-	case frontend:
-		e.exportCsvFields(frontendMetrics, csvRow, ch, pxname)
-	case backend:
-		e.exportCsvFields(backendMetrics, csvRow, ch, pxname)
-	case server:
-
-		if _, ok := e.excludedServerStates[status]; !ok {
-			e.exportCsvFields(e.serverMetrics, csvRow, ch, pxname, svname)
-		}
+	registry.MustRegister(current)
+	registry.MustRegister(version.NewCollector("haproxy_exporter"))
+	if !*disableExporterMetrics {
+		registry.MustRegister(
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			collectors.NewGoCollector(),
+		)
 	}
-}
 
-func parseStatusField(value string) int64 {
-	switch value {
-	case "UP", "UP 1/3", "UP 2/3", "OPEN", "no check", "DRAIN":
-		return 1
-	case "DOWN", "DOWN 1/2", "NOLB", "MAINT", "MAINT(via)", "MAINT(resolution)":
-		return 0
-	default:
-		return 0
+	// Without a pid file, the PID is only looked up for a single target.
+	var scrapeURI string
+	if targets := current.Settings().targets; len(targets) == 1 {
+		scrapeURI = targets[0].uri
 	}
-}
-
-func (e *Exporter) exportCsvFields(metrics map[int]metricInfo, csvRow []string, ch chan<- prometheus.Metric, labels ...string) {
-	for fieldIdx, metric := range metrics {
-		if fieldIdx > len(csvRow)-1 {
-			// We can't break here because we are not looping over the fields in sorted order.
-			continue
-		}
-		valueStr := csvRow[fieldIdx]
-		if valueStr == "" {
-			continue
-		}
-
-		var err error = nil
-		var value float64
-		var valueInt int64
-
-		switch fieldIdx {
-		case statusField:
-			valueInt = parseStatusField(valueStr)
-			value = float64(valueInt)
-		case checkDurationField, qtimeMsField, ctimeMsField, rtimeMsField, ttimeMsField:
-			value, err = strconv.ParseFloat(valueStr, 64)
-			value /= 1000
-		default:
-			valueInt, err = strconv.ParseInt(valueStr, 10, 64)
-			value = float64(valueInt)
-		}
-		if err != nil {
-			level.Error(e.logger).Log("msg", "Can't parse CSV field value", "value", valueStr, "err", err)
-			e.csvParseFailures.Inc()
-			continue
-		}
-		ch <- prometheus.MustNewConstMetric(metric.Desc, metric.Type, value, labels...)
-	}
-}
-
-// filterServerMetrics returns the set of server metrics specified by the comma
-// separated filter.
-func filterServerMetrics(filter string) (map[int]metricInfo, error) {
-	metrics := map[int]metricInfo{}
-	if len(filter) == 0 {
-		return metrics, nil
+	var gatherer prometheus.Gatherer = registry
+	if *haProxyPidFile != "" {
+		gatherer = prometheus.Gatherers{registry, processGatherer{pidFile: *haProxyPidFile}}
+	} else if pidFn, source := haproxyPidFn(scrapeURI, *haProxyTimeout); pidFn != nil {
+		level.Info(logger).Log("msg", "Exporting process metrics of HAProxy", "pid_from", source)
+		procExporter := collectors.NewProcessCollector(collectors.ProcessCollectorOpts{
+			PidFn:     pidFn,
+			Namespace: namespace,
+		})
+		registry.MustRegister(procExporter)
 	}
 
-	for _, f := range strings.Split(filter, ",") {
-		field, err := strconv.Atoi(f)
+	if *once {
+		up, err := dump(os.Stdout, gatherer, current.MetricRules())
 		if err != nil {
-			return nil, fmt.Errorf("invalid server metric field number: %v", f)
+			level.Error(logger).Log("msg", "Error gathering metrics", "err", err)
+			os.Exit(1)
 		}
-		if metric, ok := serverMetrics[field]; ok {
-			metrics[field] = metric
+		if !up {
+			os.Exit(1)
 		}
+		os.Exit(0)
 	}
 
-	return metrics, nil
-}
-
-func main() {
-	const pidFileHelpText = `Path to HAProxy pid file.
-
-	If provided, the standard process metrics get exported for the HAProxy
-	process, prefixed with 'haproxy_process_...'. The haproxy_process exporter
-	needs to have read access to files owned by the HAProxy process. Depends on
-	the availability of /proc.
-
-	https://prometheus.io/docs/instrumenting/writing_clientlibs/#process-metrics.`
-
-	var (
-		webConfig                  = webflag.AddFlags(kingpin.CommandLine, ":9101")
-		metricsPath                = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
-		haProxyScrapeURI           = kingpin.Flag("haproxy.scrape-uri", "URI on which to scrape HAProxy.").Default("http://localhost/;csv").String()
-		haProxySSLVerify           = kingpin.Flag("haproxy.ssl-verify", "Flag that enables SSL certificate verification for the scrape URI").Default("true").Bool()
-		haProxyServerMetricFields  = kingpin.Flag("haproxy.server-metric-fields", "Comma-separated list of exported server metrics. See http://cbonte.github.io/haproxy-dconv/configuration-1.5.html#9.1").Default(serverMetrics.String()).String()
-		haProxyServerExcludeStates = kingpin.Flag("haproxy.server-exclude-states", "Comma-separated list of exported server states to exclude. See https://cbonte.github.io/haproxy-dconv/1.8/management.html#9.1, field 17 statuus").Default(excludedServerStates).String()
-		haProxyTimeout             = kingpin.Flag("haproxy.timeout", "Timeout for trying to get stats from HAProxy.").Default("5s").Duration()
-		haProxyPidFile             = kingpin.Flag("haproxy.pid-file", pidFileHelpText).Default("").String()
-		httpProxyFromEnv           = kingpin.Flag("http.proxy-from-env", "Flag that enables using HTTP proxy settings from environment variables ($http_proxy, $https_proxy, $no_proxy)").Default("false").Bool()
-	)
-
-	promlogConfig := &promlog.Config{}
-	flag.AddFlags(kingpin.CommandLine, promlogConfig)
-	kingpin.Version(version.Print("haproxy_exporter"))
-	kingpin.HelpFlag.Short('h')
-	kingpin.Parse()
-	logger := promlog.New(promlogConfig)
-
-	selectedServerMetrics, err := filterServerMetrics(*haProxyServerMetricFields)
-	if err != nil {
-		level.Error(logger).Log("msg", "Error filtering server metrics", "err", err)
-		os.Exit(1)
+	handlerOpts := promhttp.HandlerOpts{
+		MaxRequestsInFlight: *maxRequests,
+		Timeout:             *requestTimeout,
+		EnableOpenMetrics:   *enableOpenMetrics,
+		ErrorHandling:       errorHandlingModes[*errorHandling],
+		DisableCompression:  *compression == "off",
 	}
-
-	level.Info(logger).Log("msg", "Starting haproxy_exporter", "version", version.Info())
-	level.Info(logger).Log("msg", "Build context", "context", version.BuildContext())
-
-	exporter, err := NewExporter(*haProxyScrapeURI, *haProxySSLVerify, *httpProxyFromEnv, selectedServerMetrics, *haProxyServerExcludeStates, *haProxyTimeout, logger)
-	if err != nil {
-		level.Error(logger).Log("msg", "Error creating an exporter", "err", err)
-		os.Exit(1)
+	gatherer = withMetricRules(gatherer, current.MetricRules)
+	var metricsHandler http.Handler = promhttp.HandlerFor(gatherer, handlerOpts)
+	metricsHandler = withCollectFilter(metricsHandler, current.Exporters, current.MetricRules, handlerOpts)
+	if *compression == "force" {
+		metricsHandler = withForcedGzip(metricsHandler)
 	}
-	prometheus.MustRegister(exporter)
-	prometheus.MustRegister(version.NewCollector("haproxy_exporter"))
-
-	if *haProxyPidFile != "" {
-		procExporter := collectors.NewProcessCollector(collectors.ProcessCollectorOpts{
-			PidFn:     prometheus.NewPidFileFn(*haProxyPidFile),
-			Namespace: namespace,
-		})
-		prometheus.MustRegister(procExporter)
+	if !*disableExporterMetrics {
+		metricsHandler = promhttp.InstrumentMetricHandler(registry, metricsHandler)
 	}
-
-	http.Handle(*metricsPath, promhttp.Handler())
+	http.Handle(*metricsPath, metricsHandler)
+	http.Handle("/probe", probeHandler(current.Settings, handlerOpts, logger))
+	if *enableDebugEndpoints {
+		http.Handle("/debug/haproxy-stats", collector.RawStatsHandler(current.Exporters))
+		http.Handle("/debug/parsed", collector.ParsedStatsHandler(current.Exporters))
+	}
+	http.Handle("/api/v1/metrics", collector.SnapshotHandler(current.Exporters))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
              <head><title>Haproxy Exporter</title></head>
@@ -628,7 +284,16 @@ func main() {
              </body>
              </html>`))
 	})
-	srv := &http.Server{}
+	if *remoteWriteURL != "" {
+		w := newRemoteWriter(*remoteWriteURL, *remoteWriteInterval, *remoteWriteTimeout, *remoteWriteLabels, gatherer, logger)
+		go w.run(context.Background())
+	}
+
+	var handler http.Handler = http.DefaultServeMux
+	if *logRequests {
+		handler = withRequestLogging(handler, logger)
+	}
+	srv := &http.Server{Handler: handler}
 	if err := web.ListenAndServe(srv, webConfig, logger); err != nil {
 		level.Error(logger).Log("msg", "Error starting HTTP server", "err", err)
 		os.Exit(1)
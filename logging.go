@@ -0,0 +1,37 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/prometheus/common/promlog"
+)
+
+// newSlogLogger returns a slog.Logger writing to w in the level and format
+// configured with the promlog flags: --log.format=logfmt selects the text
+// handler of slog, json its JSON handler.
+func newSlogLogger(cfg *promlog.Config, w io.Writer) *slog.Logger {
+	var lvl slog.Level // Info, unless the level is set.
+	if cfg.Level != nil {
+		// The promlog levels are named like those of slog.
+		lvl.UnmarshalText([]byte(cfg.Level.String()))
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	if cfg.Format != nil && cfg.Format.String() == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
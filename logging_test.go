@@ -0,0 +1,43 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/promlog"
+)
+
+func TestNewSlogLogger(t *testing.T) {
+	for _, format := range []string{"logfmt", "json"} {
+		logLevel, logFormat := &promlog.AllowedLevel{}, &promlog.AllowedFormat{}
+		logLevel.Set("warn")
+		logFormat.Set(format)
+
+		var buf bytes.Buffer
+		logger := newSlogLogger(&promlog.Config{Level: logLevel, Format: logFormat}, &buf)
+		logger.Info("Dropped")
+		logger.Warn("Kept", "target", "http://localhost/")
+
+		if strings.Contains(buf.String(), "Dropped") || strings.Count(buf.String(), "\n") != 1 {
+			t.Errorf("%s: want only the warning, have %q", format, buf.String())
+		}
+		if isJSON := json.Valid(buf.Bytes()); isJSON != (format == "json") {
+			t.Errorf("%s: want JSON %v, have %q", format, format == "json", buf.String())
+		}
+	}
+}
@@ -0,0 +1,367 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parser parses the CSV stats of HAProxy, as returned by the "show
+// stat" command and the stats page with the ;csv suffix.
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// MinFields is the number of fields of the stats of HAProxy 1.4, the oldest
+// supported version. Every row has at least that many fields.
+const MinFields = 33
+
+// ErrShortRow is the error of the rows with fewer than MinFields fields.
+var ErrShortRow = errors.New("row has too few fields")
+
+// Indexes of the fields identifying a row.
+const (
+	ProxyField  = 0
+	ServerField = 1
+	TypeField   = 32
+)
+
+// Types of rows, as found in their type field.
+const (
+	TypeFrontend = "0"
+	TypeBackend  = "1"
+	TypeServer   = "2"
+	TypeListener = "3"
+)
+
+// Row is a row of the stats, describing a frontend, backend, server or
+// listener.
+type Row struct {
+	// Line is the line number of the row in the stats, starting at 1.
+	Line   int
+	Fields []string
+}
+
+// Proxy returns the name of the proxy of the row.
+func (r Row) Proxy() string { return r.Fields[ProxyField] }
+
+// Server returns the name of the server of the row, or FRONTEND or BACKEND.
+func (r Row) Server() string { return r.Fields[ServerField] }
+
+// Type returns the type of the row, one of the Type constants.
+func (r Row) Type() string { return r.Fields[TypeField] }
+
+// RowError describes why a row was skipped.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Err) }
+func (e *RowError) Unwrap() error { return e.Err }
+
+// SkippedRowsError is returned by ParseStats if some rows were skipped.
+type SkippedRowsError []*RowError
+
+func (e SkippedRowsError) Error() string {
+	if len(e) == 1 {
+		return "skipped 1 row: " + e[0].Error()
+	}
+	return fmt.Sprintf("skipped %d rows, first: %s", len(e), e[0])
+}
+
+// Stats are the parsed stats of HAProxy.
+type Stats struct {
+	// Header holds the names of the columns, from the "# pxname,svname,..."
+	// comment HAProxy puts on the first line. It is nil if there is none.
+	Header []string
+	Rows   []Row
+}
+
+// ParseStats parses the stats read from r. Comments, like the header of the
+// stats, are ignored.
+//
+// Rows which aren't valid CSV, have a different number of fields than the
+// first row, or have fewer than MinFields fields are skipped. They are
+// reported by a SkippedRowsError returned along with the other rows. Errors
+// reading r end parsing; they are returned as is with the rows read before.
+func ParseStats(r io.Reader) ([]Row, error) {
+	stats, err := Parse(r)
+	return stats.Rows, err
+}
+
+// Parse is like ParseStats, but also returns the header of the stats.
+func Parse(r io.Reader) (Stats, error) {
+	var (
+		stats   Stats
+		skipped SkippedRowsError
+	)
+	sr := NewReader(r)
+	defer sr.Close()
+	header, err := sr.Header()
+	if err != nil {
+		return stats, err
+	}
+	stats.Header = header
+	for {
+		row, err := sr.Read()
+		if err == io.EOF {
+			break
+		}
+		if rowErr, ok := err.(*RowError); ok {
+			skipped = append(skipped, rowErr)
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+		stats.Rows = append(stats.Rows, row)
+	}
+
+	if len(skipped) > 0 {
+		return stats, skipped
+	}
+	return stats, nil
+}
+
+// Reader reads the rows of the stats one at a time, so that the stats are
+// never held in memory as a whole.
+type Reader struct {
+	// If ReuseFields is true, the fields of a row are only valid until the
+	// next call of Read, which reuses their slice. Otherwise the fields of
+	// the rows are allocated in chunks for rowsPerChunk rows at once.
+	ReuseFields bool
+	// If SkipMessages is true, lines without commas are skipped instead of
+	// returned as short rows, e.g. the error messages HAProxy answers some of
+	// several show stat commands with.
+	SkipMessages bool
+
+	br         *bufio.Reader
+	headerRead bool
+	header     []string
+	line       int
+	buf        []byte
+	eof        bool
+	chunk      []string
+	fields     []string // The reused fields, with ReuseFields.
+	// The number of fields of the first row with enough of them, which all
+	// rows must have.
+	numFields int
+}
+
+// bufioPool holds the read buffers of the Readers which were closed.
+var bufioPool = sync.Pool{
+	New: func() any { return bufio.NewReaderSize(nil, 64<<10) },
+}
+
+// NewReader returns a Reader reading the stats from r. Its read buffer is
+// reused from a Reader closed before if possible.
+func NewReader(r io.Reader) *Reader {
+	br := bufioPool.Get().(*bufio.Reader)
+	br.Reset(r)
+	return &Reader{br: br, numFields: -1}
+}
+
+// Close releases the read buffer of r to be reused by other Readers. The
+// rows read before stay valid, unless ReuseFields is set. r can't be used
+// anymore.
+func (r *Reader) Close() {
+	if r.br == nil {
+		return
+	}
+	r.br.Reset(nil)
+	bufioPool.Put(r.br)
+	r.br, r.buf, r.chunk, r.fields = nil, nil, nil, nil
+}
+
+// Header returns the names of the columns of the stats, as in Stats.Header.
+// It must be called before reading the first row, or never. Lines without
+// fields before the header, e.g. the error message HAProxy answers the first
+// of several show stat commands with, are skipped.
+func (r *Reader) Header() ([]string, error) {
+	if r.headerRead {
+		return r.header, nil
+	}
+	r.headerRead = true
+	for {
+		if prefix, err := r.br.Peek(2); err == nil && string(prefix) == "# " {
+			header, err := r.br.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			r.header = ParseHeader(header)
+			r.line++
+			return r.header, nil
+		}
+		skipped, err := r.skipMessage()
+		if err != nil {
+			return nil, err
+		}
+		if !skipped {
+			return nil, nil
+		}
+	}
+}
+
+// skipMessage skips the next line if it is not a row, having no commas, and
+// returns whether it did. Lines longer than the read buffer are rows. Only as
+// much of the line as needed to tell is waited for.
+func (r *Reader) skipMessage() (bool, error) {
+	for n := 128; ; n *= 2 {
+		n = min(n, r.br.Size())
+		data, err := r.br.Peek(n)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return false, err
+		}
+		line, complete := data, err == io.EOF && len(data) > 0
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, complete = data[:i+1], true
+		}
+		switch {
+		case bytes.IndexByte(line, ',') >= 0:
+			return false, nil
+		case complete:
+			if _, err := r.br.Discard(len(line)); err != nil {
+				return false, err
+			}
+			r.line++
+			return true, nil
+		case err != nil:
+			return false, nil
+		}
+	}
+}
+
+// Read returns the next row of the stats, or io.EOF after the last one. Rows
+// which are skipped, as described for ParseStats, are returned as a
+// *RowError, after which reading goes on. Other errors end reading. The
+// fields of a row stay valid when reading the next.
+func (r *Reader) Read() (Row, error) {
+	if _, err := r.Header(); err != nil {
+		return Row{}, err
+	}
+	for !r.eof {
+		data, err := readLine(r.br, &r.buf)
+		if err == io.EOF {
+			r.eof = true
+		} else if err != nil {
+			return Row{}, err
+		}
+		r.line++
+		if len(data) == 0 || data[0] == '#' || r.SkipMessages && bytes.IndexByte(data, ',') < 0 {
+			continue
+		}
+		return r.row(data)
+	}
+	return Row{}, io.EOF
+}
+
+// row returns the row of a line of the stats.
+func (r *Reader) row(data []byte) (Row, error) {
+	line := r.line
+
+	// HAProxy doesn't quote fields, so that splitting the line at its commas
+	// does. Only lines with quotes are left to encoding/csv.
+	var fields []string
+	if bytes.IndexByte(data, '"') >= 0 {
+		var err error
+		if fields, err = splitQuoted(data); err != nil {
+			return Row{}, &RowError{Line: line, Err: err}
+		}
+	} else if n := bytes.Count(data, []byte{','}) + 1; r.ReuseFields {
+		if cap(r.fields) < n {
+			r.fields = make([]string, n)
+		}
+		fields = r.fields[:n:n]
+		split(fields, string(data))
+	} else {
+		if len(r.chunk) < n {
+			r.chunk = make([]string, n*rowsPerChunk)
+		}
+		fields = r.chunk[:n:n]
+		r.chunk = r.chunk[n:]
+		split(fields, string(data))
+	}
+
+	// A short first row, e.g. an error message of HAProxy, doesn't set the
+	// number of fields of the rows.
+	if r.numFields >= 0 && len(fields) != r.numFields {
+		return Row{}, &RowError{Line: line, Err: csv.ErrFieldCount}
+	}
+	if len(fields) < MinFields {
+		return Row{}, &RowError{Line: line, Err: fmt.Errorf("%w: %d, at least %d are required", ErrShortRow, len(fields), MinFields)}
+	}
+	if r.numFields < 0 {
+		r.numFields = len(fields)
+	}
+	return Row{Line: line, Fields: fields}, nil
+}
+
+// rowsPerChunk is the number of rows whose fields a Reader allocates at once.
+const rowsPerChunk = 256
+
+// readLine returns the next line read from br, without its line ending. It is
+// only valid until the next call, and may be kept in buf if it is longer than
+// the buffer of br.
+func readLine(br *bufio.Reader, buf *[]byte) ([]byte, error) {
+	data, err := br.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		*buf = append((*buf)[:0], data...)
+		for err == bufio.ErrBufferFull {
+			data, err = br.ReadSlice('\n')
+			*buf = append(*buf, data...)
+		}
+		data = *buf
+	}
+	data = bytes.TrimSuffix(data, []byte{'\n'})
+	data = bytes.TrimSuffix(data, []byte{'\r'})
+	return data, err
+}
+
+// split sets fields to the comma-separated fields of line, of which there are
+// exactly len(fields). The fields share the memory of line. Most fields are
+// short, so that a plain loop beats searching for the commas.
+func split(fields []string, line string) {
+	n, start := 0, 0
+	for i := 0; i < len(line); i++ {
+		if line[i] == ',' {
+			fields[n] = line[start:i]
+			n, start = n+1, i+1
+		}
+	}
+	fields[n] = line[start:]
+}
+
+// splitQuoted returns the fields of a line with quotes, parsed as CSV.
+func splitQuoted(line []byte) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(line))
+	r.FieldsPerRecord = -1
+	fields, err := r.Read()
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		return nil, pe.Err
+	}
+	return fields, err
+}
+
+// ParseHeader returns the column names of a "# pxname,svname,..." header line.
+// The trailing comma HAProxy ends every line with doesn't make for an empty
+// column.
+func ParseHeader(line string) []string {
+	line = strings.TrimSuffix(strings.TrimPrefix(strings.TrimRight(line, "\r\n"), "# "), ",")
+	return strings.Split(line, ",")
+}
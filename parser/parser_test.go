@@ -0,0 +1,309 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const (
+	frontendRow = "foo,FRONTEND,0,0,0,0,,0,0,0,,0,,0,0,0,0,OPEN,,,,,,,,,1,2,0,,,,0,0,0,0,\n"
+	serverRow   = "foo,foo-instance-0,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,0,0,0,\n"
+)
+
+func TestParseStats(t *testing.T) {
+	data := "# pxname,svname,qcur\n" + frontendRow + serverRow
+
+	rows, err := ParseStats(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(rows); want != have {
+		t.Fatalf("want %d rows, have %d", want, have)
+	}
+	if want, have := TypeFrontend, rows[0].Type(); want != have {
+		t.Errorf("want type %q, have %q", want, have)
+	}
+	if want, have := "foo-instance-0", rows[1].Server(); want != have {
+		t.Errorf("want server %q, have %q", want, have)
+	}
+	if want, have := 3, rows[1].Line; want != have {
+		t.Errorf("want line %d, have %d", want, have)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	stats, err := Parse(strings.NewReader("# pxname,svname,qcur,\n" + frontendRow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []string{"pxname", "svname", "qcur"}, stats.Header; !reflect.DeepEqual(want, have) {
+		t.Errorf("want header %q, have %q", want, have)
+	}
+	if want, have := 2, stats.Rows[0].Line; want != have {
+		t.Errorf("want line %d, have %d", want, have)
+	}
+
+	stats, err = Parse(strings.NewReader(frontendRow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Header != nil {
+		t.Errorf("want no header, have %q", stats.Header)
+	}
+}
+
+func TestParseStatsSkippedRows(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		line  int
+		rows  int
+		short bool
+	}{
+		{name: "too few fields", data: "not,enough,fields\n", line: 1, rows: 0, short: true},
+		{name: "missing comma", data: frontendRow + "foo,bug-missing-comma,0,0,0,0,,0,0,0,,0,,0,0,0,0,DRAIN (agent)1,1,0,0,0,5007,0,,1,8,1,,0,,2,0,0,0,\n", line: 2, rows: 1},
+		{name: "bare quote", data: frontendRow + `foo,"bar"baz,` + serverRow[16:], line: 2, rows: 1},
+	}
+
+	for _, tt := range tests {
+		rows, err := ParseStats(strings.NewReader(tt.data))
+		var skipped SkippedRowsError
+		if !errors.As(err, &skipped) || len(skipped) != 1 {
+			t.Errorf("%s: want one skipped row, have %v", tt.name, err)
+			continue
+		}
+		if want, have := tt.line, skipped[0].Line; want != have {
+			t.Errorf("%s: want skipped line %d, have %d", tt.name, want, have)
+		}
+		if want, have := tt.rows, len(rows); want != have {
+			t.Errorf("%s: want %d rows, have %d", tt.name, want, have)
+		}
+		if want, have := tt.short, errors.Is(skipped[0], ErrShortRow); want != have {
+			t.Errorf("%s: want short row %t, have %t", tt.name, want, have)
+		}
+	}
+}
+
+func TestParseStatsLines(t *testing.T) {
+	long := strings.TrimSuffix(serverRow, ",\n") + strings.Repeat(",", 100000) + "\n"
+	tests := []struct {
+		name   string
+		data   string
+		fields []int // The numbers of fields of the rows.
+		server string
+	}{
+		{name: "CRLF", data: strings.ReplaceAll(frontendRow+serverRow, "\n", "\r\n"), fields: []int{37, 37}, server: "foo-instance-0"},
+		{name: "no final newline", data: frontendRow + strings.TrimSuffix(serverRow, "\n"), fields: []int{37, 37}, server: "foo-instance-0"},
+		{name: "comments and empty lines", data: frontendRow + "\n# comment\n\n" + serverRow, fields: []int{37, 37}, server: "foo-instance-0"},
+		{name: "quoted fields", data: frontendRow + `foo,"bar,""baz""",` + serverRow[19:], fields: []int{37, 37}, server: `bar,"baz"`},
+		{name: "long line", data: long, fields: []int{100036}, server: "foo-instance-0"},
+	}
+
+	for _, tt := range tests {
+		rows, err := ParseStats(strings.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var fields []int
+		for _, r := range rows {
+			fields = append(fields, len(r.Fields))
+		}
+		if !reflect.DeepEqual(tt.fields, fields) {
+			t.Errorf("%s: want rows with %v fields, have %v", tt.name, tt.fields, fields)
+			continue
+		}
+		if have := rows[len(rows)-1].Server(); have != tt.server {
+			t.Errorf("%s: want server %q, have %q", tt.name, tt.server, have)
+		}
+	}
+}
+
+func TestParseStatsReadError(t *testing.T) {
+	_, err := ParseStats(iotest.TimeoutReader(strings.NewReader(frontendRow)))
+	var skipped SkippedRowsError
+	if err == nil || errors.As(err, &skipped) {
+		t.Errorf("want read error, have %v", err)
+	}
+}
+
+func FuzzParseStats(f *testing.F) {
+	f.Add([]byte(frontendRow + serverRow))
+	f.Add([]byte("# pxname,svname\n" + frontendRow))
+	f.Add([]byte("not,enough,fields"))
+	f.Add([]byte(`foo,"bar""baz",` + serverRow[16:]))
+	f.Add([]byte(`foo,"unterminated` + "\n" + frontendRow))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stats, _ := Parse(bytes.NewReader(data))
+		for _, r := range stats.Rows {
+			if len(r.Fields) < MinFields {
+				t.Errorf("row on line %d has only %d fields", r.Line, len(r.Fields))
+			}
+			// The accessors must not panic.
+			_, _, _ = r.Proxy(), r.Server(), r.Type()
+		}
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	header := "# pxname,svname,qcur\n"
+	data := []byte(header + frontendRow + strings.Repeat(serverRow, 10000))
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	data := "# pxname,svname\n" + frontendRow + "foo,bar\n" + frontendRow
+	r := NewReader(strings.NewReader(data))
+	header, err := r.Header()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header) != 2 || header[0] != "pxname" {
+		t.Errorf("unexpected header %q", header)
+	}
+
+	var lines []int
+	var skipped []int
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			skipped = append(skipped, rowErr.Line)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, row.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{2, 4}) || !reflect.DeepEqual(skipped, []int{3}) {
+		t.Errorf("want rows of lines [2 4] and skipped [3], have %v and %v", lines, skipped)
+	}
+}
+
+func TestReaderMessages(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		skipMessages bool
+		header       int // The number of columns of the header.
+		lines        []int
+		skipped      []int
+	}{
+		{name: "message before header", data: "No such proxy.\n\n# pxname,svname\n" + frontendRow, header: 2, lines: []int{4}},
+		{name: "message without header", data: "No such proxy.\n" + frontendRow, lines: []int{2}},
+		{name: "message at end", data: frontendRow + "No such proxy.", lines: []int{1}, skipped: []int{2}},
+		{name: "skipped messages", data: "# pxname,svname\n" + frontendRow + "\nNo such proxy.\n# pxname,svname\n" + serverRow, skipMessages: true, header: 2, lines: []int{2, 6}},
+		{name: "reported messages", data: "# pxname,svname\n" + frontendRow + "\nNo such proxy.\n# pxname,svname\n" + serverRow, header: 2, lines: []int{2, 6}, skipped: []int{4}},
+	}
+
+	for _, tt := range tests {
+		r := NewReader(strings.NewReader(tt.data))
+		r.SkipMessages = tt.skipMessages
+		header, err := r.Header()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := tt.header, len(header); want != have {
+			t.Errorf("%s: want %d columns, have %d", tt.name, want, have)
+		}
+		var lines, skipped []int
+		for {
+			row, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			var rowErr *RowError
+			if errors.As(err, &rowErr) {
+				skipped = append(skipped, rowErr.Line)
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, row.Line)
+		}
+		if !reflect.DeepEqual(lines, tt.lines) || !reflect.DeepEqual(skipped, tt.skipped) {
+			t.Errorf("%s: want rows of lines %v and skipped %v, have %v and %v", tt.name, tt.lines, tt.skipped, lines, skipped)
+		}
+		r.Close()
+	}
+}
+
+func TestReaderReuseFields(t *testing.T) {
+	data := strings.Repeat(frontendRow, 10)
+	read := func(r *Reader) int {
+		n := 0
+		for {
+			row, err := r.Read()
+			if err == io.EOF {
+				return n
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if row.Proxy() != "foo" {
+				t.Fatalf("unexpected proxy %q", row.Proxy())
+			}
+			n++
+		}
+	}
+
+	r := NewReader(strings.NewReader(data))
+	r.ReuseFields = true
+	first, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &first.Fields[0] != &second.Fields[0] {
+		t.Error("want the fields of the rows to be reused")
+	}
+	r.Close()
+
+	// Only the line of every row is allocated, besides the Reader.
+	allocs := testing.AllocsPerRun(10, func() {
+		r := NewReader(strings.NewReader(data))
+		r.ReuseFields = true
+		if n := read(r); n != 10 {
+			t.Fatalf("want 10 rows, have %d", n)
+		}
+		r.Close()
+	})
+	if allocs > 14 {
+		t.Errorf("want at most 14 allocations reading 10 rows, have %v", allocs)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/haproxy_exporter/collector"
+)
+
+// probeHandler returns a handler scraping the HAProxy given by the target
+// query parameter, in the manner of the blackbox exporter. The module
+// parameter selects the options used for the scrape from the modules of the
+// current settings; without it their probe options are used.
+func probeHandler(settings func() scrapeSettings, handlerOpts promhttp.HandlerOpts, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := settings()
+		params := r.URL.Query()
+
+		target := params.Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+
+		opts := s.probeOpts
+		if name := params.Get("module"); name != "" {
+			var ok bool
+			if opts, ok = s.modules[name]; !ok {
+				http.Error(w, fmt.Sprintf("unknown module %q", name), http.StatusBadRequest)
+				return
+			}
+		}
+
+		logger := log.With(logger, "target", target)
+		e, err := collector.NewExporter(target, opts, logger)
+		if err != nil {
+			level.Debug(logger).Log("msg", "Error creating an exporter", "err", err)
+			http.Error(w, fmt.Sprintf("invalid target %q: %s", target, err), http.StatusBadRequest)
+			return
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(e)
+		rules := func() []metricRule { return s.metricRules }
+		promhttp.HandlerFor(withMetricRules(registry, rules), handlerOpts).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/haproxy_exporter/collector"
+)
+
+func TestProbeHandler(t *testing.T) {
+	const data = "foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer s.Close()
+
+	defaultOpts := collector.ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, Timeout: 5 * time.Second}
+	withAuth := defaultOpts
+	withAuth.Username, withAuth.Password = "admin", "secret"
+	settings := scrapeSettings{probeOpts: defaultOpts, modules: map[string]collector.ExporterOpts{"auth": withAuth}}
+	h := probeHandler(func() scrapeSettings { return settings }, promhttp.HandlerOpts{}, log.NewNopLogger())
+
+	tests := []struct {
+		query    string
+		status   int
+		contains string
+	}{
+		{query: "module=auth&target=" + url.QueryEscape(s.URL), status: http.StatusOK, contains: "haproxy_up 1"},
+		{query: "target=" + url.QueryEscape(s.URL), status: http.StatusOK, contains: `haproxy_exporter_scrape_errors_total{type="auth"} 1`},
+		{query: "module=bogus&target=" + url.QueryEscape(s.URL), status: http.StatusBadRequest},
+		{query: "target=gopher://gopher.quux.org", status: http.StatusBadRequest},
+		{query: "module=auth", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/probe?"+tt.query, nil))
+		if tt.status != rec.Code {
+			t.Errorf("%s: want status %d, have %d", tt.query, tt.status, rec.Code)
+			continue
+		}
+		if !strings.Contains(rec.Body.String(), tt.contains) {
+			t.Errorf("%s: want %q in response, have %q", tt.query, tt.contains, rec.Body.String())
+		}
+	}
+}
+
+func TestProbeHandlerCache(t *testing.T) {
+	const data = "foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer s.Close()
+
+	// The modules share a cache, but not the stats fetched with the
+	// credentials of another module.
+	defaultOpts := collector.ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, Timeout: 5 * time.Second, Cache: collector.NewScrapeCache(time.Hour)}
+	withAuth, withOtherAuth := defaultOpts, defaultOpts
+	withAuth.Username, withAuth.Password = "admin", "secret"
+	withOtherAuth.Username, withOtherAuth.Password = "admin", "guess"
+	settings := scrapeSettings{probeOpts: defaultOpts, modules: map[string]collector.ExporterOpts{"auth": withAuth, "other": withOtherAuth}}
+	h := probeHandler(func() scrapeSettings { return settings }, promhttp.HandlerOpts{}, log.NewNopLogger())
+
+	for _, tt := range []struct{ query, contains string }{
+		{query: "module=auth&target=" + url.QueryEscape(s.URL), contains: "haproxy_up 1"},
+		{query: "module=auth&target=" + url.QueryEscape(s.URL), contains: "haproxy_exporter_cache_hits_total 1"},
+		{query: "target=" + url.QueryEscape(s.URL), contains: "haproxy_up 0"},
+		{query: "module=other&target=" + url.QueryEscape(s.URL), contains: "haproxy_up 0"},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/probe?"+tt.query, nil))
+		if !strings.Contains(rec.Body.String(), tt.contains) {
+			t.Errorf("%s: want %q in response, have %q", tt.query, tt.contains, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/haproxy_exporter/collector"
+)
+
+// procRoot is the mount point of procfs, in which HAProxy processes are
+// looked up.
+var procRoot = "/proc"
+
+// processGatherer gathers the process metrics of the HAProxy processes of a
+// pid file, which has a PID per process with nbproc. With several PIDs, their
+// metrics are told apart by the label process, the position of the PID in the
+// file counting from 1. It is a Gatherer rather than a Collector, as the
+// processes are only known when gathering.
+type processGatherer struct {
+	pidFile string
+}
+
+// Gather gathers the metrics of every process of the pid file. Like the
+// process collector, it gathers nothing if the file can't be read.
+func (g processGatherer) Gather() ([]*dto.MetricFamily, error) {
+	pids, err := readPidFile(g.pidFile)
+	if err != nil {
+		return nil, nil
+	}
+	reg := prometheus.NewRegistry()
+	for i, pid := range pids {
+		pid := pid
+		r := prometheus.Registerer(reg)
+		if len(pids) > 1 {
+			r = prometheus.WrapRegistererWith(prometheus.Labels{"process": strconv.Itoa(i + 1)}, reg)
+		}
+		if err := r.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{
+			PidFn:     func() (int, error) { return pid, nil },
+			Namespace: namespace,
+		})); err != nil {
+			return nil, err
+		}
+	}
+	return reg.Gather()
+}
+
+// readPidFile returns the PIDs of a pid file, which are separated by
+// whitespace.
+func readPidFile(path string) ([]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, f := range strings.Fields(string(data)) {
+		pid, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PID %q in %s", f, path)
+		}
+		pids = append(pids, pid)
+	}
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("no PID in %s", path)
+	}
+	return pids, nil
+}
+
+// haproxyPidFn returns the function finding the PID of HAProxy for its process
+// metrics without a pid file, and where it finds it. The PID is read from the
+// Pid field of show info if HAProxy is scraped on a unix socket, and otherwise
+// looked up in procRoot if HAProxy is scraped on this host. It returns nil if
+// HAProxy runs elsewhere.
+func haproxyPidFn(scrapeURI string, timeout time.Duration) (func() (int, error), string) {
+	u, err := url.Parse(scrapeURI)
+	if err != nil {
+		return nil, ""
+	}
+	if u.Scheme == "unix" {
+		return pidFromInfo(collector.SocketFetcher("unix", u.Path, "show info\n", timeout), timeout), "show info"
+	}
+	if ip := net.ParseIP(u.Hostname()); u.Hostname() == "localhost" || ip != nil && ip.IsLoopback() {
+		return findHAProxyPid, procRoot
+	}
+	return nil, ""
+}
+
+// pidFromInfo returns a function reading the PID of HAProxy from the Pid field
+// of the show info fetched by fetch.
+func pidFromInfo(fetch collector.Fetcher, timeout time.Duration) func() (int, error) {
+	return func() (int, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		r, err := fetch.Fetch(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			if pid, ok := strings.CutPrefix(s.Text(), "Pid: "); ok {
+				return strconv.Atoi(pid)
+			}
+		}
+		if err := s.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("show info lacks the Pid field")
+	}
+}
+
+// findHAProxyPid returns the PID of the process named haproxy in procRoot. Of a
+// master process and its worker, it returns the worker, which handles the
+// traffic.
+func findHAProxyPid() (int, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return 0, err
+	}
+	parents := map[int]int{} // By PID, of the haproxy processes.
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		// Processes exiting meanwhile are skipped.
+		comm, ppid, err := readProcStat(pid)
+		if err != nil || comm != "haproxy" {
+			continue
+		}
+		parents[pid] = ppid
+	}
+
+	var workers []int
+	for pid, ppid := range parents {
+		if _, ok := parents[ppid]; ok {
+			workers = append(workers, pid)
+		}
+	}
+	switch {
+	case len(parents) == 0:
+		return 0, errors.New("no HAProxy process found")
+	case len(parents) == 1:
+		for pid := range parents {
+			return pid, nil
+		}
+	case len(workers) == 1:
+		return workers[0], nil
+	}
+	return 0, fmt.Errorf("found %d HAProxy processes, choose one with --haproxy.pid-file", len(parents))
+}
+
+// readProcStat returns the command name and the parent PID of a process from
+// its stat file, which starts with "<pid> (<comm>) <state> <ppid>".
+func readProcStat(pid int) (string, int, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return "", 0, err
+	}
+	// The command name may contain spaces and parentheses itself.
+	start, end := bytes.IndexByte(data, '('), bytes.LastIndexByte(data, ')')
+	if start < 0 || end < start {
+		return "", 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 2 {
+		return "", 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid stat of process %d: %w", pid, err)
+	}
+	return string(data[start+1 : end]), ppid, nil
+}
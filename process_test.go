@@ -0,0 +1,203 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// writeProcStats writes the stat files of processes with the given command
+// names and parent PIDs, by PID, to a new procRoot.
+func writeProcStats(t *testing.T, procs map[int][2]string) {
+	t.Helper()
+	procRoot = t.TempDir()
+	t.Cleanup(func() { procRoot = "/proc" })
+	for pid, p := range procs {
+		dir := filepath.Join(procRoot, fmt.Sprint(pid))
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		stat := fmt.Sprintf("%d (%s) S %s 1 1 0 -1 4194560\n", pid, p[0], p[1])
+		if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadPidFile(t *testing.T) {
+	tests := []struct {
+		content string
+		pids    []int
+	}{
+		{content: "42\n", pids: []int{42}},
+		{content: "42\n43\n44\n", pids: []int{42, 43, 44}},
+		{content: "\n"},
+		{content: "42\nfoo\n"},
+	}
+
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "haproxy.pid")
+		if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		pids, err := readPidFile(file)
+		if tt.pids == nil {
+			if err == nil {
+				t.Errorf("%q: want error, have PIDs %v", tt.content, pids)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.content, err)
+		} else if !reflect.DeepEqual(tt.pids, pids) {
+			t.Errorf("%q: want PIDs %v, have %v", tt.content, tt.pids, pids)
+		}
+	}
+}
+
+func TestProcessGatherer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process metrics need /proc")
+		return
+	}
+	tests := []struct {
+		pids   string
+		labels []string
+	}{
+		{pids: fmt.Sprintf("%d\n", os.Getpid()), labels: []string{""}},
+		{pids: fmt.Sprintf("%d\n%d\n", os.Getpid(), os.Getpid()), labels: []string{"1", "2"}},
+	}
+
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "haproxy.pid")
+		if err := os.WriteFile(file, []byte(tt.pids), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mfs, err := processGatherer{pidFile: file}.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var labels []string
+		for _, mf := range mfs {
+			if mf.GetName() != "haproxy_process_start_time_seconds" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				label := ""
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "process" {
+						label = l.GetValue()
+					}
+				}
+				labels = append(labels, label)
+			}
+		}
+		if !reflect.DeepEqual(tt.labels, labels) {
+			t.Errorf("%q: want process labels %q, have %q", tt.pids, tt.labels, labels)
+		}
+	}
+}
+
+func TestFindHAProxyPid(t *testing.T) {
+	tests := []struct {
+		name  string
+		procs map[int][2]string
+		pid   int
+	}{
+		{name: "single", procs: map[int][2]string{1: {"init", "0"}, 42: {"haproxy", "1"}}, pid: 42},
+		{name: "master and worker", procs: map[int][2]string{1: {"init", "0"}, 42: {"haproxy", "1"}, 43: {"haproxy", "42"}, 44: {"my (haproxy)", "1"}}, pid: 43},
+		{name: "none", procs: map[int][2]string{1: {"init", "0"}}},
+		{name: "unrelated", procs: map[int][2]string{42: {"haproxy", "1"}, 50: {"haproxy", "1"}}},
+	}
+
+	for _, tt := range tests {
+		writeProcStats(t, tt.procs)
+		pid, err := findHAProxyPid()
+		if tt.pid == 0 {
+			if err == nil {
+				t.Errorf("%s: want error, have PID %d", tt.name, pid)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if pid != tt.pid {
+			t.Errorf("%s: want PID %d, have %d", tt.name, tt.pid, pid)
+		}
+	}
+}
+
+func TestPidFromInfo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not on windows")
+		return
+	}
+	socket := filepath.Join(t.TempDir(), "haproxy.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("can't start test server: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			if cmd, err := bufio.NewReader(c).ReadString('\n'); err == nil && cmd == "show info\n" {
+				c.Write([]byte("Name: HAProxy\nVersion: 2.8.0\nPid: 1234\n"))
+			}
+			c.Close()
+		}
+	}()
+
+	pidFn, source := haproxyPidFn("unix:"+socket, 5*time.Second)
+	if source != "show info" {
+		t.Fatalf("want PID from show info, have %q", source)
+	}
+	pid, err := pidFn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid != 1234 {
+		t.Errorf("want PID 1234, have %d", pid)
+	}
+}
+
+func TestHAProxyPidFn(t *testing.T) {
+	tests := []struct {
+		uri, source string
+	}{
+		{uri: "unix:/run/haproxy.sock", source: "show info"},
+		{uri: "http://localhost/;csv", source: "/proc"},
+		{uri: "tcp://127.0.0.1:9999", source: "/proc"},
+		{uri: "http://haproxy.example.com/;csv"},
+		{uri: ""},
+	}
+
+	for _, tt := range tests {
+		pidFn, source := haproxyPidFn(tt.uri, time.Second)
+		if source != tt.source || (pidFn == nil) != (tt.source == "") {
+			t.Errorf("%q: want PID from %q, have %q", tt.uri, tt.source, source)
+		}
+	}
+}
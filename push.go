@@ -0,0 +1,209 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// remoteWriter periodically pushes the metrics of a Gatherer to a Prometheus
+// remote write endpoint, for HAProxy hosts which can't be scraped.
+type remoteWriter struct {
+	url      string
+	interval time.Duration
+	labels   map[string]string
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	logger   log.Logger
+}
+
+func newRemoteWriter(url string, interval, timeout time.Duration, labels map[string]string, g prometheus.Gatherer, logger log.Logger) *remoteWriter {
+	return &remoteWriter{
+		url:      url,
+		interval: interval,
+		labels:   labels,
+		gatherer: g,
+		client:   &http.Client{Timeout: timeout},
+		logger:   logger,
+	}
+}
+
+// run pushes the metrics every interval until ctx is done.
+func (w *remoteWriter) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.push(ctx); err != nil {
+			level.Error(w.logger).Log("msg", "Error pushing metrics", "url", w.url, "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// push gathers the metrics once and sends them.
+func (w *remoteWriter) push(ctx context.Context) error {
+	mfs, err := w.gatherer.Gather()
+	if err != nil && len(mfs) == 0 {
+		return err
+	}
+	if err != nil {
+		level.Warn(w.logger).Log("msg", "Error gathering some metrics, pushing the others", "err", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.url, bytes.NewReader(snappy.Encode(nil, encodeWriteRequest(mfs, w.labels, time.Now()))))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// encodeWriteRequest returns the remote write protobuf WriteRequest holding a
+// sample at ts for every series of mfs, with extra labels added.
+func encodeWriteRequest(mfs []*dto.MetricFamily, extra map[string]string, ts time.Time) []byte {
+	var buf []byte
+	ms := ts.UnixMilli()
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			labels := map[string]string{}
+			for k, v := range extra {
+				labels[k] = v
+			}
+			for _, lp := range m.Label {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			for _, s := range samples(mf.GetName(), mf.GetType(), m) {
+				series := encodeTimeSeries(s.name, labels, s.extra, s.value, ms)
+				buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+				buf = protowire.AppendBytes(buf, series)
+			}
+		}
+	}
+	return buf
+}
+
+// sample is a single value of a metric, as exposed in the text format.
+type sample struct {
+	name  string
+	extra [2]string // An additional label pair, e.g. le or quantile.
+	value float64
+}
+
+func samples(name string, t dto.MetricType, m *dto.Metric) []sample {
+	switch t {
+	case dto.MetricType_COUNTER:
+		return []sample{{name: name, value: m.GetCounter().GetValue()}}
+	case dto.MetricType_GAUGE:
+		return []sample{{name: name, value: m.GetGauge().GetValue()}}
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		res := []sample{}
+		for _, q := range s.Quantile {
+			res = append(res, sample{name: name, extra: [2]string{"quantile", formatFloat(q.GetQuantile())}, value: q.GetValue()})
+		}
+		return append(res,
+			sample{name: name + "_sum", value: s.GetSampleSum()},
+			sample{name: name + "_count", value: float64(s.GetSampleCount())},
+		)
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		res := []sample{}
+		for _, b := range h.Bucket {
+			res = append(res, sample{name: name + "_bucket", extra: [2]string{"le", formatFloat(b.GetUpperBound())}, value: float64(b.GetCumulativeCount())})
+		}
+		if n := len(h.Bucket); n == 0 || !math.IsInf(h.Bucket[n-1].GetUpperBound(), 1) {
+			res = append(res, sample{name: name + "_bucket", extra: [2]string{"le", "+Inf"}, value: float64(h.GetSampleCount())})
+		}
+		return append(res,
+			sample{name: name + "_sum", value: h.GetSampleSum()},
+			sample{name: name + "_count", value: float64(h.GetSampleCount())},
+		)
+	default:
+		return []sample{{name: name, value: m.GetUntyped().GetValue()}}
+	}
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// encodeTimeSeries returns a protobuf TimeSeries with a single sample. The
+// labels are sorted by name, as required by the remote write protocol.
+func encodeTimeSeries(name string, labels map[string]string, extra [2]string, value float64, ms int64) []byte {
+	all := make(map[string]string, len(labels)+2)
+	for k, v := range labels {
+		all[k] = v
+	}
+	if extra[0] != "" {
+		all[extra[0]] = extra[1]
+	}
+	all["__name__"] = name
+	names := make([]string, 0, len(all))
+	for k := range all {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var buf []byte
+	for _, k := range names {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, k)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, all[k])
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, label)
+	}
+
+	var s []byte
+	s = protowire.AppendTag(s, 1, protowire.Fixed64Type)
+	s = protowire.AppendFixed64(s, math.Float64bits(value))
+	s = protowire.AppendTag(s, 2, protowire.VarintType)
+	s = protowire.AppendVarint(s, uint64(ms))
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+	return protowire.AppendBytes(buf, s)
+}
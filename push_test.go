@@ -0,0 +1,150 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodedSeries is a TimeSeries decoded from a remote write request.
+type decodedSeries struct {
+	labels map[string]string
+	value  float64
+	ms     int64
+}
+
+// decodeWriteRequest decodes the fields of a WriteRequest used by
+// encodeWriteRequest.
+func decodeWriteRequest(t *testing.T, buf []byte) []decodedSeries {
+	t.Helper()
+	fields := func(b []byte, f func(num protowire.Number, typ protowire.Type, b []byte) int) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				t.Fatal(protowire.ParseError(n))
+			}
+			b = b[n:]
+			n = f(num, typ, b)
+			if n < 0 {
+				t.Fatal(protowire.ParseError(n))
+			}
+			b = b[n:]
+		}
+	}
+
+	var res []decodedSeries
+	fields(buf, func(_ protowire.Number, _ protowire.Type, b []byte) int {
+		ts, n := protowire.ConsumeBytes(b)
+		s := decodedSeries{labels: map[string]string{}}
+		fields(ts, func(num protowire.Number, _ protowire.Type, b []byte) int {
+			msg, n := protowire.ConsumeBytes(b)
+			if num == 1 {
+				var name, value string
+				fields(msg, func(num protowire.Number, _ protowire.Type, b []byte) int {
+					v, n := protowire.ConsumeString(b)
+					if num == 1 {
+						name = v
+					} else {
+						value = v
+					}
+					return n
+				})
+				s.labels[name] = value
+				return n
+			}
+			fields(msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+				if num == 1 {
+					v, n := protowire.ConsumeFixed64(b)
+					s.value = math.Float64frombits(v)
+					return n
+				}
+				v, n := protowire.ConsumeVarint(b)
+				s.ms = int64(v)
+				return n
+			})
+			return n
+		})
+		res = append(res, s)
+		return n
+	})
+	return res
+}
+
+func TestRemoteWriter(t *testing.T) {
+	received := make(chan []byte, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			http.Error(w, "not snappy", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		buf, err := snappy.Decode(nil, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received <- buf
+	}))
+	defer s.Close()
+
+	registry := prometheus.NewRegistry()
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "haproxy_up", Help: "Help."}, []string{"role"})
+	g.WithLabelValues("edge").Set(1)
+	registry.MustRegister(g)
+
+	w := newRemoteWriter(s.URL, time.Minute, time.Second, map[string]string{"instance": "edge-1"}, registry, log.NewNopLogger())
+	start := time.Now()
+	if err := w.push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	series := decodeWriteRequest(t, <-received)
+	if want, have := 1, len(series); want != have {
+		t.Fatalf("want %d series, have %d", want, have)
+	}
+	wantLabels := map[string]string{"__name__": "haproxy_up", "instance": "edge-1", "role": "edge"}
+	if !reflect.DeepEqual(wantLabels, series[0].labels) {
+		t.Errorf("want labels %v, have %v", wantLabels, series[0].labels)
+	}
+	if want, have := 1.0, series[0].value; want != have {
+		t.Errorf("want value %v, have %v", want, have)
+	}
+	if series[0].ms < start.UnixMilli() {
+		t.Errorf("want timestamp after %d, have %d", start.UnixMilli(), series[0].ms)
+	}
+}
+
+func TestRemoteWriterError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer s.Close()
+
+	w := newRemoteWriter(s.URL, time.Minute, time.Second, nil, prometheus.NewRegistry(), log.NewNopLogger())
+	if err := w.push(context.Background()); err == nil {
+		t.Errorf("expected error for HTTP status 400")
+	}
+}
@@ -0,0 +1,524 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/haproxy_exporter/collector"
+	"gopkg.in/yaml.v2"
+)
+
+// haproxyFlags holds the values of the command-line flags which can be
+// overridden by the haproxy section of the config file. They are re-applied on
+// every reload of the config file.
+type haproxyFlags struct {
+	scrapeURI            string
+	sslVerify            bool
+	proxyFromEnv         bool
+	serverMetricFields   string
+	serverExcludeStates  string
+	includeUnprovisioned bool
+	serverNameInclude    string
+	serverNameExclude    string
+	excludeUncheckedUp   bool
+	unlimitedValue       string
+	timingMilliseconds   bool
+	runtimeCollectors    string
+	schemaMetrics        bool
+	scrapeLevel          string
+	proxies              string
+	maxServersPerBackend int
+	maxServers           int
+	aggregateServers     bool
+	idLabels             bool
+	multiProcess         string
+	streamStats          bool
+	parseWorkers         int
+	metricBuffer         int
+	namingScheme         string
+	scrapeErrorLogEvery  int
+	timeout              time.Duration
+}
+
+// scrapeSettings are the settings of the scrapes derived from the flags and
+// the config file.
+type scrapeSettings struct {
+	targets     []scrapeTarget
+	probeOpts   collector.ExporterOpts
+	modules     map[string]collector.ExporterOpts
+	metricRules []metricRule
+	// hash is the hash of the effective settings, see settingsHash.
+	hash [sha256.Size]byte
+}
+
+// scrapeTarget is an HAProxy scraped on the metrics endpoint.
+type scrapeTarget struct {
+	uri  string
+	opts collector.ExporterOpts
+}
+
+// newExporters returns an Exporter for every target of s. With several targets
+// their log messages and the metrics of their scrapes are told apart by the
+// target URI.
+func (s scrapeSettings) newExporters(logger log.Logger) ([]*collector.Exporter, error) {
+	exporters := make([]*collector.Exporter, 0, len(s.targets))
+	for _, t := range s.targets {
+		l, opts := logger, t.opts
+		if len(s.targets) > 1 {
+			l = log.With(logger, "target", t.uri)
+			opts.Target = redactURI(t.uri)
+		}
+		e, err := collector.NewExporter(t.uri, opts, l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %w", t.uri, err)
+		}
+		exporters = append(exporters, e)
+	}
+	return exporters, nil
+}
+
+// redactURI returns uri without the password of its user information, if it
+// is a valid URL.
+func redactURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	return u.Redacted()
+}
+
+// newScrapeSettings returns the scrape settings of the flags f overridden by
+// cfg. The exporters of the targets keep their counters in counters, so that
+// they continue across reloads of the config file.
+func newScrapeSettings(f haproxyFlags, cfg *Config, setFlags map[string]bool, cache *collector.ScrapeCache, counters *collector.Counters) (scrapeSettings, error) {
+	override(&f.scrapeURI, cfg.HAProxy.ScrapeURI, "haproxy.scrape-uri", setFlags)
+	override(&f.sslVerify, cfg.HAProxy.SSLVerify, "haproxy.ssl-verify", setFlags)
+	override(&f.proxyFromEnv, cfg.HAProxy.ProxyFromEnv, "http.proxy-from-env", setFlags)
+	override(&f.serverMetricFields, cfg.HAProxy.ServerMetricFields, "haproxy.server-metric-fields", setFlags)
+	override(&f.serverExcludeStates, cfg.HAProxy.ServerExcludeStates, "haproxy.server-exclude-states", setFlags)
+	override(&f.includeUnprovisioned, cfg.HAProxy.IncludeUnprovisionedServers, "haproxy.include-unprovisioned-servers", setFlags)
+	override(&f.serverNameInclude, cfg.HAProxy.ServerNameInclude, "haproxy.server-name-include", setFlags)
+	override(&f.serverNameExclude, cfg.HAProxy.ServerNameExclude, "haproxy.server-name-exclude", setFlags)
+	override(&f.excludeUncheckedUp, cfg.HAProxy.ExcludeUncheckedServerUp, "haproxy.exclude-unchecked-server-up", setFlags)
+	override(&f.unlimitedValue, cfg.HAProxy.UnlimitedValue, "haproxy.unlimited-value", setFlags)
+	override(&f.timingMilliseconds, cfg.HAProxy.TimingMilliseconds, "haproxy.timing-milliseconds", setFlags)
+	override(&f.runtimeCollectors, cfg.HAProxy.RuntimeCollectors, "haproxy.runtime-collectors", setFlags)
+	override(&f.schemaMetrics, cfg.HAProxy.SchemaMetrics, "haproxy.schema-metrics", setFlags)
+	override(&f.scrapeLevel, cfg.HAProxy.ScrapeLevel, "haproxy.scrape-level", setFlags)
+	override(&f.proxies, cfg.HAProxy.Proxies, "haproxy.proxies", setFlags)
+	override(&f.maxServersPerBackend, cfg.HAProxy.MaxServersPerBackend, "haproxy.max-servers-per-backend", setFlags)
+	override(&f.maxServers, cfg.HAProxy.MaxServers, "haproxy.max-servers", setFlags)
+	override(&f.aggregateServers, cfg.HAProxy.AggregateServers, "haproxy.aggregate-servers", setFlags)
+	override(&f.idLabels, cfg.HAProxy.IDLabels, "haproxy.id-labels", setFlags)
+	override(&f.multiProcess, cfg.HAProxy.MultiProcess, "haproxy.multi-process", setFlags)
+	override(&f.streamStats, cfg.HAProxy.StreamStats, "haproxy.stream-stats", setFlags)
+	override(&f.parseWorkers, cfg.HAProxy.ParseWorkers, "haproxy.parse-workers", setFlags)
+	override(&f.metricBuffer, cfg.HAProxy.MetricBuffer, "haproxy.metric-buffer", setFlags)
+	override(&f.timeout, cfg.HAProxy.Timeout, "haproxy.timeout", setFlags)
+	override(&f.namingScheme, cfg.HAProxy.NamingScheme, "metrics.naming-scheme", setFlags)
+	override(&f.scrapeErrorLogEvery, cfg.HAProxy.ScrapeErrorLogEvery, "log.scrape-errors-every", setFlags)
+	hash, err := settingsHash(f, cfg)
+	if err != nil {
+		return scrapeSettings{}, err
+	}
+
+	serverMetrics, err := collector.FilterServerMetrics(f.serverMetricFields)
+	if err != nil {
+		return scrapeSettings{}, fmt.Errorf("error filtering server metrics: %w", err)
+	}
+	opts := collector.ExporterOpts{
+		SSLVerify:                   f.sslVerify,
+		ProxyFromEnv:                f.proxyFromEnv,
+		ServerMetrics:               serverMetrics,
+		ExcludedServerStates:        f.serverExcludeStates,
+		IncludeUnprovisionedServers: f.includeUnprovisioned,
+		ServerNameInclude:           f.serverNameInclude,
+		ServerNameExclude:           f.serverNameExclude,
+		ExcludeUncheckedServerUp:    f.excludeUncheckedUp,
+		UnlimitedValue:              f.unlimitedValue,
+		TimingMilliseconds:          f.timingMilliseconds,
+		RuntimeCollectors:           f.runtimeCollectors,
+		SchemaMetrics:               f.schemaMetrics,
+		ScrapeLevel:                 f.scrapeLevel,
+		Proxies:                     f.proxies,
+		MaxServersPerBackend:        f.maxServersPerBackend,
+		MaxServers:                  f.maxServers,
+		AggregateServers:            f.aggregateServers,
+		IDLabels:                    f.idLabels,
+		MultiProcess:                f.multiProcess,
+		StreamStats:                 f.streamStats,
+		ParseWorkers:                f.parseWorkers,
+		MetricBuffer:                f.metricBuffer,
+		NamingScheme:                f.namingScheme,
+		ScrapeErrorLogEvery:         f.scrapeErrorLogEvery,
+		Timeout:                     f.timeout,
+		Cache:                       cache,
+	}
+	if opts.LabelRules, err = collector.CompileLabelRules(cfg.LabelRules); err != nil {
+		return scrapeSettings{}, fmt.Errorf("invalid label rules: %w", err)
+	}
+
+	// Probes, modules and targets don't inherit the credentials of the
+	// scrape URI.
+	s := scrapeSettings{
+		probeOpts: opts,
+		modules:   map[string]collector.ExporterOpts{},
+		hash:      hash,
+	}
+	for name, m := range cfg.Modules {
+		if s.modules[name], err = m.apply(opts); err != nil {
+			return scrapeSettings{}, fmt.Errorf("invalid module %q: %w", name, err)
+		}
+	}
+	for _, t := range cfg.Targets {
+		targetOpts := opts
+		if t.Module != "" {
+			var ok bool
+			if targetOpts, ok = s.modules[t.Module]; !ok {
+				return scrapeSettings{}, fmt.Errorf("unknown module %q for target %q", t.Module, t.ScrapeURI)
+			}
+		}
+		targetOpts.ConstLabels = t.Labels
+		targetOpts.Counters = counters
+		s.targets = append(s.targets, scrapeTarget{uri: t.ScrapeURI, opts: targetOpts})
+	}
+	if s.metricRules, err = compileMetricRules(cfg.MetricRules); err != nil {
+		return scrapeSettings{}, fmt.Errorf("invalid metric rules: %w", err)
+	}
+	if len(s.targets) == 0 {
+		setIfConfigured(&opts.Username, cfg.HAProxy.Username)
+		setIfConfigured(&opts.Password, cfg.HAProxy.Password)
+		opts.Counters = counters
+		s.targets = []scrapeTarget{{uri: f.scrapeURI, opts: opts}}
+	}
+	return s, nil
+}
+
+// settingsHash returns a hash of the effective scrape settings: the flags f,
+// already overridden by the haproxy section of cfg, and the other options of
+// cfg applying to the scrapes. Options only taking effect after a restart,
+// e.g. those of the web section, are left out.
+func settingsHash(f haproxyFlags, cfg *Config) ([sha256.Size]byte, error) {
+	scrapeCfg := Config{
+		HAProxy: HAProxyConfig{
+			ModuleConfig: ModuleConfig{Username: cfg.HAProxy.Username, Password: cfg.HAProxy.Password},
+		},
+		Modules:     cfg.Modules,
+		Targets:     cfg.Targets,
+		MetricRules: cfg.MetricRules,
+		LabelRules:  cfg.LabelRules,
+	}
+	content, err := yaml.Marshal(scrapeCfg)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("error hashing the settings: %w", err)
+	}
+	return sha256.Sum256(append([]byte(fmt.Sprintf("%+v\n", f)), content...)), nil
+}
+
+// reloadableExporter is a prometheus.Collector delegating to the Exporters of
+// the most recently loaded configuration.
+type reloadableExporter struct {
+	mutex     sync.RWMutex
+	exporters []*collector.Exporter
+	settings  scrapeSettings
+}
+
+// Exporters returns the current Exporters, one per target.
+func (r *reloadableExporter) Exporters() []*collector.Exporter {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.exporters
+}
+
+// Settings returns the current scrape settings.
+func (r *reloadableExporter) Settings() scrapeSettings {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.settings
+}
+
+// MetricRules returns the current metric rules.
+func (r *reloadableExporter) MetricRules() []metricRule {
+	return r.Settings().metricRules
+}
+
+func (r *reloadableExporter) set(exporters []*collector.Exporter, s scrapeSettings) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.exporters, r.settings = exporters, s
+}
+
+var (
+	configInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "config_info"),
+		"Configuration of the exporter: the schemes of the scrape URIs of its targets, the numbers of targets and modules, and the naming scheme of the metrics.",
+		[]string{"scrape_uri_scheme", "targets", "modules", "naming_scheme"}, nil,
+	)
+	configHash = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "config_hash"),
+		"Hash of the effective scrape settings, of the flags and the config file, which changes with every change of them.",
+		nil, nil,
+	)
+)
+
+// Describe sends no descriptors, as they change with the configuration. This
+// makes reloadableExporter an unchecked collector.
+func (r *reloadableExporter) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect collects the metrics of the current Exporters concurrently, along
+// with the information about and the hash of the current configuration.
+func (r *reloadableExporter) Collect(ch chan<- prometheus.Metric) {
+	if s := r.Settings(); len(s.targets) > 0 {
+		ch <- prometheus.MustNewConstMetric(configInfo, prometheus.GaugeValue, 1, s.infoLabels()...)
+		ch <- prometheus.MustNewConstMetric(configHash, prometheus.GaugeValue, hashValue(s.hash))
+	}
+	collectAll(r.Exporters(), ch, nil)
+}
+
+// infoLabels returns the label values of haproxy_exporter_config_info for s,
+// which has at least one target. The schemes of several targets are sorted
+// and separated by commas.
+func (s scrapeSettings) infoLabels() []string {
+	var schemes []string
+	seen := map[string]bool{}
+	for _, t := range s.targets {
+		scheme := t.uri
+		if u, err := url.Parse(t.uri); err == nil {
+			scheme = u.Scheme
+		}
+		if !seen[scheme] {
+			seen[scheme] = true
+			schemes = append(schemes, scheme)
+		}
+	}
+	sort.Strings(schemes)
+	return []string{
+		strings.Join(schemes, ","),
+		fmt.Sprint(len(s.targets)),
+		fmt.Sprint(len(s.modules)),
+		s.targets[0].opts.NamingScheme,
+	}
+}
+
+// collectAll collects the given sections of the metrics of all exporters, or
+// all of them if sections is nil, concurrently, so that a slow target doesn't
+// delay the others.
+func collectAll(exporters []*collector.Exporter, ch chan<- prometheus.Metric, sections map[string]bool) {
+	var wg sync.WaitGroup
+	for _, e := range exporters {
+		wg.Add(1)
+		go func(e *collector.Exporter) {
+			defer wg.Done()
+			if sections == nil {
+				e.Collect(ch)
+			} else {
+				e.CollectSections(ch, sections)
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+// configReloader loads the config file into a reloadableExporter, whenever
+// its content changes.
+type configReloader struct {
+	path     string
+	target   *reloadableExporter
+	settings func(*Config) (scrapeSettings, error)
+	logger   log.Logger
+
+	hash [sha256.Size]byte
+	cfg  *Config
+
+	lastReloadSuccessful prometheus.Gauge
+	lastReloadSuccess    prometheus.Gauge
+}
+
+func newConfigReloader(path string, target *reloadableExporter, settings func(*Config) (scrapeSettings, error), logger log.Logger) *configReloader {
+	return &configReloader{
+		path:     path,
+		target:   target,
+		settings: settings,
+		logger:   logger,
+		lastReloadSuccessful: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "exporter_config_last_reload_successful",
+			Help:      "Whether the last attempt to load the config file was successful.",
+		}),
+		lastReloadSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "exporter_config_last_reload_success_timestamp_seconds",
+			Help:      "Timestamp of the last successful load of the config file.",
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (r *configReloader) Describe(ch chan<- *prometheus.Desc) {
+	ch <- r.lastReloadSuccessful.Desc()
+	ch <- r.lastReloadSuccess.Desc()
+}
+
+// Collect implements prometheus.Collector.
+func (r *configReloader) Collect(ch chan<- prometheus.Metric) {
+	ch <- r.lastReloadSuccessful
+	ch <- r.lastReloadSuccess
+}
+
+// reload loads the config file, unless it is unchanged since the last
+// successful load, and swaps in Exporters using it. On errors the previous
+// configuration stays in effect.
+func (r *configReloader) reload() error {
+	err := r.load()
+	if err != nil {
+		r.lastReloadSuccessful.Set(0)
+		return err
+	}
+	r.lastReloadSuccessful.Set(1)
+	return nil
+}
+
+func (r *configReloader) load() error {
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(content)
+	if r.cfg != nil && hash == r.hash {
+		return nil
+	}
+
+	cfg, err := loadConfig(r.path)
+	if err != nil {
+		return err
+	}
+	s, err := r.settings(cfg)
+	if err != nil {
+		return err
+	}
+	exporters, err := s.newExporters(r.logger)
+	if err != nil {
+		return err
+	}
+	r.target.set(exporters, s)
+
+	if r.cfg != nil {
+		changed := configDiff(r.cfg, cfg)
+		level.Info(r.logger).Log("msg", "Reloaded config file", "file", r.path, "changed", strings.Join(changed, ","))
+		for _, c := range changed {
+			if strings.HasPrefix(c, "web.") || c == "haproxy.scrape_cache_ttl" || c == "haproxy.pid_file" {
+				level.Warn(r.logger).Log("msg", "Config option only takes effect after a restart", "option", c)
+			}
+		}
+	}
+	r.hash, r.cfg = hash, cfg
+	r.lastReloadSuccess.SetToCurrentTime()
+	return nil
+}
+
+// hashValue returns the first 48 bits of hash as a number, which a float64
+// holds exactly.
+func hashValue(hash [sha256.Size]byte) float64 {
+	return float64(binary.BigEndian.Uint64(hash[:8]) >> 16)
+}
+
+// watch reloads the config file whenever the directory containing it changes.
+// Watching the directory instead of the file catches editors replacing the
+// file and symlink swaps, as done for Kubernetes ConfigMaps.
+func (r *configReloader) watch() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := w.Add(filepath.Dir(r.path)); err != nil {
+		w.Close()
+		return err
+	}
+
+	go func() {
+		defer w.Close()
+		for {
+			select {
+			case _, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if err := r.reload(); err != nil {
+					level.Error(r.logger).Log("msg", "Error reloading config file", "file", r.path, "err", err)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				level.Error(r.logger).Log("msg", "Error watching config file", "file", r.path, "err", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// configDiff returns the options which differ between a and b, in the form
+// section.option. Modules are compared as a whole, and so are targets, which
+// are reported as just targets.
+func configDiff(a, b *Config) []string {
+	flatten := func(c *Config) map[string]interface{} {
+		// Marshaling a Config can't fail.
+		out, _ := yaml.Marshal(c)
+		var sections map[string]interface{}
+		yaml.Unmarshal(out, &sections)
+
+		options := map[string]interface{}{}
+		for section, v := range sections {
+			m, ok := v.(map[interface{}]interface{})
+			if !ok {
+				options[section] = v
+				continue
+			}
+			for k, v := range m {
+				options[fmt.Sprintf("%s.%v", section, k)] = v
+			}
+		}
+		return options
+	}
+	optsA, optsB := flatten(a), flatten(b)
+
+	changed := []string{}
+	for k, v := range optsA {
+		if !reflect.DeepEqual(v, optsB[k]) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range optsB {
+		if _, ok := optsA[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,298 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/haproxy_exporter/collector"
+)
+
+func newTestReloader(path string) (*configReloader, *reloadableExporter) {
+	flags := haproxyFlags{
+		scrapeURI:          "http://localhost/;csv",
+		sslVerify:          true,
+		serverMetricFields: collector.DefaultServerMetricFields,
+		namingScheme:       collector.NamingLegacy,
+		timeout:            5 * time.Second,
+	}
+	current, counters := &reloadableExporter{}, collector.NewCounters()
+	settings := func(cfg *Config) (scrapeSettings, error) {
+		return newScrapeSettings(flags, cfg, nil, nil, counters)
+	}
+	return newConfigReloader(path, current, settings, log.NewNopLogger()), current
+}
+
+func TestConfigReloader(t *testing.T) {
+	path := writeConfig(t, "haproxy:\n  scrape_uri: http://haproxy1/;csv\n")
+	r, current := newTestReloader(path)
+
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "http://haproxy1/;csv", current.Exporters()[0].URI; want != have {
+		t.Errorf("want scrape URI %q, have %q", want, have)
+	}
+	if want, have := 1.0, testutil.ToFloat64(r.lastReloadSuccessful); want != have {
+		t.Errorf("want last reload successful %v, have %v", want, have)
+	}
+	hash := current.Settings().hash
+
+	if err := os.WriteFile(path, []byte("haproxy:\n  scrape_uri: http://haproxy2/;csv\n  naming_scheme: v2\n  scrape_error_log_every: 3\nmodules:\n  socket: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "http://haproxy2/;csv", current.Exporters()[0].URI; want != have {
+		t.Errorf("want scrape URI %q, have %q", want, have)
+	}
+	if _, ok := current.Settings().modules["socket"]; !ok {
+		t.Errorf("want module from reloaded config")
+	}
+	if want, have := collector.NamingV2, current.Settings().probeOpts.NamingScheme; want != have {
+		t.Errorf("want naming scheme %q from reloaded config, have %q", want, have)
+	}
+	if want, have := 3, current.Settings().probeOpts.ScrapeErrorLogEvery; want != have {
+		t.Errorf("want scrape errors logged every %d from reloaded config, have %d", want, have)
+	}
+	if have := current.Settings().hash; have == hash {
+		t.Errorf("want config hash to change with the config file, have %x before and after", have)
+	}
+
+	if err := os.WriteFile(path, []byte("haproxy:\n  scrape_uri: gopher://haproxy3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reload(); err == nil {
+		t.Errorf("expected error for invalid config")
+	}
+	if want, have := "http://haproxy2/;csv", current.Exporters()[0].URI; want != have {
+		t.Errorf("want previous scrape URI %q after failed reload, have %q", want, have)
+	}
+	if want, have := 0.0, testutil.ToFloat64(r.lastReloadSuccessful); want != have {
+		t.Errorf("want last reload successful %v, have %v", want, have)
+	}
+}
+
+func TestConfigReloaderCounters(t *testing.T) {
+	h := newHaproxy([]byte("foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"))
+	defer h.Close()
+	path := writeConfig(t, "haproxy:\n  scrape_uri: "+h.URL+"\n")
+	r, current := newTestReloader(path)
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	testutil.CollectAndCount(current)
+
+	// The exporter replaced by the reload continues the counters.
+	if err := os.WriteFile(path, []byte("haproxy:\n  scrape_uri: "+h.URL+"\nmodules:\n  socket: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+# HELP haproxy_exporter_scrapes_total Current total HAProxy scrapes.
+# TYPE haproxy_exporter_scrapes_total counter
+haproxy_exporter_scrapes_total 2
+`
+	if err := testutil.CollectAndCompare(current, strings.NewReader(expected), "haproxy_exporter_scrapes_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigReloaderWatch(t *testing.T) {
+	path := writeConfig(t, "haproxy:\n  scrape_uri: http://haproxy1/;csv\n")
+	r, current := newTestReloader(path)
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.watch(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("haproxy:\n  scrape_uri: http://haproxy2/;csv\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for current.Exporters()[0].URI != "http://haproxy2/;csv" {
+		if time.Now().After(deadline) {
+			t.Fatalf("config file change was not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConfigDiff(t *testing.T) {
+	uri1, uri2, timeout := "http://haproxy1/;csv", "http://haproxy2/;csv", time.Second
+	a := &Config{HAProxy: HAProxyConfig{ScrapeURI: &uri1}}
+	b := &Config{
+		HAProxy: HAProxyConfig{ScrapeURI: &uri2, ModuleConfig: ModuleConfig{Timeout: &timeout}},
+		Modules: map[string]ModuleConfig{"socket": {}},
+	}
+
+	b.Targets = []TargetConfig{{ScrapeURI: uri1}}
+
+	want := []string{"haproxy.scrape_uri", "haproxy.timeout", "modules.socket", "targets"}
+	if have := configDiff(a, b); !reflect.DeepEqual(want, have) {
+		t.Errorf("want changed options %v, have %v", want, have)
+	}
+	if have := configDiff(b, b); len(have) != 0 {
+		t.Errorf("want no changes, have %v", have)
+	}
+}
+
+func TestSettingsHash(t *testing.T) {
+	flags := haproxyFlags{scrapeURI: "http://localhost/;csv", namingScheme: collector.NamingLegacy, timeout: 5 * time.Second}
+	hash := func(f haproxyFlags, cfg *Config) [32]byte {
+		s, err := newScrapeSettings(f, cfg, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.hash
+	}
+
+	// Without a config file, the hash is that of the flags.
+	current := &reloadableExporter{}
+	s, err := newScrapeSettings(flags, &Config{}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current.set(nil, s)
+	if n := testutil.CollectAndCount(current, "haproxy_exporter_config_hash"); n != 1 {
+		t.Errorf("want the config hash without a config file, have %d metrics", n)
+	}
+
+	base := hash(flags, &Config{})
+	if have := hash(flags, &Config{}); have != base {
+		t.Errorf("want the same hash for the same settings, have %x and %x", base, have)
+	}
+	other := flags
+	other.timeout = time.Second
+	if have := hash(other, &Config{}); have == base {
+		t.Errorf("want the hash to change with the flags")
+	}
+	timeout := time.Second
+	if have := hash(flags, &Config{HAProxy: HAProxyConfig{ModuleConfig: ModuleConfig{Timeout: &timeout}}}); have == base {
+		t.Errorf("want the hash to change with the config file")
+	}
+	// The hash is that of the effective settings, wherever they are set.
+	if have := hash(flags, &Config{HAProxy: HAProxyConfig{ModuleConfig: ModuleConfig{Timeout: &timeout}}}); have != hash(other, &Config{}) {
+		t.Errorf("want the same hash for the same effective settings")
+	}
+	pidFile := "/run/haproxy.pid"
+	if have := hash(flags, &Config{HAProxy: HAProxyConfig{PidFile: &pidFile}}); have != base {
+		t.Errorf("want options not applying to scrapes left out of the hash")
+	}
+}
+
+func TestConfigReloaderTargets(t *testing.T) {
+	const data = "foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer s.Close()
+
+	path := writeConfig(t, `
+targets:
+  - scrape_uri: `+s.URL+`/edge
+    labels:
+      role: edge
+  - scrape_uri: `+s.URL+`/internal
+    labels:
+      role: internal
+`)
+	r, current := newTestReloader(path)
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(current.Exporters()); want != have {
+		t.Fatalf("want %d exporters, have %d", want, have)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(current)
+	expected := `
+# HELP haproxy_exporter_config_info Configuration of the exporter: the schemes of the scrape URIs of its targets, the numbers of targets and modules, and the naming scheme of the metrics.
+# TYPE haproxy_exporter_config_info gauge
+haproxy_exporter_config_info{modules="0",naming_scheme="legacy",scrape_uri_scheme="http",targets="2"} 1
+# HELP haproxy_frontend_current_sessions Current number of active sessions.
+# TYPE haproxy_frontend_current_sessions gauge
+haproxy_frontend_current_sessions{frontend="foo",role="edge"} 1
+# HELP haproxy_exporter_scrape_errors_total Number of failed HAProxy scrapes by type of error: dial, auth, timeout, parse or other.
+# TYPE haproxy_exporter_scrape_errors_total counter
+haproxy_exporter_scrape_errors_total{role="internal",target="` + s.URL + `/internal",type="other"} 1
+# HELP haproxy_up Was the last scrape of HAProxy successful.
+# TYPE haproxy_up gauge
+haproxy_up{role="edge",target="` + s.URL + `/edge"} 1
+haproxy_up{role="internal",target="` + s.URL + `/internal"} 0
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "haproxy_up", "haproxy_exporter_config_info", "haproxy_exporter_scrape_errors_total", "haproxy_frontend_current_sessions"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectAllConcurrently(t *testing.T) {
+	exit := make(chan bool)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-exit
+	}))
+	defer slow.Close()
+	fast := newHaproxy([]byte("foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"))
+	defer fast.Close()
+
+	var exporters []*collector.Exporter
+	for _, uri := range []string{slow.URL, fast.URL} {
+		e, err := collector.NewExporter(uri, collector.ExporterOpts{ServerMetrics: serverMetrics, Timeout: 5 * time.Second, Target: uri}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		exporters = append(exporters, e)
+	}
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collectAll(exporters, ch, nil)
+		close(ch)
+	}()
+
+	// haproxy_up of the fast target arrives while the slow one is still
+	// being scraped.
+	timeout := time.After(2 * time.Second)
+	for up := false; !up; {
+		select {
+		case m := <-ch:
+			desc := m.Desc().String()
+			up = strings.Contains(desc, `"haproxy_up"`) && strings.Contains(desc, fast.URL)
+		case <-timeout:
+			close(exit)
+			t.Fatal("scrape of a target blocked by the scrape of another target")
+		}
+	}
+	close(exit)
+	for range ch {
+	}
+}
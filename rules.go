@@ -0,0 +1,179 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// MetricRuleConfig renames or drops the metrics matching either Name exactly
+// or the anchored regular expression Regex.
+type MetricRuleConfig struct {
+	Name   string `yaml:"name"`
+	Regex  string `yaml:"regex"`
+	Action string `yaml:"action"`
+	// Replacement is the new name for the rename action. It may refer to
+	// capture groups of Regex, e.g. $1.
+	Replacement string `yaml:"replacement"`
+}
+
+// metricRule is a compiled MetricRuleConfig.
+type metricRule struct {
+	re          *regexp.Regexp
+	drop        bool
+	replacement string
+}
+
+// compileMetricRules checks and compiles the given rules.
+func compileMetricRules(cfgs []MetricRuleConfig) ([]metricRule, error) {
+	rules := make([]metricRule, 0, len(cfgs))
+	for i, c := range cfgs {
+		var expr string
+		switch {
+		case c.Name != "" && c.Regex != "":
+			return nil, fmt.Errorf("rule %d: only one of name and regex may be set", i)
+		case c.Name != "":
+			expr = regexp.QuoteMeta(c.Name)
+		case c.Regex != "":
+			expr = c.Regex
+		default:
+			return nil, fmt.Errorf("rule %d: one of name and regex must be set", i)
+		}
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		r := metricRule{re: re, replacement: c.Replacement}
+		switch c.Action {
+		case "drop":
+			r.drop = true
+		case "rename":
+			if c.Replacement == "" {
+				return nil, fmt.Errorf("rule %d: rename without replacement", i)
+			}
+			if c.Name != "" && !model.IsValidMetricName(model.LabelValue(c.Replacement)) {
+				return nil, fmt.Errorf("rule %d: invalid metric name %q", i, c.Replacement)
+			}
+		default:
+			return nil, fmt.Errorf("rule %d: invalid action %q", i, c.Action)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// rulesGatherer applies the current metric rules to the metrics gathered by a
+// Gatherer. Rules are applied in order, each to the name resulting from the
+// previous ones.
+type rulesGatherer struct {
+	gatherer prometheus.Gatherer
+	rules    func() []metricRule
+}
+
+// withMetricRules returns g with the rules returned by rules applied.
+func withMetricRules(g prometheus.Gatherer, rules func() []metricRule) prometheus.Gatherer {
+	return rulesGatherer{gatherer: g, rules: rules}
+}
+
+// Gather implements prometheus.Gatherer.
+func (g rulesGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := g.gatherer.Gather()
+	rules := g.rules()
+	if len(rules) == 0 {
+		return mfs, err
+	}
+
+	byName := map[string]*dto.MetricFamily{}
+	// The label sets of the families renamed to the same name as another one,
+	// to detect series which can't be told apart anymore.
+	labelSets := map[string]map[string]bool{}
+	for _, mf := range mfs {
+		name, keep := applyMetricRules(rules, mf.GetName())
+		if !keep {
+			continue
+		}
+		if !model.IsValidMetricName(model.LabelValue(name)) {
+			return nil, fmt.Errorf("metric %q renamed to invalid name %q", mf.GetName(), name)
+		}
+		if other, ok := byName[name]; ok {
+			// Several metrics were renamed to the same name.
+			if other.GetType() != mf.GetType() {
+				return nil, fmt.Errorf("metric %q renamed to %q, which has a different type", mf.GetName(), name)
+			}
+			seen, ok := labelSets[name]
+			if !ok {
+				seen = make(map[string]bool, len(other.Metric))
+				for _, m := range other.Metric {
+					seen[labelSetKey(m)] = true
+				}
+				labelSets[name] = seen
+			}
+			for _, m := range mf.Metric {
+				key := labelSetKey(m)
+				if seen[key] {
+					return nil, fmt.Errorf("metric %q renamed to %q, which has a series with the same labels", mf.GetName(), name)
+				}
+				seen[key] = true
+			}
+			other.Metric = append(other.Metric, mf.Metric...)
+			continue
+		}
+		mf.Name = &name
+		byName[name] = mf
+	}
+
+	res := make([]*dto.MetricFamily, 0, len(byName))
+	for _, mf := range byName {
+		res = append(res, mf)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].GetName() < res[j].GetName() })
+	return res, err
+}
+
+// labelSetKey returns a key of the label set of m. The label pairs of gathered
+// metrics are sorted by name.
+func labelSetKey(m *dto.Metric) string {
+	var b strings.Builder
+	for _, lp := range m.GetLabel() {
+		b.WriteString(lp.GetName())
+		b.WriteByte(model.SeparatorByte)
+		b.WriteString(lp.GetValue())
+		b.WriteByte(model.SeparatorByte)
+	}
+	return b.String()
+}
+
+// applyMetricRules returns the name of the metric called name after applying
+// rules, and false if it is dropped.
+func applyMetricRules(rules []metricRule, name string) (string, bool) {
+	for _, r := range rules {
+		m := r.re.FindStringSubmatchIndex(name)
+		if m == nil {
+			continue
+		}
+		if r.drop {
+			return "", false
+		}
+		name = string(r.re.ExpandString(nil, r.replacement, name, m))
+	}
+	return name, true
+}
@@ -0,0 +1,116 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricRules(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{"haproxy_up", "haproxy_frontend_bytes_in_total", "haproxy_backend_bytes_in_total", "haproxy_server_bytes_in_total"} {
+		g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: "Help."})
+		g.Set(1)
+		registry.MustRegister(g)
+	}
+
+	rules, err := compileMetricRules([]MetricRuleConfig{
+		{Regex: "haproxy_server_.*", Action: "drop"},
+		{Regex: "haproxy_(frontend|backend)_bytes_in_total", Action: "rename", Replacement: "haproxy_${1}_received_bytes_total"},
+		{Name: "haproxy_up", Action: "rename", Replacement: "haproxy_scrape_up"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP haproxy_backend_received_bytes_total Help.
+# TYPE haproxy_backend_received_bytes_total gauge
+haproxy_backend_received_bytes_total 1
+# HELP haproxy_frontend_received_bytes_total Help.
+# TYPE haproxy_frontend_received_bytes_total gauge
+haproxy_frontend_received_bytes_total 1
+# HELP haproxy_scrape_up Help.
+# TYPE haproxy_scrape_up gauge
+haproxy_scrape_up 1
+`
+	g := withMetricRules(registry, func() []metricRule { return rules })
+	if err := testutil.GatherAndCompare(g, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompileMetricRulesInvalid(t *testing.T) {
+	tests := []struct {
+		rule MetricRuleConfig
+		err  string
+	}{
+		{rule: MetricRuleConfig{Action: "drop"}, err: "one of name and regex must be set"},
+		{rule: MetricRuleConfig{Name: "a", Regex: "a", Action: "drop"}, err: "only one of name and regex"},
+		{rule: MetricRuleConfig{Regex: "(", Action: "drop"}, err: "missing closing )"},
+		{rule: MetricRuleConfig{Name: "a", Action: "keep"}, err: `invalid action "keep"`},
+		{rule: MetricRuleConfig{Name: "a", Action: "rename"}, err: "rename without replacement"},
+		{rule: MetricRuleConfig{Name: "a", Action: "rename", Replacement: "0a"}, err: `invalid metric name "0a"`},
+	}
+
+	for _, tt := range tests {
+		_, err := compileMetricRules([]MetricRuleConfig{tt.rule})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: want error containing %q, have %v", tt.rule, tt.err, err)
+		}
+	}
+}
+
+func TestMetricRulesSameName(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{"haproxy_frontend_bytes_in_total", "haproxy_backend_bytes_in_total"} {
+		g := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: "Help."}, []string{"proxy"})
+		g.WithLabelValues(strings.Split(name, "_")[1]).Set(1)
+		registry.MustRegister(g)
+	}
+	rules, err := compileMetricRules([]MetricRuleConfig{
+		{Regex: "haproxy_(frontend|backend)_bytes_in_total", Action: "rename", Replacement: "haproxy_received_bytes_total"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Series with other labels are merged into the renamed family.
+	expected := `
+# HELP haproxy_received_bytes_total Help.
+# TYPE haproxy_received_bytes_total gauge
+haproxy_received_bytes_total{proxy="backend"} 1
+haproxy_received_bytes_total{proxy="frontend"} 1
+`
+	g := withMetricRules(registry, func() []metricRule { return rules })
+	if err := testutil.GatherAndCompare(g, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+
+	// Series with the same labels can't be told apart anymore.
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "haproxy_frontend_bytes_out_total", Help: "Help.", ConstLabels: prometheus.Labels{"proxy": "frontend"}}))
+	rules, err = compileMetricRules([]MetricRuleConfig{
+		{Regex: "haproxy_frontend_bytes_(in|out)_total", Action: "rename", Replacement: "haproxy_frontend_bytes_total"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Gather(); err == nil || !strings.Contains(err.Error(), "series with the same labels") {
+		t.Errorf("want error for series with the same labels, have %v", err)
+	}
+}
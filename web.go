@@ -0,0 +1,126 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/haproxy_exporter/collector"
+)
+
+// statusRecorder remembers the status code written to the wrapped
+// ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush implements http.Flusher, if the wrapped ResponseWriter does.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController to
+// find its other optional interfaces, e.g. http.Hijacker.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// withRequestLogging wraps h so that every request it serves is logged along
+// with its response status and duration.
+func withRequestLogging(h http.Handler, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		level.Info(logger).Log(
+			"msg", "Handled request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"remote_addr", r.RemoteAddr,
+			"status", rec.status,
+			"duration_seconds", time.Since(start).Seconds(),
+		)
+	})
+}
+
+// withForcedGzip wraps h so that it answers every request as if the client had
+// asked for a gzip encoded response.
+func withForcedGzip(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.Clone(r.Context())
+		r.Header.Set("Accept-Encoding", "gzip")
+		h.ServeHTTP(w, r)
+	})
+}
+
+// sectionCollector is a prometheus.Collector delivering only some sections of
+// the metrics of Exporters.
+type sectionCollector struct {
+	exporters []*collector.Exporter
+	sections  map[string]bool
+}
+
+func (c sectionCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, e := range c.exporters {
+		e.Describe(ch)
+	}
+}
+
+func (c sectionCollector) Collect(ch chan<- prometheus.Metric) {
+	collectAll(c.exporters, ch, c.sections)
+}
+
+// withCollectFilter wraps h so that requests selecting sections with the
+// collect[] query parameter are served only those sections of the current
+// Exporters, instead of everything served by h. The metric rules returned by
+// rules are applied to them.
+func withCollectFilter(h http.Handler, exporters func() []*collector.Exporter, rules func() []metricRule, opts promhttp.HandlerOpts) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filters := r.URL.Query()["collect[]"]
+		if len(filters) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		sections := map[string]bool{}
+		for _, f := range filters {
+			if !collector.IsSection(f) {
+				http.Error(w, fmt.Sprintf("unknown section %q in collect[]", f), http.StatusBadRequest)
+				return
+			}
+			sections[f] = true
+		}
+
+		registry := prometheus.NewRegistry()
+		if err := registry.Register(sectionCollector{exporters: exporters(), sections: sections}); err != nil {
+			http.Error(w, fmt.Sprintf("can't register the metrics of the sections: %v", err), http.StatusInternalServerError)
+			return
+		}
+		promhttp.HandlerFor(withMetricRules(registry, rules), opts).ServeHTTP(w, r)
+	})
+}
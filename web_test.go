@@ -0,0 +1,168 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/haproxy_exporter/collector"
+)
+
+// serverMetrics are all server metrics, which are exported by default.
+var serverMetrics, _ = collector.FilterServerMetrics(collector.DefaultServerMetricFields)
+
+// newHaproxy returns an HTTP server answering every request with the stats.
+func newHaproxy(stats []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(stats)
+	}))
+}
+
+func TestWithRequestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	h := withRequestLogging(http.NotFoundHandler(), log.NewLogfmtLogger(&buf))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, want := range []string{"method=GET", "path=/metrics", "remote_addr=192.0.2.1:1234", "status=404", "duration_seconds="} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("want %q in log line, have %q", want, buf.String())
+		}
+	}
+}
+
+func TestWithRequestLoggingFlush(t *testing.T) {
+	var unwrapped http.ResponseWriter
+	h := withRequestLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		unwrapped = w.(interface{ Unwrap() http.ResponseWriter }).Unwrap()
+	}), log.NewNopLogger())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if !rec.Flushed {
+		t.Errorf("want the response flushed")
+	}
+	if unwrapped != rec {
+		t.Errorf("want the wrapped ResponseWriter unwrapped, have %T", unwrapped)
+	}
+}
+
+func TestWithForcedGzip(t *testing.T) {
+	var have string
+	h := withForcedGzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		have = r.Header.Get("Accept-Encoding")
+	}))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if want := "gzip"; want != have {
+		t.Errorf("want Accept-Encoding %q, have %q", want, have)
+	}
+	if req.Header.Get("Accept-Encoding") != "" {
+		t.Errorf("original request must not be modified")
+	}
+}
+
+func TestWithCollectFilter(t *testing.T) {
+	const data = `foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,
+foo,foo-instance-0,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,2,
+foo,BACKEND,0,0,0,0,,0,0,0,,0,,0,0,0,0,UP,1,1,0,0,0,5007,0,,1,8,1,,0,,1,
+`
+	hp := newHaproxy([]byte(data))
+	defer hp.Close()
+
+	e, _ := collector.NewExporter(hp.URL, collector.ExporterOpts{SSLVerify: true, ServerMetrics: serverMetrics, Timeout: 5 * time.Second}, log.NewNopLogger())
+	h := withCollectFilter(http.NotFoundHandler(), func() []*collector.Exporter { return []*collector.Exporter{e} }, func() []metricRule { return nil }, promhttp.HandlerOpts{})
+
+	tests := []struct {
+		query       string
+		status      int
+		contains    []string
+		notContains []string
+	}{
+		{
+			query:  "",
+			status: http.StatusNotFound,
+		},
+		{
+			query:       "?collect[]=frontend",
+			status:      http.StatusOK,
+			contains:    []string{"haproxy_frontend_current_sessions", "haproxy_up 1"},
+			notContains: []string{"haproxy_backend_", "haproxy_server_"},
+		},
+		{
+			query:       "?collect[]=backend&collect[]=server",
+			status:      http.StatusOK,
+			contains:    []string{"haproxy_backend_current_sessions", "haproxy_server_current_sessions"},
+			notContains: []string{"haproxy_frontend_"},
+		},
+		{
+			query:  "?collect[]=bogus",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics"+tt.query, nil))
+		if tt.status != rec.Code {
+			t.Errorf("%s: want status %d, have %d", tt.query, tt.status, rec.Code)
+			continue
+		}
+		for _, want := range tt.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("%s: want %q in response", tt.query, want)
+			}
+		}
+		for _, unwanted := range tt.notContains {
+			if strings.Contains(rec.Body.String(), unwanted) {
+				t.Errorf("%s: want no %q in response", tt.query, unwanted)
+			}
+		}
+	}
+}
+
+func TestWithCollectFilterInconsistentLabels(t *testing.T) {
+	hp := newHaproxy([]byte("foo,FRONTEND,,,1,2,,,,,,,,,,,,OPEN,,,,,,,,,,,,,,,0,\n"))
+	defer hp.Close()
+
+	var exporters []*collector.Exporter
+	for _, labels := range []prometheus.Labels{{"role": "edge"}, {"env": "prod"}} {
+		e, err := collector.NewExporter(hp.URL, collector.ExporterOpts{ServerMetrics: serverMetrics, ConstLabels: labels, Timeout: 5 * time.Second}, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		exporters = append(exporters, e)
+	}
+	h := withCollectFilter(http.NotFoundHandler(), func() []*collector.Exporter { return exporters }, func() []metricRule { return nil }, promhttp.HandlerOpts{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics?collect[]=frontend", nil))
+	if want, have := http.StatusInternalServerError, rec.Code; want != have {
+		t.Errorf("want status %d, have %d", want, have)
+	}
+}